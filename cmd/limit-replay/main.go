@@ -0,0 +1,121 @@
+// Command limit-replay replays samples captured by limit.RecordingLimit through a limit algorithm and prints the
+// resulting limit timeline as CSV, allowing tuning changes to be evaluated offline against real traffic.
+//
+// Usage:
+//
+//	limit-replay -algorithm gradient2 -smoothing 0.1 -input capture.jsonl > timeline.csv
+package main
+
+import (
+	"encoding/csv"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+
+	"github.com/platinummonkey/go-concurrency-limits/core"
+	"github.com/platinummonkey/go-concurrency-limits/limit"
+)
+
+type config struct {
+	algorithm    string
+	initialLimit int
+	minLimit     int
+	maxLimit     int
+	smoothing    float64
+	longWindow   int
+	backOffRatio float64
+}
+
+func main() {
+	if err := run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr); err != nil {
+		fmt.Fprintf(os.Stderr, "limit-replay: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func run(args []string, stdin io.Reader, stdout io.Writer, stderr io.Writer) error {
+	flags := flag.NewFlagSet("limit-replay", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	cfg := config{}
+	input := flags.String("input", "-", "capture file written by limit.RecordingLimit, - for stdin")
+	flags.StringVar(&cfg.algorithm, "algorithm", "gradient2",
+		"limit algorithm: aimd, bbr, cubic, gradient, gradient2 or vegas")
+	flags.IntVar(&cfg.initialLimit, "initial-limit", 20, "initial limit")
+	flags.IntVar(&cfg.minLimit, "min-limit", 1, "minimum limit")
+	flags.IntVar(&cfg.maxLimit, "max-limit", 1000, "maximum limit")
+	flags.Float64Var(&cfg.smoothing, "smoothing", 0.2, "smoothing factor for gradient, gradient2 and vegas")
+	flags.IntVar(&cfg.longWindow, "long-window", 600, "long RTT window for gradient2")
+	flags.Float64Var(&cfg.backOffRatio, "backoff-ratio", 0.9, "back off ratio for aimd")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	var r io.Reader = stdin
+	if *input != "-" {
+		f, err := os.Open(*input)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		r = f
+	}
+
+	// time based algorithms follow the capture's clock rather than the wall clock
+	var now int64
+	l, err := newLimit(cfg, func() int64 { return now })
+	if err != nil {
+		return err
+	}
+
+	w := csv.NewWriter(stdout)
+	if err := w.Write([]string{"timestamp", "rtt", "inFlight", "didDrop", "recordedLimit", "limit"}); err != nil {
+		return err
+	}
+	err = limit.ReplaySamples(
+		limit.NewSampleRecordReader(r),
+		l,
+		func(timestamp int64) { now = timestamp },
+		func(record limit.SampleRecord, newLimit int) {
+			w.Write([]string{
+				strconv.FormatInt(record.Timestamp, 10),
+				strconv.FormatInt(record.RTT, 10),
+				strconv.Itoa(record.InFlight),
+				strconv.FormatBool(record.DidDrop),
+				strconv.Itoa(record.Limit),
+				strconv.Itoa(newLimit),
+			})
+		},
+	)
+	if err != nil {
+		return err
+	}
+	w.Flush()
+	return w.Error()
+}
+
+func newLimit(cfg config, clock limit.Clock) (core.Limit, error) {
+	switch cfg.algorithm {
+	case "aimd":
+		// aimd has no bounds of its own, the estimate is clamped instead
+		aimd := limit.NewAIMDLimit("replay", cfg.initialLimit, cfg.backOffRatio)
+		return limit.NewBoundedLimit(aimd, cfg.minLimit, cfg.maxLimit, 0, 0)
+	case "bbr":
+		return limit.NewBBRLimit("replay", cfg.initialLimit, cfg.minLimit, cfg.maxLimit, 0, 0, 0, nil)
+	case "cubic":
+		return limit.NewCubicLimit("replay", cfg.initialLimit, cfg.minLimit, cfg.maxLimit, 0, 0, true, clock, nil)
+	case "gradient":
+		return limit.NewGradientLimitWithRegistry("replay", cfg.initialLimit, cfg.minLimit, cfg.maxLimit,
+			cfg.smoothing, nil, -1, 0, nil), nil
+	case "gradient2":
+		return limit.NewGradient2Limit("replay", cfg.initialLimit, cfg.maxLimit, cfg.minLimit, nil,
+			cfg.smoothing, cfg.longWindow, nil)
+	case "vegas":
+		// vegas has no minimum limit of its own, the estimate is clamped as for aimd
+		vegas := limit.NewVegasLimitWithRegistry("replay", cfg.initialLimit, nil, cfg.maxLimit, cfg.smoothing,
+			nil, nil, nil, nil, nil, -1, nil)
+		return limit.NewBoundedLimit(vegas, cfg.minLimit, cfg.maxLimit, 0, 0)
+	}
+	return nil, fmt.Errorf("unknown algorithm %q", cfg.algorithm)
+}
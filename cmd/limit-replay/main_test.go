@@ -0,0 +1,91 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testCapture = `{"t":0,"r":10000000,"i":10,"l":10}
+{"t":1000000,"r":10000000,"i":11,"l":11}
+{"t":2000000,"r":50000000,"i":1,"d":true,"l":9}
+`
+
+func TestRun(t *testing.T) {
+	t.Parallel()
+
+	t.Run("AIMD", func(t2 *testing.T) {
+		t2.Parallel()
+		asrt := assert.New(t2)
+		var stdout, stderr bytes.Buffer
+		err := run([]string{"-algorithm", "aimd", "-initial-limit", "10", "-backoff-ratio", "0.5"},
+			strings.NewReader(testCapture), &stdout, &stderr)
+		asrt.NoError(err)
+		asrt.Equal(`timestamp,rtt,inFlight,didDrop,recordedLimit,limit
+0,10000000,10,false,10,11
+1000000,10000000,11,false,11,12
+2000000,50000000,1,true,9,6
+`, stdout.String())
+	})
+
+	t.Run("AllAlgorithmsFromFile", func(t2 *testing.T) {
+		t2.Parallel()
+		asrt := assert.New(t2)
+		dir, err := ioutil.TempDir("", "limit-replay")
+		asrt.NoError(err)
+		defer os.RemoveAll(dir)
+		path := filepath.Join(dir, "capture.jsonl")
+		asrt.NoError(ioutil.WriteFile(path, []byte(testCapture), 0600))
+
+		for _, algorithm := range []string{"aimd", "bbr", "cubic", "gradient", "gradient2", "vegas"} {
+			var stdout, stderr bytes.Buffer
+			err := run([]string{"-algorithm", algorithm, "-input", path}, strings.NewReader(""), &stdout, &stderr)
+			asrt.NoError(err, algorithm)
+			asrt.Len(strings.Split(strings.TrimSpace(stdout.String()), "\n"), 4, algorithm)
+		}
+	})
+
+	t.Run("AIMDBounds", func(t2 *testing.T) {
+		t2.Parallel()
+		asrt := assert.New(t2)
+		var stdout, stderr bytes.Buffer
+		err := run([]string{"-algorithm", "aimd", "-initial-limit", "10", "-backoff-ratio", "0.5", "-min-limit", "8",
+			"-max-limit", "11"}, strings.NewReader(testCapture), &stdout, &stderr)
+		asrt.NoError(err)
+		asrt.Equal(`timestamp,rtt,inFlight,didDrop,recordedLimit,limit
+0,10000000,10,false,10,11
+1000000,10000000,11,false,11,11
+2000000,50000000,1,true,9,8
+`, stdout.String())
+	})
+
+	t.Run("VegasMinLimit", func(t2 *testing.T) {
+		t2.Parallel()
+		asrt := assert.New(t2)
+		var stdout, stderr bytes.Buffer
+		err := run([]string{"-algorithm", "vegas", "-initial-limit", "20", "-min-limit", "30"},
+			strings.NewReader(testCapture), &stdout, &stderr)
+		asrt.NoError(err)
+		asrt.Equal(`timestamp,rtt,inFlight,didDrop,recordedLimit,limit
+0,10000000,10,false,10,30
+1000000,10000000,11,false,11,30
+2000000,50000000,1,true,9,30
+`, stdout.String())
+	})
+
+	t.Run("Errors", func(t2 *testing.T) {
+		t2.Parallel()
+		asrt := assert.New(t2)
+		var stdout, stderr bytes.Buffer
+		asrt.EqualError(run([]string{"-algorithm", "unknown"}, strings.NewReader(""), &stdout, &stderr),
+			`unknown algorithm "unknown"`)
+		asrt.Error(run([]string{"-input", "/does/not/exist"}, strings.NewReader(""), &stdout, &stderr))
+		asrt.Error(run([]string{"-unknown-flag"}, strings.NewReader(""), &stdout, &stderr))
+		asrt.Error(run(nil, strings.NewReader("garbage"), &stdout, &stderr))
+	})
+}
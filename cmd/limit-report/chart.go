@@ -0,0 +1,204 @@
+package main
+
+import (
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	chartWidth  = 900
+	chartHeight = 220
+	marginLeft  = 60
+	marginRight = 20
+	marginTop   = 15
+	marginBot   = 30
+)
+
+// palette colors the series in order, wrapping around.
+var palette = []string{"#1f77b4", "#ff7f0e", "#2ca02c", "#d62728", "#9467bd", "#8c564b", "#e377c2", "#17becf"}
+
+// bucket aggregates the samples of a series within a time bucket.
+type bucket struct {
+	count    int
+	limit    int
+	inFlight int
+	drops    int
+	rttMin   float64
+	rttAvg   float64
+	rttP     float64
+}
+
+// bucketize aggregates the samples into buckets of the given width starting at start, empty buckets are nil.
+func bucketize(s *series, start int64, width time.Duration, count int, percentile float64) []*bucket {
+	rtts := make([][]float64, count)
+	buckets := make([]*bucket, count)
+	for _, smpl := range s.samples {
+		idx := int((smpl.timestamp - start) / width.Nanoseconds())
+		if idx < 0 {
+			idx = 0
+		}
+		if idx >= count {
+			idx = count - 1
+		}
+		b := buckets[idx]
+		if b == nil {
+			b = &bucket{}
+			buckets[idx] = b
+		}
+		b.count++
+		b.limit = smpl.limit
+		if smpl.inFlight > b.inFlight {
+			b.inFlight = smpl.inFlight
+		}
+		if smpl.didDrop {
+			b.drops++
+		}
+		rtts[idx] = append(rtts[idx], milliseconds(smpl.rtt))
+	}
+	for i, b := range buckets {
+		if b == nil {
+			continue
+		}
+		sort.Float64s(rtts[i])
+		sum := 0.0
+		for _, rtt := range rtts[i] {
+			sum += rtt
+		}
+		b.rttMin = rtts[i][0]
+		b.rttAvg = sum / float64(len(rtts[i]))
+		b.rttP = percentileOf(rtts[i], percentile)
+	}
+	return buckets
+}
+
+// percentileOf returns the nearest rank percentile of the sorted values.
+func percentileOf(sorted []float64, percentile float64) float64 {
+	idx := int(math.Ceil(percentile*float64(len(sorted)))) - 1
+	if idx < 0 {
+		idx = 0
+	}
+	return sorted[idx]
+}
+
+func milliseconds(nanos int64) float64 {
+	return float64(nanos) / float64(time.Millisecond)
+}
+
+// chart is a line chart rendered as SVG.
+type chart struct {
+	Title  string
+	Width  int
+	Height int
+	Left   int
+	Right  int
+	Top    int
+	Bottom int
+	XTicks []tick
+	YTicks []tick
+	Lines  []line
+}
+
+type tick struct {
+	Pos   float64
+	Label string
+}
+
+type line struct {
+	Name   string
+	Color  string
+	Dash   string
+	Points string
+}
+
+// point is a value at the given bucket.
+type point struct {
+	bucket int
+	value  float64
+}
+
+type lineSpec struct {
+	name   string
+	color  string
+	dash   string
+	points []point
+}
+
+// newChart lays out the lines over buckets of the given width.
+func newChart(title string, width time.Duration, count int, specs []lineSpec) chart {
+	c := chart{
+		Title:  title,
+		Width:  chartWidth,
+		Height: chartHeight,
+		Left:   marginLeft,
+		Right:  chartWidth - marginRight,
+		Top:    marginTop,
+		Bottom: chartHeight - marginBot,
+	}
+	maxValue := 0.0
+	for _, spec := range specs {
+		for _, p := range spec.points {
+			maxValue = math.Max(maxValue, p.value)
+		}
+	}
+	yStep := niceStep(maxValue / 4)
+	yMax := math.Max(yStep, math.Ceil(maxValue/yStep)*yStep)
+	span := width.Seconds() * float64(count)
+	xStep := niceStep(span / 8)
+
+	x := func(seconds float64) float64 {
+		return round(float64(c.Left) + seconds/span*float64(c.Right-c.Left))
+	}
+	y := func(value float64) float64 {
+		return round(float64(c.Bottom) - value/yMax*float64(c.Bottom-c.Top))
+	}
+	for i := 0; float64(i)*yStep <= yMax+yStep/2; i++ {
+		v := float64(i) * yStep
+		c.YTicks = append(c.YTicks, tick{Pos: y(v), Label: formatValue(v)})
+	}
+	for i := 0; float64(i)*xStep <= span+xStep/2; i++ {
+		v := float64(i) * xStep
+		c.XTicks = append(c.XTicks, tick{Pos: x(v), Label: formatValue(v) + "s"})
+	}
+	for _, spec := range specs {
+		coords := make([]string, 0, len(spec.points))
+		for _, p := range spec.points {
+			// points are plotted at the middle of their bucket
+			seconds := (float64(p.bucket) + 0.5) * width.Seconds()
+			coords = append(coords, formatValue(x(seconds))+","+formatValue(y(p.value)))
+		}
+		c.Lines = append(c.Lines, line{
+			Name:   spec.name,
+			Color:  spec.color,
+			Dash:   spec.dash,
+			Points: strings.Join(coords, " "),
+		})
+	}
+	return c
+}
+
+// round rounds the coordinate to a tenth of a pixel.
+func round(v float64) float64 {
+	return math.Round(v*10) / 10
+}
+
+// niceStep rounds the step up to 1, 2 or 5 times a power of 10.
+func niceStep(step float64) float64 {
+	if step <= 0 {
+		return 1
+	}
+	magnitude := math.Pow(10, math.Floor(math.Log10(step)))
+	for _, m := range []float64{1, 2, 5} {
+		if step <= m*magnitude {
+			return m * magnitude
+		}
+	}
+	return 10 * magnitude
+}
+
+// formatValue formats the value with up to 3 decimals, dropping trailing zeros.
+func formatValue(v float64) string {
+	return strings.TrimSuffix(strings.TrimRight(strconv.FormatFloat(v, 'f', 3, 64), "0"), ".")
+}
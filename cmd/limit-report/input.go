@@ -0,0 +1,135 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strconv"
+
+	"github.com/platinummonkey/go-concurrency-limits/limit"
+)
+
+// sample is a single limit sample, regardless of the format it was read from.
+type sample struct {
+	timestamp int64
+	rtt       int64
+	inFlight  int
+	didDrop   bool
+	limit     int
+}
+
+// series is the samples of a single limiter or partition.
+type series struct {
+	name    string
+	samples []sample
+}
+
+// readSeries reads the samples from r, detecting the format from its content:
+//   - JSON lines written by limit.RecordingLimit
+//   - a JSON array written by limit.HistoryLimit
+//   - CSV written by the limit-replay command, the replayed limit is charted
+func readSeries(name string, r io.Reader) (*series, error) {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	trimmed := bytes.TrimSpace(data)
+	var samples []sample
+	switch {
+	case len(trimmed) == 0:
+		return nil, fmt.Errorf("%s: no samples", name)
+	case trimmed[0] == '{':
+		samples, err = readRecords(trimmed)
+	case trimmed[0] == '[':
+		samples, err = readHistory(trimmed)
+	default:
+		samples, err = readReplay(trimmed)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", name, err)
+	}
+	if len(samples) == 0 {
+		return nil, fmt.Errorf("%s: no samples", name)
+	}
+	return &series{name: name, samples: samples}, nil
+}
+
+func readRecords(data []byte) ([]sample, error) {
+	reader := limit.NewSampleRecordReader(bytes.NewReader(data))
+	var samples []sample
+	for {
+		record, err := reader.Next()
+		if err == io.EOF {
+			return samples, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if record.Outcome != "" {
+			// request outcomes written by a recording limiter to the same capture are not samples of the limit
+			continue
+		}
+		samples = append(samples, sample{
+			timestamp: record.Timestamp,
+			rtt:       record.RTT,
+			inFlight:  record.InFlight,
+			didDrop:   record.DidDrop,
+			limit:     record.Limit,
+		})
+	}
+}
+
+func readHistory(data []byte) ([]sample, error) {
+	var entries []limit.LimitHistoryEntry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, fmt.Errorf("invalid history: %v", err)
+	}
+	samples := make([]sample, 0, len(entries))
+	for _, entry := range entries {
+		samples = append(samples, sample{
+			timestamp: entry.Timestamp.UnixNano(),
+			rtt:       entry.RTT,
+			inFlight:  entry.InFlight,
+			didDrop:   entry.DidDrop,
+			limit:     entry.Limit,
+		})
+	}
+	return samples, nil
+}
+
+func readReplay(data []byte) ([]sample, error) {
+	rows, err := csv.NewReader(bytes.NewReader(data)).ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	columns := make(map[string]int)
+	for i, column := range rows[0] {
+		columns[column] = i
+	}
+	for _, column := range []string{"timestamp", "rtt", "inFlight", "didDrop", "limit"} {
+		if _, ok := columns[column]; !ok {
+			return nil, fmt.Errorf("unrecognized format, missing CSV column %q", column)
+		}
+	}
+
+	samples := make([]sample, 0, len(rows)-1)
+	for i, row := range rows[1:] {
+		var s sample
+		var errs [5]error
+		s.timestamp, errs[0] = strconv.ParseInt(row[columns["timestamp"]], 10, 64)
+		s.rtt, errs[1] = strconv.ParseInt(row[columns["rtt"]], 10, 64)
+		s.inFlight, errs[2] = strconv.Atoi(row[columns["inFlight"]])
+		s.didDrop, errs[3] = strconv.ParseBool(row[columns["didDrop"]])
+		s.limit, errs[4] = strconv.Atoi(row[columns["limit"]])
+		for _, err := range errs {
+			if err != nil {
+				return nil, fmt.Errorf("invalid row %d: %v", i+2, err)
+			}
+		}
+		samples = append(samples, s)
+	}
+	return samples, nil
+}
@@ -0,0 +1,88 @@
+// Command limit-report renders limit samples as a self-contained HTML report charting the estimated limit, in flight,
+// RTT and drops over time, so tuning changes can be reviewed side by side.  Each input is charted as its own series,
+// typically one per limiter or partition, and may be:
+//   - a capture written by limit.RecordingLimit, including simulations by wrapping the limit with the
+//     simulation's clock
+//   - the JSON history written by limit.HistoryLimit
+//   - the CSV output of the limit-replay command
+//
+// Inputs are named name=path, defaulting to the path, and - reads from stdin.
+//
+// Usage:
+//
+//	limit-replay -algorithm gradient2 -smoothing 0.1 -input capture.jsonl > smoothing-0.1.csv
+//	limit-report -output report.html recorded=capture.jsonl smoothing-0.1=smoothing-0.1.csv
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+func main() {
+	if err := run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr); err != nil {
+		fmt.Fprintf(os.Stderr, "limit-report: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func run(args []string, stdin io.Reader, stdout io.Writer, stderr io.Writer) error {
+	flags := flag.NewFlagSet("limit-report", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	output := flags.String("output", "-", "HTML report file, - for stdout")
+	title := flags.String("title", "Limit report", "title of the report")
+	bucket := flags.Duration("bucket", 0, "width of the time buckets, 0 to fit the timeline")
+	percentile := flags.Float64("percentile", 0.99, "RTT percentile charted, (0,1]")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() == 0 {
+		return fmt.Errorf("at least one input must be specified")
+	}
+	if *percentile <= 0 || *percentile > 1 {
+		return fmt.Errorf("percentile must be between (0,1]")
+	}
+
+	all := make([]*series, 0, flags.NArg())
+	for _, arg := range flags.Args() {
+		s, err := readInput(arg, stdin)
+		if err != nil {
+			return err
+		}
+		all = append(all, s)
+	}
+	r := newReport(*title, all, *bucket, *percentile)
+
+	if *output == "-" {
+		return r.write(stdout)
+	}
+	f, err := os.Create(*output)
+	if err != nil {
+		return err
+	}
+	if err := r.write(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// readInput reads the series of an input argument, name=path or path.
+func readInput(arg string, stdin io.Reader) (*series, error) {
+	name, path := arg, arg
+	if idx := strings.Index(arg, "="); idx >= 0 {
+		name, path = arg[:idx], arg[idx+1:]
+	}
+	if path == "-" {
+		return readSeries(name, stdin)
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return readSeries(name, f)
+}
@@ -0,0 +1,209 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testCapture = `{"t":0,"r":10000000,"i":10,"l":10}
+{"t":1000000,"r":20000000,"i":11,"l":11}
+
+{"t":2000000,"r":50000000,"i":1,"d":true,"l":9}
+`
+
+const testReplay = `timestamp,rtt,inFlight,didDrop,recordedLimit,limit
+0,10000000,10,false,10,11
+1000000,20000000,11,false,11,12
+2000000,50000000,1,true,9,6
+`
+
+const testHistory = `[
+{"timestamp":"2020-01-01T00:00:00Z","rtt":10000000,"inFlight":10,"didDrop":false,"limit":10},
+{"timestamp":"2020-01-01T00:00:00.001Z","rtt":20000000,"inFlight":11,"didDrop":false,"limit":11},
+{"timestamp":"2020-01-01T00:00:00.002Z","rtt":50000000,"inFlight":1,"didDrop":true,"limit":9}
+]`
+
+func TestReadSeries(t *testing.T) {
+	t.Parallel()
+
+	t.Run("Formats", func(t2 *testing.T) {
+		t2.Parallel()
+		asrt := assert.New(t2)
+		for name, input := range map[string]string{"capture": testCapture, "replay": testReplay, "history": testHistory} {
+			s, err := readSeries(name, strings.NewReader(input))
+			asrt.NoError(err, name)
+			asrt.Equal(name, s.name)
+			asrt.Len(s.samples, 3, name)
+			asrt.Equal(int64(1000000), s.samples[1].timestamp-s.samples[0].timestamp, name)
+			asrt.Equal(int64(20000000), s.samples[1].rtt, name)
+			asrt.Equal(11, s.samples[1].inFlight, name)
+			asrt.True(s.samples[2].didDrop, name)
+		}
+
+		s, err := readSeries("replay", strings.NewReader(testReplay))
+		asrt.NoError(err)
+		asrt.Equal(6, s.samples[2].limit, "replayed rather than recorded limit")
+	})
+
+	t.Run("InterleavedCapture", func(t2 *testing.T) {
+		t2.Parallel()
+		asrt := assert.New(t2)
+		// request outcomes recorded alongside the samples are skipped
+		capture := `{"t":0,"s":0,"r":0,"i":10,"l":10,"o":"rejected"}
+{"t":0,"r":10000000,"i":10,"l":10}
+{"t":1000000,"r":20000000,"i":11,"l":11}
+{"t":1500000,"s":0,"r":1500000,"i":11,"d":true,"l":11,"o":"dropped"}
+{"t":2000000,"r":50000000,"i":1,"d":true,"l":9}
+`
+		s, err := readSeries("capture", strings.NewReader(capture))
+		asrt.NoError(err)
+		asrt.Len(s.samples, 3)
+		asrt.Equal(int64(10000000), s.samples[0].rtt)
+		drops := 0
+		for _, sample := range s.samples {
+			if sample.didDrop {
+				drops++
+			}
+		}
+		asrt.Equal(1, drops)
+	})
+
+	t.Run("Invalid", func(t2 *testing.T) {
+		t2.Parallel()
+		asrt := assert.New(t2)
+		for _, input := range []string{
+			"",
+			"  \n",
+			"[]",
+			`{"t":"now"}`,
+			`[{"timestamp":1}]`,
+			"timestamp,rtt\n0,1\n",
+			"timestamp,rtt,inFlight,didDrop,limit\n0,1,2,maybe,3\n",
+		} {
+			_, err := readSeries("test", strings.NewReader(input))
+			asrt.Error(err, input)
+		}
+	})
+}
+
+func TestNewReport(t *testing.T) {
+	t.Parallel()
+	asrt := assert.New(t)
+
+	s := &series{name: "test"}
+	for i := 0; i < 100; i++ {
+		s.samples = append(s.samples, sample{
+			timestamp: int64(i) * int64(10*time.Millisecond),
+			rtt:       int64(i+1) * int64(time.Millisecond),
+			inFlight:  i % 10,
+			didDrop:   i%10 == 9,
+			limit:     10 + i,
+		})
+	}
+
+	buckets := bucketize(s, 0, 100*time.Millisecond, 10, 0.9)
+	asrt.Len(buckets, 10)
+	asrt.Equal(10, buckets[0].count)
+	asrt.Equal(19, buckets[0].limit)
+	asrt.Equal(9, buckets[0].inFlight)
+	asrt.Equal(1, buckets[0].drops)
+	asrt.Equal(1.0, buckets[0].rttMin)
+	asrt.Equal(5.5, buckets[0].rttAvg)
+	asrt.Equal(9.0, buckets[0].rttP)
+
+	r := newReport("Test", []*series{s}, 100*time.Millisecond, 0.9)
+	asrt.Equal("100ms", r.Bucket)
+	asrt.Equal("p90", r.Percentile)
+	asrt.Len(r.Charts, 4)
+	asrt.Len(r.Charts[0].Lines, 1)
+	asrt.Len(r.Charts[2].Lines, 3)
+	asrt.Equal("test p90", r.Charts[2].Lines[2].Name)
+	asrt.Equal(strings.Count(r.Charts[0].Lines[0].Points, ","), 10)
+	asrt.Equal("0", r.Charts[0].YTicks[0].Label)
+	asrt.Equal(1, len(r.Series))
+	asrt.Equal(seriesSummary{
+		Name:       "test",
+		Color:      palette[0],
+		Samples:    100,
+		Duration:   "990ms",
+		FirstLimit: 10,
+		FinalLimit: 109,
+		MinLimit:   10,
+		MaxLimit:   109,
+		Drops:      10,
+		AvgRTT:     "50.5ms",
+		RTT:        "90ms",
+	}, r.Series[0])
+
+	r = newReport("Test", []*series{s}, 0, 0.99)
+	asrt.Equal("3ms", r.Bucket, "sized to fit the timeline")
+}
+
+func TestNiceStep(t *testing.T) {
+	t.Parallel()
+	asrt := assert.New(t)
+	asrt.Equal(1.0, niceStep(0))
+	asrt.Equal(1.0, niceStep(0.9))
+	asrt.Equal(2.0, niceStep(1.5))
+	asrt.Equal(50.0, niceStep(31))
+	asrt.Equal(100.0, niceStep(51))
+	asrt.Equal("0.3", formatValue(3*0.1))
+	asrt.Equal("25", formatValue(25))
+}
+
+func TestRun(t *testing.T) {
+	t.Parallel()
+
+	t.Run("Files", func(t2 *testing.T) {
+		t2.Parallel()
+		asrt := assert.New(t2)
+		dir, err := ioutil.TempDir("", "limit-report")
+		asrt.NoError(err)
+		defer os.RemoveAll(dir)
+		capture := filepath.Join(dir, "capture.jsonl")
+		asrt.NoError(ioutil.WriteFile(capture, []byte(testCapture), 0644))
+		output := filepath.Join(dir, "report.html")
+
+		err = run([]string{"-output", output, "-title", "Smoothing <0.1>", capture, "replay=-"},
+			strings.NewReader(testReplay), ioutil.Discard, ioutil.Discard)
+		asrt.NoError(err)
+		data, err := ioutil.ReadFile(output)
+		asrt.NoError(err)
+		html := string(data)
+		asrt.True(strings.HasPrefix(html, "<!DOCTYPE html>"))
+		asrt.Contains(html, "<title>Smoothing &lt;0.1&gt;</title>")
+		asrt.Contains(html, capture+"</td>")
+		asrt.Contains(html, "replay</td>")
+		asrt.Equal(4, strings.Count(html, "<svg xmlns"))
+		asrt.Equal(12, strings.Count(html, "<polyline"))
+	})
+
+	t.Run("Stdout", func(t2 *testing.T) {
+		t2.Parallel()
+		asrt := assert.New(t2)
+		var stdout bytes.Buffer
+		err := run([]string{"-bucket", "1ms", "-"}, strings.NewReader(testHistory), &stdout, ioutil.Discard)
+		asrt.NoError(err)
+		asrt.Contains(stdout.String(), "buckets of 1ms")
+	})
+
+	t.Run("Invalid", func(t2 *testing.T) {
+		t2.Parallel()
+		asrt := assert.New(t2)
+		for _, args := range [][]string{
+			{},
+			{"-percentile", "0", "-"},
+			{"missing.jsonl"},
+			{"-unknown"},
+		} {
+			asrt.Error(run(args, strings.NewReader(testCapture), ioutil.Discard, ioutil.Discard), strings.Join(args, " "))
+		}
+	})
+}
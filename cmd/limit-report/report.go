@@ -0,0 +1,183 @@
+package main
+
+import (
+	"html/template"
+	"io"
+	"math"
+	"sort"
+	"time"
+)
+
+// maxBuckets is the number of buckets the timeline is split into unless the bucket width is set.
+const maxBuckets = 300
+
+// report is the data rendered by reportTemplate.
+type report struct {
+	Title      string
+	Bucket     string
+	Percentile string
+	Series     []seriesSummary
+	Charts     []chart
+}
+
+// seriesSummary summarizes a series over the whole timeline.
+type seriesSummary struct {
+	Name       string
+	Color      string
+	Samples    int
+	Duration   string
+	FirstLimit int
+	FinalLimit int
+	MinLimit   int
+	MaxLimit   int
+	Drops      int
+	AvgRTT     string
+	RTT        string
+}
+
+// newReport aggregates the series into buckets, of the given width or sized to fit maxBuckets when 0, and lays out
+// the charts.  The series share the time axis, starting at the earliest sample.
+func newReport(title string, all []*series, width time.Duration, percentile float64) *report {
+	start, end := int64(math.MaxInt64), int64(math.MinInt64)
+	for _, s := range all {
+		for _, smpl := range s.samples {
+			if smpl.timestamp < start {
+				start = smpl.timestamp
+			}
+			if smpl.timestamp > end {
+				end = smpl.timestamp
+			}
+		}
+	}
+	span := time.Duration(end - start)
+	if width <= 0 {
+		width = (span / maxBuckets).Round(time.Millisecond)
+		if width < time.Millisecond {
+			width = time.Millisecond
+		}
+	}
+	count := int(span/width) + 1
+
+	r := &report{
+		Title:      title,
+		Bucket:     width.String(),
+		Percentile: "p" + formatValue(percentile*100),
+	}
+	var limits, inFlights, rtts, drops []lineSpec
+	for i, s := range all {
+		color := palette[i%len(palette)]
+		buckets := bucketize(s, start, width, count, percentile)
+		limitPoints := make([]point, 0, count)
+		inFlightPoints := make([]point, 0, count)
+		minPoints := make([]point, 0, count)
+		avgPoints := make([]point, 0, count)
+		pPoints := make([]point, 0, count)
+		dropPoints := make([]point, 0, count)
+		for idx, b := range buckets {
+			if b == nil {
+				continue
+			}
+			limitPoints = append(limitPoints, point{bucket: idx, value: float64(b.limit)})
+			inFlightPoints = append(inFlightPoints, point{bucket: idx, value: float64(b.inFlight)})
+			minPoints = append(minPoints, point{bucket: idx, value: b.rttMin})
+			avgPoints = append(avgPoints, point{bucket: idx, value: b.rttAvg})
+			pPoints = append(pPoints, point{bucket: idx, value: b.rttP})
+			dropPoints = append(dropPoints, point{bucket: idx, value: float64(b.drops)})
+		}
+		limits = append(limits, lineSpec{name: s.name, color: color, points: limitPoints})
+		inFlights = append(inFlights, lineSpec{name: s.name, color: color, points: inFlightPoints})
+		rtts = append(rtts,
+			lineSpec{name: s.name + " min", color: color, dash: "2,3", points: minPoints},
+			lineSpec{name: s.name + " avg", color: color, points: avgPoints},
+			lineSpec{name: s.name + " " + r.Percentile, color: color, dash: "6,3", points: pPoints},
+		)
+		drops = append(drops, lineSpec{name: s.name, color: color, points: dropPoints})
+		r.Series = append(r.Series, summarize(s, color, percentile))
+	}
+	r.Charts = []chart{
+		newChart("Estimated limit", width, count, limits),
+		newChart("Max in flight", width, count, inFlights),
+		newChart("RTT (ms), dotted min, solid avg, dashed "+r.Percentile, width, count, rtts),
+		newChart("Drops per "+r.Bucket, width, count, drops),
+	}
+	return r
+}
+
+func summarize(s *series, color string, percentile float64) seriesSummary {
+	summary := seriesSummary{
+		Name:       s.name,
+		Color:      color,
+		Samples:    len(s.samples),
+		FirstLimit: s.samples[0].limit,
+		FinalLimit: s.samples[len(s.samples)-1].limit,
+		MinLimit:   s.samples[0].limit,
+		MaxLimit:   s.samples[0].limit,
+	}
+	start, end := s.samples[0].timestamp, s.samples[0].timestamp
+	rtts := make([]float64, 0, len(s.samples))
+	sum := 0.0
+	for _, smpl := range s.samples {
+		if smpl.timestamp < start {
+			start = smpl.timestamp
+		}
+		if smpl.timestamp > end {
+			end = smpl.timestamp
+		}
+		if smpl.limit < summary.MinLimit {
+			summary.MinLimit = smpl.limit
+		}
+		if smpl.limit > summary.MaxLimit {
+			summary.MaxLimit = smpl.limit
+		}
+		if smpl.didDrop {
+			summary.Drops++
+		}
+		rtt := milliseconds(smpl.rtt)
+		rtts = append(rtts, rtt)
+		sum += rtt
+	}
+	sort.Float64s(rtts)
+	summary.Duration = time.Duration(end - start).String()
+	summary.AvgRTT = formatValue(sum/float64(len(rtts))) + "ms"
+	summary.RTT = formatValue(percentileOf(rtts, percentile)) + "ms"
+	return summary
+}
+
+// write renders the report as a self-contained HTML page.
+func (r *report) write(w io.Writer) error {
+	return reportTemplate.Execute(w, r)
+}
+
+var reportTemplate = template.Must(template.New("report").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+<style>
+body { font-family: sans-serif; margin: 20px; color: #333; }
+table { border-collapse: collapse; margin-bottom: 20px; }
+th, td { padding: 4px 10px; text-align: right; border-bottom: 1px solid #ddd; }
+th:first-child, td:first-child { text-align: left; }
+.swatch { display: inline-block; width: 12px; height: 12px; margin-right: 6px; }
+svg text { font-size: 11px; fill: #666; }
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+<table>
+<tr><th>series</th><th>samples</th><th>duration</th><th>first limit</th><th>final limit</th><th>min limit</th><th>max limit</th><th>drops</th><th>avg rtt</th><th>{{.Percentile}} rtt</th></tr>
+{{range .Series}}<tr><td><svg class="swatch" width="12" height="12"><rect width="12" height="12" fill="{{.Color}}"/></svg>{{.Name}}</td><td>{{.Samples}}</td><td>{{.Duration}}</td><td>{{.FirstLimit}}</td><td>{{.FinalLimit}}</td><td>{{.MinLimit}}</td><td>{{.MaxLimit}}</td><td>{{.Drops}}</td><td>{{.AvgRTT}}</td><td>{{.RTT}}</td></tr>
+{{end}}</table>
+<p>Samples are aggregated into buckets of {{.Bucket}}, limits are the last value in a bucket and in flight the maximum.</p>
+{{range .Charts}}{{$chart := .}}<h2>{{.Title}}</h2>
+<svg xmlns="http://www.w3.org/2000/svg" width="{{.Width}}" height="{{.Height}}" viewBox="0 0 {{.Width}} {{.Height}}">
+{{range .YTicks}}<line x1="{{$chart.Left}}" x2="{{$chart.Right}}" y1="{{.Pos}}" y2="{{.Pos}}" stroke="#eee"/>
+<text x="{{$chart.Left}}" y="{{.Pos}}" dx="-6" dy="4" text-anchor="end">{{.Label}}</text>
+{{end}}{{range .XTicks}}<text x="{{.Pos}}" y="{{$chart.Bottom}}" dy="16" text-anchor="middle">{{.Label}}</text>
+{{end}}<line x1="{{.Left}}" x2="{{.Right}}" y1="{{.Bottom}}" y2="{{.Bottom}}" stroke="#999"/>
+<line x1="{{.Left}}" x2="{{.Left}}" y1="{{.Top}}" y2="{{.Bottom}}" stroke="#999"/>
+{{range .Lines}}<polyline fill="none" stroke="{{.Color}}" stroke-width="1.5"{{if .Dash}} stroke-dasharray="{{.Dash}}"{{end}} points="{{.Points}}"><title>{{.Name}}</title></polyline>
+{{end}}</svg>
+{{end}}</body>
+</html>
+`))
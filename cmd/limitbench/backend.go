@@ -0,0 +1,129 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// latencyPoint is the backend latency at a given concurrency.
+type latencyPoint struct {
+	concurrency int
+	latency     time.Duration
+}
+
+// latencyCurve is a piecewise linear curve of the backend latency as a function of its concurrency, held flat before
+// the first and after the last point.
+type latencyCurve []latencyPoint
+
+// parseLatencyCurve parses a curve of the form "concurrency:latency,...", i.e. "0:10ms,50:10ms,100:100ms".
+func parseLatencyCurve(value string) (latencyCurve, error) {
+	var curve latencyCurve
+	for _, part := range strings.Split(value, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		fields := strings.SplitN(part, ":", 2)
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("invalid latency curve point %q, expected concurrency:latency", part)
+		}
+		concurrency, err := strconv.Atoi(fields[0])
+		if err != nil || concurrency < 0 {
+			return nil, fmt.Errorf("invalid latency curve concurrency %q", fields[0])
+		}
+		latency, err := time.ParseDuration(fields[1])
+		if err != nil || latency < 0 {
+			return nil, fmt.Errorf("invalid latency curve latency %q", fields[1])
+		}
+		curve = append(curve, latencyPoint{concurrency: concurrency, latency: latency})
+	}
+	if len(curve) == 0 {
+		return nil, fmt.Errorf("latency curve must have at least one point")
+	}
+	sort.Slice(curve, func(i, j int) bool {
+		return curve[i].concurrency < curve[j].concurrency
+	})
+	return curve, nil
+}
+
+// latency returns the latency at the given concurrency.
+func (c latencyCurve) latency(concurrency int) time.Duration {
+	if concurrency <= c[0].concurrency {
+		return c[0].latency
+	}
+	for i := 1; i < len(c); i++ {
+		if concurrency <= c[i].concurrency {
+			prev := c[i-1]
+			fraction := float64(concurrency-prev.concurrency) / float64(c[i].concurrency-prev.concurrency)
+			return prev.latency + time.Duration(fraction*float64(c[i].latency-prev.latency))
+		}
+	}
+	return c[len(c)-1].latency
+}
+
+// backend is an in-process fake of the protected resource.  Its latency follows the latency curve of its current
+// concurrency, with jitter, and a fraction of the requests fail.
+type backend struct {
+	curve     latencyCurve
+	jitter    float64
+	errorRate float64
+	inFlight  int64
+	random    *rand.Rand
+	mu        sync.Mutex
+}
+
+func newBackend(curve latencyCurve, jitter float64, errorRate float64, seed int64) *backend {
+	return &backend{
+		curve:     curve,
+		jitter:    jitter,
+		errorRate: errorRate,
+		random:    rand.New(rand.NewSource(seed)),
+	}
+}
+
+// errInjected is the failure of the backend's injected errors.
+var errInjected = errors.New("injected error")
+
+// call serves a request, blocking for its latency or until ctx is done, and returns errInjected for injected failures
+// or the error of ctx if it was done first, in which case the backend abandons the request.
+func (b *backend) call(ctx context.Context) error {
+	latency, failed := b.start()
+	defer b.finish()
+
+	timer := time.NewTimer(latency)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	if failed {
+		return errInjected
+	}
+	return nil
+}
+
+// start begins serving a request and returns its latency at the backend's concurrency and whether it fails, finish
+// must be called once the request completes or is abandoned.
+func (b *backend) start() (time.Duration, bool) {
+	concurrency := int(atomic.AddInt64(&b.inFlight, 1))
+
+	b.mu.Lock()
+	jitter := 1 + b.jitter*(2*b.random.Float64()-1)
+	failed := b.random.Float64() < b.errorRate
+	b.mu.Unlock()
+
+	return time.Duration(float64(b.curve.latency(concurrency)) * jitter), failed
+}
+
+func (b *backend) finish() {
+	atomic.AddInt64(&b.inFlight, -1)
+}
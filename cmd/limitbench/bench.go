@@ -0,0 +1,314 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/platinummonkey/go-concurrency-limits/core"
+	"github.com/platinummonkey/go-concurrency-limits/limit"
+	"github.com/platinummonkey/go-concurrency-limits/simulation"
+)
+
+// convergenceBand is how close, relative to the final limit, the limit must stay to be considered converged.
+const convergenceBand = 0.1
+
+type limitPoint struct {
+	elapsed time.Duration
+	limit   int
+}
+
+// bench is an open loop load generator sending requests through the limiter to the backend, in real time or on a
+// virtual clock.
+type bench struct {
+	cfg     config
+	backend *backend
+	limiter core.Limiter
+	limit   core.Limit
+	virtual *simulation.VirtualClock
+	clock   limit.Clock
+
+	start       int64
+	outstanding int
+	requests    int
+	overflow    int
+	rejected    int
+	succeeded   int
+	errors      int
+	timedOut    int
+	latencies   []time.Duration
+	timeline    []limitPoint
+	mu          sync.Mutex
+}
+
+// newBench will create a new bench, running on the virtual clock if not nil and in real time otherwise.
+func newBench(
+	cfg config,
+	backend *backend,
+	limiter core.Limiter,
+	l core.Limit,
+	virtual *simulation.VirtualClock,
+) *bench {
+	b := &bench{
+		cfg:     cfg,
+		backend: backend,
+		limiter: limiter,
+		limit:   l,
+		virtual: virtual,
+		clock:   limit.SystemClock,
+	}
+	if virtual != nil {
+		b.clock = virtual.Now
+	}
+	return b
+}
+
+func (b *bench) run() *report {
+	b.start = b.clock()
+	b.recordLimit(b.limit.EstimatedLimit())
+	subscription := b.limit.NotifyOnChange(b.recordLimit)
+	defer subscription.Cancel()
+
+	random := rand.New(rand.NewSource(b.cfg.Seed))
+	if b.virtual != nil {
+		b.runVirtual(random)
+	} else {
+		b.runRealTime(random)
+	}
+	return b.report(time.Duration(b.cfg.Duration))
+}
+
+// runRealTime sends the requests on the wall clock, each from its own goroutine.
+func (b *bench) runRealTime(random *rand.Rand) {
+	var wg sync.WaitGroup
+	for next, ok := b.nextArrival(random, b.start); ok; next, ok = b.nextArrival(random, next) {
+		time.Sleep(time.Duration(next - b.clock()))
+		if !b.admit() {
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			b.issue()
+		}()
+	}
+	wg.Wait()
+}
+
+// runVirtual sends the requests on the virtual clock.  The arrivals and the completions of the backend are events run
+// one at a time in time order, so the run doesn't depend on scheduling and is reproducible for a given seed.
+func (b *bench) runVirtual(random *rand.Rand) {
+	loop := &eventLoop{clock: b.virtual}
+	var arrive func()
+	arrive = func() {
+		if next, ok := b.nextArrival(random, b.clock()); ok {
+			loop.schedule(next, arrive)
+		}
+		if b.admit() {
+			b.issueVirtual(loop)
+		}
+	}
+	if next, ok := b.nextArrival(random, b.start); ok {
+		loop.schedule(next, arrive)
+	}
+	loop.run()
+}
+
+// nextArrival returns the arrival time following prev in the Poisson process, or false once past the duration.
+func (b *bench) nextArrival(random *rand.Rand, prev int64) (int64, bool) {
+	if b.cfg.RPS <= 0 {
+		return 0, false
+	}
+	next := prev + int64(random.ExpFloat64()/b.cfg.RPS*1e9)
+	return next, next-b.start < time.Duration(b.cfg.Duration).Nanoseconds()
+}
+
+// admit counts an arriving request and returns whether it fits in the load generator's outstanding requests.
+func (b *bench) admit() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.requests++
+	if b.outstanding >= b.cfg.MaxOutstanding {
+		b.overflow++
+		return false
+	}
+	b.outstanding++
+	return true
+}
+
+// acquire acquires a token from the limiter for an admitted request, counting it as rejected if it wasn't acquired.
+func (b *bench) acquire() (core.Listener, bool) {
+	listener, ok := b.limiter.Acquire(context.Background())
+	if !ok {
+		b.mu.Lock()
+		b.outstanding--
+		b.rejected++
+		b.mu.Unlock()
+	}
+	return listener, ok
+}
+
+// issue sends a single request in real time, cancelling it once it exceeds the timeout.
+func (b *bench) issue() {
+	listener, ok := b.acquire()
+	if !ok {
+		return
+	}
+	ctx := context.Background()
+	if b.cfg.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, time.Duration(b.cfg.Timeout))
+		defer cancel()
+	}
+	start := b.clock()
+	err := b.backend.call(ctx)
+	b.complete(listener, time.Duration(b.clock()-start), err)
+}
+
+// issueVirtual sends a single request on the virtual clock, completing it after its latency or the timeout, whichever
+// is first.
+func (b *bench) issueVirtual(loop *eventLoop) {
+	listener, ok := b.acquire()
+	if !ok {
+		return
+	}
+	latency, failed := b.backend.start()
+	var err error
+	switch {
+	case b.cfg.Timeout > 0 && latency > time.Duration(b.cfg.Timeout):
+		latency = time.Duration(b.cfg.Timeout)
+		err = context.DeadlineExceeded
+	case failed:
+		err = errInjected
+	}
+	loop.schedule(b.clock()+latency.Nanoseconds(), func() {
+		b.backend.finish()
+		b.complete(listener, latency, err)
+	})
+}
+
+// complete releases a request that took latency.  Requests cancelled by the timeout and injected errors are both
+// reported to the limiter as dropped.
+func (b *bench) complete(listener core.Listener, latency time.Duration, err error) {
+	b.mu.Lock()
+	b.outstanding--
+	switch {
+	case err == context.DeadlineExceeded:
+		b.timedOut++
+		b.mu.Unlock()
+		listener.OnDropped()
+	case err != nil:
+		b.errors++
+		b.mu.Unlock()
+		listener.OnDropped()
+	default:
+		b.succeeded++
+		b.latencies = append(b.latencies, latency)
+		b.mu.Unlock()
+		listener.OnSuccess()
+	}
+}
+
+func (b *bench) recordLimit(newLimit int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if len(b.timeline) > 0 && b.timeline[len(b.timeline)-1].limit == newLimit {
+		return
+	}
+	b.timeline = append(b.timeline, limitPoint{elapsed: time.Duration(b.clock() - b.start), limit: newLimit})
+}
+
+func (b *bench) report(duration time.Duration) *report {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	sort.Slice(b.latencies, func(i, j int) bool {
+		return b.latencies[i] < b.latencies[j]
+	})
+	finalLimit := b.timeline[len(b.timeline)-1].limit
+	return &report{
+		Limit:              b.cfg.Limit,
+		DurationSeconds:    duration.Seconds(),
+		Requests:           b.requests,
+		Succeeded:          b.succeeded,
+		Rejected:           b.rejected,
+		Errors:             b.errors,
+		TimedOut:           b.timedOut,
+		Overflow:           b.overflow,
+		Throughput:         float64(b.succeeded) / duration.Seconds(),
+		LatencyP50Ms:       milliseconds(percentile(b.latencies, 0.5)),
+		LatencyP99Ms:       milliseconds(percentile(b.latencies, 0.99)),
+		FinalLimit:         finalLimit,
+		LimitChanges:       len(b.timeline) - 1,
+		ConvergenceSeconds: convergenceTime(b.timeline, finalLimit).Seconds(),
+	}
+}
+
+// convergenceTime returns the time from which the limit stayed within the convergence band around the final limit.
+func convergenceTime(timeline []limitPoint, finalLimit int) time.Duration {
+	band := math.Max(1, float64(finalLimit)*convergenceBand)
+	converged := timeline[len(timeline)-1].elapsed
+	for i := len(timeline) - 1; i >= 0; i-- {
+		if math.Abs(float64(timeline[i].limit-finalLimit)) > band {
+			break
+		}
+		converged = timeline[i].elapsed
+	}
+	return converged
+}
+
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	idx := int(math.Ceil(p*float64(len(sorted)))) - 1
+	if idx < 0 {
+		idx = 0
+	}
+	return sorted[idx]
+}
+
+func milliseconds(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+// report is the outcome of a benchmark.
+type report struct {
+	Limit              string  `json:"limit"`
+	DurationSeconds    float64 `json:"durationSeconds"`
+	Requests           int     `json:"requests"`
+	Succeeded          int     `json:"succeeded"`
+	Rejected           int     `json:"rejected"`
+	Errors             int     `json:"errors"`
+	TimedOut           int     `json:"timedOut"`
+	Overflow           int     `json:"overflow"`
+	Throughput         float64 `json:"throughput"`
+	LatencyP50Ms       float64 `json:"latencyP50Ms"`
+	LatencyP99Ms       float64 `json:"latencyP99Ms"`
+	FinalLimit         int     `json:"finalLimit"`
+	LimitChanges       int     `json:"limitChanges"`
+	ConvergenceSeconds float64 `json:"convergenceSeconds"`
+}
+
+func (r *report) String() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "limit:        %s\n", r.Limit)
+	fmt.Fprintf(&sb, "duration:     %0.2fs\n", r.DurationSeconds)
+	fmt.Fprintf(&sb, "requests:     %d\n", r.Requests)
+	fmt.Fprintf(&sb, "succeeded:    %d\n", r.Succeeded)
+	fmt.Fprintf(&sb, "rejected:     %d\n", r.Rejected)
+	fmt.Fprintf(&sb, "errors:       %d\n", r.Errors)
+	fmt.Fprintf(&sb, "timed out:    %d\n", r.TimedOut)
+	fmt.Fprintf(&sb, "overflow:     %d\n", r.Overflow)
+	fmt.Fprintf(&sb, "throughput:   %0.2f/s\n", r.Throughput)
+	fmt.Fprintf(&sb, "latency p50:  %0.2fms\n", r.LatencyP50Ms)
+	fmt.Fprintf(&sb, "latency p99:  %0.2fms\n", r.LatencyP99Ms)
+	fmt.Fprintf(&sb, "final limit:  %d\n", r.FinalLimit)
+	fmt.Fprintf(&sb, "changes:      %d\n", r.LimitChanges)
+	fmt.Fprintf(&sb, "convergence:  %0.2fs\n", r.ConvergenceSeconds)
+	return sb.String()
+}
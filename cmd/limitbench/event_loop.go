@@ -0,0 +1,61 @@
+package main
+
+import (
+	"container/heap"
+	"time"
+
+	"github.com/platinummonkey/go-concurrency-limits/simulation"
+)
+
+// eventLoop runs functions scheduled on a virtual clock in time order, advancing the clock to each event.
+type eventLoop struct {
+	clock  *simulation.VirtualClock
+	events eventQueue
+	seq    int64
+}
+
+// schedule runs fn at the given virtual time.
+func (l *eventLoop) schedule(at int64, fn func()) {
+	l.seq++
+	heap.Push(&l.events, &event{time: at, seq: l.seq, fn: fn})
+}
+
+// run runs the events until none are left.
+func (l *eventLoop) run() {
+	for l.events.Len() > 0 {
+		e := heap.Pop(&l.events).(*event)
+		l.clock.Advance(time.Duration(e.time - l.clock.Now()))
+		e.fn()
+	}
+}
+
+type event struct {
+	time int64
+	seq  int64
+	fn   func()
+}
+
+// eventQueue orders the events by time, events at the same time in the order they were scheduled.
+type eventQueue []*event
+
+func (q eventQueue) Len() int { return len(q) }
+
+func (q eventQueue) Less(i, j int) bool {
+	if q[i].time == q[j].time {
+		return q[i].seq < q[j].seq
+	}
+	return q[i].time < q[j].time
+}
+
+func (q eventQueue) Swap(i, j int) { q[i], q[j] = q[j], q[i] }
+
+func (q *eventQueue) Push(x interface{}) { *q = append(*q, x.(*event)) }
+
+func (q *eventQueue) Pop() interface{} {
+	old := *q
+	n := len(old)
+	e := old[n-1]
+	old[n-1] = nil
+	*q = old[:n-1]
+	return e
+}
@@ -0,0 +1,230 @@
+// Command limitbench load tests a limiter against an in-process fake backend and reports the throughput, latency,
+// rejections and how long the limit took to converge.  By default requests run in real time on the wall clock, so
+// scheduling decides the order the backend draws its jitter and injected errors in and the results vary from run to
+// run.  With -virtual the load and the backend run on a virtual clock from the simulation package instead: the run
+// takes a fraction of its duration and the same flags and seed give the same report.
+//
+// The backend's latency follows a piecewise linear curve of its concurrency, for example a backend that starts
+// queueing beyond 50 concurrent requests:
+//
+//	limitbench -limit gradient2 -rps 2000 -latency-curve 0:10ms,50:10ms,100:50ms,200:200ms -duration 30s
+//
+// The settings can also be read from a JSON config file, flags take precedence:
+//
+//	limitbench -config bench.json -format json
+//
+// Or reproducibly on the virtual clock:
+//
+//	limitbench -virtual -seed 42 -limit vegas -rps 2000 -duration 5m
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"time"
+
+	"github.com/platinummonkey/go-concurrency-limits/core"
+	"github.com/platinummonkey/go-concurrency-limits/limit"
+	"github.com/platinummonkey/go-concurrency-limits/limiter"
+	"github.com/platinummonkey/go-concurrency-limits/simulation"
+	"github.com/platinummonkey/go-concurrency-limits/strategy"
+)
+
+// config describes the benchmark, it's populated from the JSON config file and flags.
+type config struct {
+	Duration       duration `json:"duration"`
+	RPS            float64  `json:"rps"`
+	MaxOutstanding int      `json:"maxOutstanding"`
+	Timeout        duration `json:"timeout"`
+	LatencyCurve   string   `json:"latencyCurve"`
+	Jitter         float64  `json:"jitter"`
+	ErrorRate      float64  `json:"errorRate"`
+	Seed           int64    `json:"seed"`
+	Limit          string   `json:"limit"`
+	InitialLimit   int      `json:"initialLimit"`
+	MinLimit       int      `json:"minLimit"`
+	MaxLimit       int      `json:"maxLimit"`
+	MinWindow      duration `json:"minWindow"`
+	MaxWindow      duration `json:"maxWindow"`
+	WindowSize     int      `json:"windowSize"`
+	Format         string   `json:"format"`
+	Virtual        bool     `json:"virtual"`
+}
+
+func defaultConfig() config {
+	return config{
+		Duration:       duration(10 * time.Second),
+		RPS:            1000,
+		MaxOutstanding: 10000,
+		Timeout:        duration(time.Second),
+		LatencyCurve:   "0:10ms,50:10ms,100:50ms,200:200ms",
+		Jitter:         0.1,
+		Seed:           1,
+		Limit:          "gradient2",
+		InitialLimit:   20,
+		MinLimit:       1,
+		MaxLimit:       1000,
+		MinWindow:      duration(100 * time.Millisecond),
+		MaxWindow:      duration(time.Second),
+		WindowSize:     10,
+		Format:         "text",
+	}
+}
+
+// duration is a time.Duration read from JSON as a string, i.e. "10s".
+type duration time.Duration
+
+func (d *duration) UnmarshalJSON(data []byte) error {
+	var value string
+	if err := json.Unmarshal(data, &value); err != nil {
+		return fmt.Errorf("duration must be a string: %v", err)
+	}
+	parsed, err := time.ParseDuration(value)
+	if err != nil {
+		return err
+	}
+	*d = duration(parsed)
+	return nil
+}
+
+func (d duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+func main() {
+	if err := run(os.Args[1:], os.Stdout, os.Stderr); err != nil {
+		fmt.Fprintf(os.Stderr, "limitbench: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func run(args []string, stdout io.Writer, stderr io.Writer) error {
+	cfg, err := parseConfig(args, stderr)
+	if err != nil {
+		return err
+	}
+	curve, err := parseLatencyCurve(cfg.LatencyCurve)
+	if err != nil {
+		return err
+	}
+	if cfg.Format != "text" && cfg.Format != "json" {
+		return fmt.Errorf("unknown format %q", cfg.Format)
+	}
+	l, err := newLimit(cfg)
+	if err != nil {
+		return err
+	}
+	clock := limit.SystemClock
+	var virtual *simulation.VirtualClock
+	if cfg.Virtual {
+		virtual = simulation.NewVirtualClock(0)
+		clock = virtual.Now
+	}
+	lim, err := limiter.NewDefaultLimiterWithClock(
+		l,
+		time.Duration(cfg.MinWindow).Nanoseconds(),
+		time.Duration(cfg.MaxWindow).Nanoseconds(),
+		0,
+		cfg.WindowSize,
+		strategy.NewSimpleStrategy(cfg.InitialLimit),
+		clock,
+		nil,
+	)
+	if err != nil {
+		return err
+	}
+
+	b := newBench(cfg, newBackend(curve, cfg.Jitter, cfg.ErrorRate, cfg.Seed), lim, l, virtual)
+	r := b.run()
+	if cfg.Format == "json" {
+		encoder := json.NewEncoder(stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(r)
+	}
+	_, err = io.WriteString(stdout, r.String())
+	return err
+}
+
+// parseConfig applies the config file, if any, over the defaults and the flags explicitly set over the config file.
+func parseConfig(args []string, stderr io.Writer) (config, error) {
+	cfg := defaultConfig()
+	var configPath string
+	flags := flag.NewFlagSet("limitbench", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.StringVar(&configPath, "config", "", "JSON config file, flags take precedence")
+	flags.DurationVar((*time.Duration)(&cfg.Duration), "duration", time.Duration(cfg.Duration), "duration of the load")
+	flags.Float64Var(&cfg.RPS, "rps", cfg.RPS, "requests per second, arriving as a Poisson process")
+	flags.IntVar(&cfg.MaxOutstanding, "max-outstanding", cfg.MaxOutstanding,
+		"maximum outstanding requests of the load generator, further requests are counted as overflow")
+	flags.DurationVar((*time.Duration)(&cfg.Timeout), "timeout", time.Duration(cfg.Timeout),
+		"client timeout, slower requests are cancelled and reported as dropped, 0 disables it")
+	flags.StringVar(&cfg.LatencyCurve, "latency-curve", cfg.LatencyCurve,
+		"backend latency as a function of its concurrency, concurrency:latency,...")
+	flags.Float64Var(&cfg.Jitter, "jitter", cfg.Jitter, "relative jitter of the backend latency, [0,1]")
+	flags.Float64Var(&cfg.ErrorRate, "error-rate", cfg.ErrorRate, "fraction of backend requests failing, [0,1]")
+	flags.Int64Var(&cfg.Seed, "seed", cfg.Seed,
+		"seed of the load and backend random sources, runs are only reproducible with -virtual")
+	flags.StringVar(&cfg.Limit, "limit", cfg.Limit, "limit algorithm: aimd, fixed, gradient, gradient2 or vegas")
+	flags.IntVar(&cfg.InitialLimit, "initial-limit", cfg.InitialLimit, "initial limit")
+	flags.IntVar(&cfg.MinLimit, "min-limit", cfg.MinLimit, "minimum limit")
+	flags.IntVar(&cfg.MaxLimit, "max-limit", cfg.MaxLimit, "maximum limit")
+	flags.DurationVar((*time.Duration)(&cfg.MinWindow), "min-window", time.Duration(cfg.MinWindow),
+		"minimum sampling window of the limiter")
+	flags.DurationVar((*time.Duration)(&cfg.MaxWindow), "max-window", time.Duration(cfg.MaxWindow),
+		"maximum sampling window of the limiter")
+	flags.IntVar(&cfg.WindowSize, "window-size", cfg.WindowSize, "minimum samples per sampling window, >= 10")
+	flags.StringVar(&cfg.Format, "format", cfg.Format, "report format: text or json")
+	flags.BoolVar(&cfg.Virtual, "virtual", cfg.Virtual, "run on a virtual clock, reproducible for a given seed")
+	if err := flags.Parse(args); err != nil {
+		return cfg, err
+	}
+	if configPath == "" {
+		return cfg, nil
+	}
+
+	set := make(map[string]string)
+	flags.Visit(func(f *flag.Flag) {
+		set[f.Name] = f.Value.String()
+	})
+	data, err := ioutil.ReadFile(configPath)
+	if err != nil {
+		return cfg, err
+	}
+	cfg = defaultConfig()
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return cfg, fmt.Errorf("invalid config %s: %v", configPath, err)
+	}
+	// the flags are bound to cfg, setting them again applies them over the config file
+	for name, value := range set {
+		if err := flags.Set(name, value); err != nil {
+			return cfg, err
+		}
+	}
+	return cfg, nil
+}
+
+func newLimit(cfg config) (core.Limit, error) {
+	switch cfg.Limit {
+	case "aimd":
+		// aimd has no bounds of its own, the estimate is clamped instead
+		aimd := limit.NewAIMDLimit("limitbench", cfg.InitialLimit, 0.9)
+		return limit.NewBoundedLimit(aimd, cfg.MinLimit, cfg.MaxLimit, 0, 0)
+	case "fixed":
+		return limit.NewFixedLimit("limitbench", cfg.InitialLimit), nil
+	case "gradient":
+		return limit.NewGradientLimitWithRegistry("limitbench", cfg.InitialLimit, cfg.MinLimit, cfg.MaxLimit,
+			-1, nil, -1, 0, nil), nil
+	case "gradient2":
+		return limit.NewGradient2Limit("limitbench", cfg.InitialLimit, cfg.MaxLimit, cfg.MinLimit, nil, -1, 600, nil)
+	case "vegas":
+		// vegas has no minimum limit of its own, the estimate is clamped as for aimd
+		vegas := limit.NewVegasLimitWithRegistry("limitbench", cfg.InitialLimit, nil, cfg.MaxLimit, -1,
+			nil, nil, nil, nil, nil, -1, nil)
+		return limit.NewBoundedLimit(vegas, cfg.MinLimit, cfg.MaxLimit, 0, 0)
+	}
+	return nil, fmt.Errorf("unknown limit %q", cfg.Limit)
+}
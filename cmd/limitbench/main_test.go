@@ -0,0 +1,188 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLatencyCurve(t *testing.T) {
+	t.Parallel()
+	asrt := assert.New(t)
+
+	curve, err := parseLatencyCurve("100:100ms, 0:10ms,50:10ms")
+	asrt.NoError(err)
+	asrt.Equal(10*time.Millisecond, curve.latency(0))
+	asrt.Equal(10*time.Millisecond, curve.latency(50))
+	asrt.Equal(55*time.Millisecond, curve.latency(75))
+	asrt.Equal(100*time.Millisecond, curve.latency(100))
+	asrt.Equal(100*time.Millisecond, curve.latency(1000))
+
+	for _, value := range []string{"", "10", "a:10ms", "-1:10ms", "10:fast", "10:-1ms"} {
+		_, err := parseLatencyCurve(value)
+		asrt.Error(err, value)
+	}
+}
+
+func TestParseConfig(t *testing.T) {
+	t.Parallel()
+
+	t.Run("Defaults", func(t2 *testing.T) {
+		t2.Parallel()
+		asrt := assert.New(t2)
+		cfg, err := parseConfig(nil, ioutil.Discard)
+		asrt.NoError(err)
+		asrt.Equal(defaultConfig(), cfg)
+	})
+
+	t.Run("FlagsOverConfigFile", func(t2 *testing.T) {
+		t2.Parallel()
+		asrt := assert.New(t2)
+		dir, err := ioutil.TempDir("", "limitbench")
+		asrt.NoError(err)
+		defer os.RemoveAll(dir)
+		path := filepath.Join(dir, "bench.json")
+		asrt.NoError(ioutil.WriteFile(path,
+			[]byte(`{"duration":"2s","rps":50,"limit":"vegas","maxLimit":200}`), 0644))
+
+		cfg, err := parseConfig([]string{"-rps", "75", "-config", path}, ioutil.Discard)
+		asrt.NoError(err)
+		asrt.Equal(duration(2*time.Second), cfg.Duration)
+		asrt.Equal(75.0, cfg.RPS)
+		asrt.Equal("vegas", cfg.Limit)
+		asrt.Equal(200, cfg.MaxLimit)
+		asrt.Equal(defaultConfig().InitialLimit, cfg.InitialLimit)
+	})
+
+	t.Run("InvalidConfigFile", func(t2 *testing.T) {
+		t2.Parallel()
+		asrt := assert.New(t2)
+		dir, err := ioutil.TempDir("", "limitbench")
+		asrt.NoError(err)
+		defer os.RemoveAll(dir)
+		path := filepath.Join(dir, "bench.json")
+		asrt.NoError(ioutil.WriteFile(path, []byte(`{"duration":10}`), 0644))
+
+		_, err = parseConfig([]string{"-config", path}, ioutil.Discard)
+		asrt.Error(err)
+		_, err = parseConfig([]string{"-config", filepath.Join(dir, "missing.json")}, ioutil.Discard)
+		asrt.Error(err)
+	})
+}
+
+func TestRun(t *testing.T) {
+	t.Parallel()
+
+	t.Run("Invalid", func(t2 *testing.T) {
+		t2.Parallel()
+		asrt := assert.New(t2)
+		for _, args := range [][]string{
+			{"-limit", "unknown"},
+			{"-format", "xml"},
+			{"-latency-curve", "fast"},
+		} {
+			asrt.Error(run(args, ioutil.Discard, ioutil.Discard), strings.Join(args, " "))
+		}
+	})
+
+	t.Run("Text", func(t2 *testing.T) {
+		t2.Parallel()
+		asrt := assert.New(t2)
+		var stdout bytes.Buffer
+		err := run([]string{"-duration", "200ms", "-rps", "200", "-latency-curve", "0:1ms", "-limit", "aimd"},
+			&stdout, ioutil.Discard)
+		asrt.NoError(err)
+		asrt.Contains(stdout.String(), "limit:        aimd\n")
+		asrt.Contains(stdout.String(), "throughput:")
+		asrt.Contains(stdout.String(), "convergence:")
+	})
+
+	t.Run("JSON", func(t2 *testing.T) {
+		t2.Parallel()
+		asrt := assert.New(t2)
+		var stdout bytes.Buffer
+		err := run([]string{"-duration", "200ms", "-rps", "200", "-latency-curve", "0:1ms", "-error-rate", "0.5",
+			"-limit", "fixed", "-initial-limit", "100", "-format", "json"}, &stdout, ioutil.Discard)
+		asrt.NoError(err)
+		var r report
+		asrt.NoError(json.Unmarshal(stdout.Bytes(), &r))
+		asrt.Equal("fixed", r.Limit)
+		asrt.True(r.Requests > 0)
+		asrt.Equal(r.Requests, r.Succeeded+r.Errors+r.Rejected+r.TimedOut+r.Overflow)
+		asrt.True(r.Errors > 0)
+		asrt.Equal(100, r.FinalLimit)
+		asrt.Equal(0, r.LimitChanges)
+	})
+	t.Run("VirtualReproducible", func(t2 *testing.T) {
+		t2.Parallel()
+		asrt := assert.New(t2)
+		args := []string{"-virtual", "-duration", "30s", "-rps", "500", "-seed", "42", "-error-rate", "0.01",
+			"-limit", "vegas", "-format", "json"}
+		var first, second bytes.Buffer
+		asrt.NoError(run(args, &first, ioutil.Discard))
+		asrt.NoError(run(args, &second, ioutil.Discard))
+		asrt.Equal(first.String(), second.String())
+
+		var r report
+		asrt.NoError(json.Unmarshal(first.Bytes(), &r))
+		asrt.True(r.Requests > 10000)
+		asrt.Equal(r.Requests, r.Succeeded+r.Errors+r.Rejected+r.TimedOut+r.Overflow)
+		asrt.True(r.LimitChanges > 0)
+	})
+
+	t.Run("MinLimit", func(t2 *testing.T) {
+		t2.Parallel()
+		asrt := assert.New(t2)
+		for _, algorithm := range []string{"aimd", "vegas"} {
+			var stdout bytes.Buffer
+			// the injected errors are drops in every window, backing the limit off to the minimum
+			err := run([]string{"-virtual", "-duration", "10s", "-rps", "200", "-latency-curve", "0:1ms",
+				"-error-rate", "0.5", "-limit", algorithm, "-min-limit", "15", "-format", "json"}, &stdout, ioutil.Discard)
+			asrt.NoError(err)
+			var r report
+			asrt.NoError(json.Unmarshal(stdout.Bytes(), &r))
+			asrt.Equal(15, r.FinalLimit, algorithm)
+		}
+	})
+
+	t.Run("Timeout", func(t2 *testing.T) {
+		t2.Parallel()
+		asrt := assert.New(t2)
+		for _, mode := range []string{"-virtual=false", "-virtual"} {
+			var stdout bytes.Buffer
+			start := time.Now()
+			// the backend is far slower than the timeout, the requests are cancelled rather than waited for
+			err := run([]string{mode, "-duration", "100ms", "-rps", "100", "-latency-curve", "0:10s",
+				"-timeout", "20ms", "-limit", "fixed", "-format", "json"}, &stdout, ioutil.Discard)
+			asrt.NoError(err)
+			asrt.True(time.Since(start) < 5*time.Second, mode)
+			var r report
+			asrt.NoError(json.Unmarshal(stdout.Bytes(), &r))
+			asrt.True(r.TimedOut > 0, mode)
+			asrt.Equal(r.Requests, r.TimedOut+r.Rejected+r.Overflow, mode)
+		}
+	})
+}
+
+func TestConvergenceTime(t *testing.T) {
+	t.Parallel()
+	asrt := assert.New(t)
+
+	timeline := []limitPoint{
+		{elapsed: 0, limit: 20},
+		{elapsed: time.Second, limit: 60},
+		{elapsed: 2 * time.Second, limit: 95},
+		{elapsed: 3 * time.Second, limit: 120},
+		{elapsed: 4 * time.Second, limit: 98},
+		{elapsed: 5 * time.Second, limit: 100},
+	}
+	asrt.Equal(4*time.Second, convergenceTime(timeline, 100))
+	asrt.Equal(time.Duration(0), convergenceTime(timeline[:1], 20))
+}
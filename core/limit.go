@@ -36,17 +36,31 @@ type SampleWindow interface {
 	DidDrop() bool
 }
 
+// SampleWindowDropCounter is optionally implemented by a SampleWindow that counts its dropped samples.
+type SampleWindowDropCounter interface {
+	// DropCount is the number of dropped samples in the sample window, these are not included in SampleCount.
+	DropCount() int
+}
+
 // LimitChangeListener is a callback method to receive a notification whenever the limit is updated to a new value.
 type LimitChangeListener func(limit int)
 
+// LimitChangeSubscription is a handle to a LimitChangeListener registered via Limit.NotifyOnChange.
+type LimitChangeSubscription interface {
+	// Cancel unregisters the listener.  A notification already being dispatched may still be delivered.
+	Cancel()
+}
+
 // Limit is a Contract for an algorithm that calculates a concurrency limit based on rtt measurements.
 type Limit interface {
 	// EstimatedLimit returns the current estimated limit.
 	EstimatedLimit() int
 
 	// NotifyOnChange will register a callback to receive notification whenever the limit is updated to a new value.
+	// Callbacks are invoked outside of the limit's lock so they may call back into the limit.
 	// @consumer the callback
-	NotifyOnChange(consumer LimitChangeListener)
+	// returns a subscription to unregister the callback.
+	NotifyOnChange(consumer LimitChangeListener) LimitChangeSubscription
 
 	// OnSample the concurrency limit using a new rtt sample.
 	// @startTime in epoch nanoseconds
@@ -56,6 +70,18 @@ type Limit interface {
 	OnSample(startTime int64, rtt int64, inFlight int, didDrop bool)
 }
 
+// SampleWindowLimit is a Limit that is updated with the aggregated sample window rather than a single sample, for
+// algorithms that need the number of samples and drops in the window.  Limiters aggregating samples into windows use
+// OnSampleWindow in place of OnSample when the Limit implements it.
+type SampleWindowLimit interface {
+	Limit
+
+	// OnSampleWindow updates the concurrency limit using the samples aggregated in a window.
+	// @startTime in epoch nanoseconds
+	// @window the aggregated samples
+	OnSampleWindow(startTime int64, window SampleWindow)
+}
+
 // Listener implements token listener for callback to the limiter when and how it should be released.
 type Listener interface {
 	// OnSuccess is called as a notification that the operation succeeded and internally measured latency should be
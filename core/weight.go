@@ -0,0 +1,48 @@
+package core
+
+import (
+	"context"
+)
+
+type weightContextKey struct{}
+
+// WithWeight returns a copy of the context carrying the weight of the request, i.e. the number of limit units a batch
+// RPC or a large upload consumes.  Weights less than 1 are treated as 1.
+func WithWeight(ctx context.Context, weight int) context.Context {
+	return context.WithValue(ctx, weightContextKey{}, weight)
+}
+
+// WeightFromContext returns the weight of the request set with WithWeight, defaults to 1.
+func WeightFromContext(ctx context.Context) int {
+	if ctx == nil {
+		return 1
+	}
+	weight, ok := ctx.Value(weightContextKey{}).(int)
+	if !ok || weight < 1 {
+		return 1
+	}
+	return weight
+}
+
+// WeightedStrategy is a Strategy that supports acquiring several units of the limit with a single token.
+type WeightedStrategy interface {
+	Strategy
+
+	// TryAcquireWeighted will try to acquire weight units of the limit.  The returned token releases all of them.
+	// context Context of the request for partitioned limits.
+	// weight The number of units to acquire, values less than 1 are treated as 1.
+	// returns not ok if limit is exceeded, or a StrategyToken that must be released when the operation completes.
+	TryAcquireWeighted(ctx context.Context, weight int) (token StrategyToken, ok bool)
+}
+
+// WeightedLimiter is a Limiter that supports acquiring several units of the limit for expensive requests.
+type WeightedLimiter interface {
+	Limiter
+
+	// AcquireWeighted acquires weight units of the limit.  If acquired the caller must call one of the Listener
+	// methods when the operation has been completed to release all of the units.
+	//
+	// context Context for the request. The context is used by advanced strategies such as LookupPartitionStrategy.
+	// weight The number of units to acquire, values less than 1 are treated as 1.
+	AcquireWeighted(ctx context.Context, weight int) (listener Listener, ok bool)
+}
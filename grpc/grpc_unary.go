@@ -15,7 +15,7 @@ func UnaryServerInterceptor(opts ...InterceptorOption) golangGrpc.UnaryServerInt
 		fn(cfg)
 	}
 	return func(ctx context.Context, req interface{}, info *golangGrpc.UnaryServerInfo, handler golangGrpc.UnaryHandler) (interface{}, error) {
-		token, ok := cfg.limiter.Acquire(ctx)
+		token, ok := cfg.acquire(ctx, info.FullMethod, req)
 		if !ok {
 			errResp, errCode, err := cfg.limitExceededResponseClassifier(ctx, info.FullMethod, req, cfg.limiter)
 			return errResp, status.Error(errCode, err.Error())
@@ -42,7 +42,7 @@ func UnaryClientInterceptor(opts ...InterceptorOption) golangGrpc.UnaryClientInt
 		fn(cfg)
 	}
 	return func(ctx context.Context, method string, req, reply interface{}, cc *golangGrpc.ClientConn, invoker golangGrpc.UnaryInvoker, opts ...golangGrpc.CallOption) error {
-		token, ok := cfg.limiter.Acquire(ctx)
+		token, ok := cfg.acquire(ctx, method, req)
 		if !ok {
 			_, errCode, err := cfg.limitExceededResponseClassifier(ctx, method, req, cfg.limiter)
 			return status.Error(errCode, err.Error())
@@ -0,0 +1,125 @@
+package grpc
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	golangGrpc "google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/platinummonkey/go-concurrency-limits/core"
+	"github.com/platinummonkey/go-concurrency-limits/limit"
+	"github.com/platinummonkey/go-concurrency-limits/limiter"
+	"github.com/platinummonkey/go-concurrency-limits/limiter/limitertest"
+	"github.com/platinummonkey/go-concurrency-limits/strategy"
+)
+
+func TestUnaryServerInterceptor(t *testing.T) {
+	t.Parallel()
+
+	info := &golangGrpc.UnaryServerInfo{FullMethod: "/test.Service/Method"}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		if req == "fail" {
+			return nil, fmt.Errorf("failed")
+		}
+		return "ok", nil
+	}
+
+	t.Run("DefaultClassifiers", func(t2 *testing.T) {
+		t2.Parallel()
+		asrt := assert.New(t2)
+		l := limitertest.NewFakeLimiter(limitertest.AcceptN(2))
+		interceptor := UnaryServerInterceptor(WithLimiter(l))
+
+		resp, err := interceptor(context.Background(), "req", info, handler)
+		asrt.NoError(err)
+		asrt.Equal("ok", resp)
+		_, err = interceptor(context.Background(), "fail", info, handler)
+		asrt.EqualError(err, "failed")
+		_, err = interceptor(context.Background(), "req", info, handler)
+		asrt.Equal(codes.ResourceExhausted, status.Code(err))
+
+		limitertest.AssertCounts(t2, l, limitertest.Counts{Acquired: 2, Rejected: 1, Successes: 1, Drops: 1})
+		limitertest.AssertReleases(t2, l, limitertest.ReleaseSuccess, limitertest.ReleaseDropped)
+	})
+
+	t.Run("CustomClassifiers", func(t2 *testing.T) {
+		t2.Parallel()
+		asrt := assert.New(t2)
+		l := limitertest.NewFakeLimiter(limitertest.Script(false, true))
+		interceptor := UnaryServerInterceptor(
+			WithLimiter(l),
+			WithLimitExceededResponseClassifier(func(
+				ctx context.Context, method string, req interface{}, l core.Limiter,
+			) (interface{}, codes.Code, error) {
+				return "busy", codes.Unavailable, fmt.Errorf("%s busy", method)
+			}),
+			WithServerResponseTypeClassifier(func(
+				ctx context.Context, req interface{}, info *golangGrpc.UnaryServerInfo, resp interface{}, err error,
+			) ResponseType {
+				return ResponseTypeIgnore
+			}),
+		)
+
+		resp, err := interceptor(context.Background(), "req", info, handler)
+		asrt.Equal("busy", resp)
+		asrt.Equal(codes.Unavailable, status.Code(err))
+		asrt.Equal("/test.Service/Method busy", status.Convert(err).Message())
+		_, err = interceptor(context.Background(), "fail", info, handler)
+		asrt.Error(err)
+
+		limitertest.AssertReleases(t2, l, limitertest.ReleaseIgnore)
+	})
+
+	t.Run("WeightClassifier", func(t2 *testing.T) {
+		t2.Parallel()
+		asrt := assert.New(t2)
+		strtgy := strategy.NewSimpleStrategy(4)
+		l, err := limiter.NewDefaultLimiter(limit.NewFixedLimit("test", 4), 1e8, 1e9, 0, 10, strtgy, nil)
+		asrt.NoError(err)
+		interceptor := UnaryServerInterceptor(
+			WithLimiter(l),
+			WithWeightClassifier(func(ctx context.Context, method string, req interface{}) int {
+				return len(req.([]string))
+			}),
+		)
+
+		busy := 0
+		batchHandler := func(ctx context.Context, req interface{}) (interface{}, error) {
+			busy = strtgy.GetBusyCount()
+			_, err := interceptor(ctx, []string{"a", "b"}, info, handler)
+			return nil, err
+		}
+		_, err = interceptor(context.Background(), []string{"a", "b", "c"}, info, batchHandler)
+		asrt.Equal(3, busy)
+		asrt.Equal(codes.ResourceExhausted, status.Code(err), "expected the nested batch to exceed the limit")
+		asrt.Equal(0, strtgy.GetBusyCount())
+	})
+}
+
+func TestUnaryClientInterceptor(t *testing.T) {
+	t.Parallel()
+	asrt := assert.New(t)
+
+	invoker := func(
+		ctx context.Context, method string, req, reply interface{}, cc *golangGrpc.ClientConn, opts ...golangGrpc.CallOption,
+	) error {
+		if req == "fail" {
+			return status.Error(codes.DeadlineExceeded, "timeout")
+		}
+		return nil
+	}
+	l := limitertest.NewFakeLimiter(limitertest.AcceptN(2))
+	interceptor := UnaryClientInterceptor(WithLimiter(l))
+
+	asrt.NoError(interceptor(context.Background(), "/test.Service/Method", "req", nil, nil, invoker))
+	asrt.Error(interceptor(context.Background(), "/test.Service/Method", "fail", nil, nil, invoker))
+	err := interceptor(context.Background(), "/test.Service/Method", "req", nil, nil, invoker)
+	asrt.Equal(codes.ResourceExhausted, status.Code(err))
+
+	limitertest.AssertAllReleased(t, l)
+	limitertest.AssertReleases(t, l, limitertest.ReleaseSuccess, limitertest.ReleaseDropped)
+}
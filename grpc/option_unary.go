@@ -39,6 +39,10 @@ type ServerResponseClassifier func(
 	ctx context.Context, req interface{}, info *golangGrpc.UnaryServerInfo, resp interface{}, err error,
 ) ResponseType
 
+// WeightClassifier is a method definition for deriving the weight of a request, i.e. the number of items in a batch
+// RPC.  The weight is the number of limit units acquired for the request.
+type WeightClassifier func(ctx context.Context, method string, req interface{}) int
+
 func defaultLimitExceededResponseClassifier(
 	ctx context.Context,
 	method string,
@@ -48,6 +52,10 @@ func defaultLimitExceededResponseClassifier(
 	return nil, codes.ResourceExhausted, fmt.Errorf("limit exceeded for limiter=%v", l)
 }
 
+func defaultWeightClassifier(ctx context.Context, method string, req interface{}) int {
+	return core.WeightFromContext(ctx)
+}
+
 func defaultClientResponseClassifier(
 	ctx context.Context,
 	method string,
@@ -81,6 +89,15 @@ type interceptorConfig struct {
 	limitExceededResponseClassifier LimitExceededResponseClassifier
 	serverResponseClassifer         ServerResponseClassifier
 	clientResponseClassifer         ClientResponseClassifier
+	weightClassifier                WeightClassifier
+}
+
+// acquire the limiter for the request, weighted by the weight classifier if the limiter supports weights.
+func (cfg *interceptorConfig) acquire(ctx context.Context, method string, req interface{}) (core.Listener, bool) {
+	if weighted, ok := cfg.limiter.(core.WeightedLimiter); ok {
+		return weighted.AcquireWeighted(ctx, cfg.weightClassifier(ctx, method, req))
+	}
+	return cfg.limiter.Acquire(ctx)
 }
 
 // InterceptorOption represents an option that can be passed to the grpc unary
@@ -104,6 +121,7 @@ func defaults(cfg *interceptorConfig) {
 	cfg.limitExceededResponseClassifier = defaultLimitExceededResponseClassifier
 	cfg.clientResponseClassifer = defaultClientResponseClassifier
 	cfg.serverResponseClassifer = defaultServerResponseClassifier
+	cfg.weightClassifier = defaultWeightClassifier
 }
 
 // WithName sets the default limiter name if the default limiter is used, otherwise unused.
@@ -147,3 +165,11 @@ func WithServerResponseTypeClassifier(classifier ServerResponseClassifier) Inter
 		cfg.serverResponseClassifer = classifier
 	}
 }
+
+// WithWeightClassifier sets the classifier deriving the weight of a request for limiters implementing
+// core.WeightedLimiter.  By default the weight set with core.WithWeight is used.
+func WithWeightClassifier(classifier WeightClassifier) InterceptorOption {
+	return func(cfg *interceptorConfig) {
+		cfg.weightClassifier = classifier
+	}
+}
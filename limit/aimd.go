@@ -1,6 +1,7 @@
 package limit
 
 import (
+	"encoding/json"
 	"fmt"
 	"math"
 	"sync"
@@ -15,7 +16,7 @@ type AIMDLimit struct {
 	limit        int
 	backOffRatio float64
 
-	listeners []core.LimitChangeListener
+	listeners listenerRegistry
 
 	mu sync.RWMutex
 }
@@ -39,7 +40,6 @@ func NewAIMDLimit(
 		name:         name,
 		limit:        initialLimit,
 		backOffRatio: backOffRatio,
-		listeners:    make([]core.LimitChangeListener, 0),
 	}
 	return l
 }
@@ -52,32 +52,25 @@ func (l *AIMDLimit) EstimatedLimit() int {
 }
 
 // NotifyOnChange will register a callback to receive notification whenever the limit is updated to a new value.
-func (l *AIMDLimit) NotifyOnChange(consumer core.LimitChangeListener) {
-	l.mu.Lock()
-	l.listeners = append(l.listeners, consumer)
-	l.mu.Unlock()
-}
-
-// notifyListeners will call the callbacks on limit changes
-func (l *AIMDLimit) notifyListeners(newLimit int) {
-	for _, listener := range l.listeners {
-		listener(newLimit)
-	}
+func (l *AIMDLimit) NotifyOnChange(consumer core.LimitChangeListener) core.LimitChangeSubscription {
+	return l.listeners.add(consumer)
 }
 
 // OnSample the concurrency limit using a new rtt sample.
 func (l *AIMDLimit) OnSample(startTime int64, rtt int64, inFlight int, didDrop bool) {
 	l.mu.Lock()
-	l.mu.Unlock()
-
 	if didDrop {
 		l.limit = int(math.Max(1, math.Min(float64(l.limit-1), float64(int(float64(l.limit)*l.backOffRatio)))))
-		l.notifyListeners(l.limit)
 	} else if inFlight >= l.limit {
 		l.limit++
-		l.notifyListeners(l.limit)
+	} else {
+		l.mu.Unlock()
+		return
 	}
-	return
+	newLimit := l.limit
+	l.mu.Unlock()
+
+	l.listeners.notify(newLimit)
 }
 
 // BackOffRatio return the current back-off-ratio for the AIMDLimit
@@ -87,6 +80,31 @@ func (l *AIMDLimit) BackOffRatio() float64 {
 	return l.backOffRatio
 }
 
+// MarshalState returns a snapshot of the learned limit.
+func (l *AIMDLimit) MarshalState() ([]byte, error) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return json.Marshal(LimitState{
+		Algorithm:      "aimd",
+		EstimatedLimit: float64(l.limit),
+	})
+}
+
+// RestoreState restores the learned limit from a snapshot returned by MarshalState.
+func (l *AIMDLimit) RestoreState(data []byte) error {
+	state, err := unmarshalLimitState("aimd", data)
+	if err != nil {
+		return err
+	}
+	l.mu.Lock()
+	l.limit = int(math.Max(1, state.EstimatedLimit))
+	newLimit := l.limit
+	l.mu.Unlock()
+
+	l.listeners.notify(newLimit)
+	return nil
+}
+
 func (l *AIMDLimit) String() string {
 	return fmt.Sprintf("AIMDLimit{limit=%d, backOffRatio=%0.4f}", l.EstimatedLimit(), l.BackOffRatio())
 }
@@ -0,0 +1,362 @@
+package limit
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sync"
+
+	"github.com/platinummonkey/go-concurrency-limits/core"
+	"github.com/platinummonkey/go-concurrency-limits/measurements"
+)
+
+// BBRMode represents the current state of the BBRLimit state machine.
+type BBRMode int
+
+const (
+	// BBRModeStartup grows the limit exponentially until the delivery rate stops growing.
+	BBRModeStartup BBRMode = iota
+	// BBRModeDrain drains the queue built up during startup.
+	BBRModeDrain
+	// BBRModeProbeBW cycles the pacing gain around the bandwidth-delay product to probe for more bandwidth.
+	BBRModeProbeBW
+	// BBRModeProbeRTT briefly drains in flight requests in order to refresh the minimum RTT.
+	BBRModeProbeRTT
+)
+
+func (m BBRMode) String() string {
+	switch m {
+	case BBRModeStartup:
+		return "Startup"
+	case BBRModeDrain:
+		return "Drain"
+	case BBRModeProbeBW:
+		return "ProbeBW"
+	case BBRModeProbeRTT:
+		return "ProbeRTT"
+	}
+	return "Unknown"
+}
+
+const (
+	// bbrHighGain is 2/ln(2), the smallest gain that allows the limit to double every round during startup.
+	bbrHighGain = 2.885
+	// bbrFullBandwidthThreshold is the growth in max delivery rate required to consider the pipe not yet full.
+	bbrFullBandwidthThreshold = 1.25
+	// bbrFullBandwidthRounds is the number of rounds without growth before the pipe is considered full.
+	bbrFullBandwidthRounds = 3
+)
+
+// bbrPacingGainCycle is the gain cycle used in ProbeBW, one phase probing above the estimated bandwidth-delay
+// product, one draining any resulting queue and the remainder cruising at the estimate.
+var bbrPacingGainCycle = []float64{1.25, 0.75, 1, 1, 1, 1, 1, 1}
+
+// BBRLimit implements a concurrency limit inspired by TCP BBR.  Rather than reacting to queueing or loss the limit
+// builds an explicit model of the system by tracking the maximum delivery rate (in flight / rtt) and the minimum RTT
+// over sliding windows of samples.  The limit is then set to the bandwidth-delay product of those estimates:
+//
+//	bdp = maxDeliveryRate * minRTT
+//	limit = pacingGain * bdp
+//
+// The limit moves through the following states:
+//
+//  1. Startup
+//     The pacing gain is 2/ln(2) so the limit roughly doubles every sample until the delivery rate stops growing by at
+//     least 25% for 3 consecutive samples (or a drop is observed).
+//  2. Drain
+//     The pacing gain is inverted to drain the queue created during startup until in flight falls to the bdp.
+//  3. ProbeBW
+//     The pacing gain cycles through [1.25, 0.75, 1, 1, 1, 1, 1, 1], probing for more capacity and then draining
+//     any queue the probe created.
+//  4. ProbeRTT
+//     If the minimum RTT has not been refreshed for probeRTTInterval samples the limit is dropped to minLimit for
+//     probeRTTRounds samples so the queue empties and a new minimum RTT can be observed.
+//
+// All windows are measured in samples rather than wall clock time so the algorithm is deterministic given the same
+// sequence of samples.
+type BBRLimit struct {
+	name           string
+	estimatedLimit float64
+	minLimit       int
+	maxLimit       int
+
+	// windowed max filter of the delivery rate in requests per second
+	maxDeliveryRate core.MeasurementInterface
+	// windowed min filter of the RTT in nanoseconds
+	minRTT core.MeasurementInterface
+
+	mode                 BBRMode
+	cycleIndex           int
+	fullBandwidth        float64
+	fullBandwidthCount   int
+	probeRTTInterval     int
+	probeRTTRounds       int
+	samplesSinceMinRTT   int
+	probeRTTRoundsRemain int
+
+	listeners listenerRegistry
+	logger    Logger
+	mu        sync.RWMutex
+}
+
+// NewDefaultBBRLimit will create a new BBRLimit with defaults.
+func NewDefaultBBRLimit(
+	name string,
+	logger Logger,
+) *BBRLimit {
+	l, _ := NewBBRLimit(
+		name,
+		20,
+		4,
+		1000,
+		10,
+		100,
+		2,
+		logger,
+	)
+	return l
+}
+
+// NewBBRLimit will create a new BBRLimit.
+// @param name: The name of the limit, included in its logs and String.
+// @param initialLimit: Initial limit used by the limiter.
+// @param minLimit: Minimum concurrency limit allowed, this is also the limit used during ProbeRTT.
+// @param maxConcurrency: Maximum allowable concurrency.  Any estimated concurrency will be capped.
+// @param bandwidthWindow: Number of samples the max delivery rate filter spans.
+// @param probeRTTInterval: Number of samples the min RTT filter spans, the limit enters ProbeRTT when exceeded.
+// @param probeRTTRounds: Number of samples spent in ProbeRTT.
+func NewBBRLimit(
+	name string,
+	initialLimit int,
+	minLimit int,
+	maxConcurrency int,
+	bandwidthWindow int,
+	probeRTTInterval int,
+	probeRTTRounds int,
+	logger Logger,
+) (*BBRLimit, error) {
+	if minLimit <= 0 {
+		minLimit = 4
+	}
+	if maxConcurrency <= 0 {
+		maxConcurrency = 1000
+	}
+	if minLimit > maxConcurrency {
+		return nil, fmt.Errorf("minLimit must be <= maxConcurrency")
+	}
+	if initialLimit <= 0 {
+		initialLimit = 20
+	}
+	if bandwidthWindow <= 0 {
+		bandwidthWindow = 10
+	}
+	if probeRTTInterval <= 0 {
+		probeRTTInterval = 100
+	}
+	if probeRTTRounds <= 0 {
+		probeRTTRounds = 2
+	}
+	if logger == nil {
+		logger = NoopLimitLogger{}
+	}
+
+	l := &BBRLimit{
+		name:             name,
+		estimatedLimit:   math.Max(float64(minLimit), math.Min(float64(maxConcurrency), float64(initialLimit))),
+		minLimit:         minLimit,
+		maxLimit:         maxConcurrency,
+		maxDeliveryRate:  measurements.NewWindowedMaximumMeasurement(bandwidthWindow),
+		minRTT:           measurements.NewWindowedMinimumMeasurement(probeRTTInterval),
+		mode:             BBRModeStartup,
+		probeRTTInterval: probeRTTInterval,
+		probeRTTRounds:   probeRTTRounds,
+		logger:           logger,
+	}
+	return l, nil
+}
+
+// EstimatedLimit returns the current estimated limit.
+func (l *BBRLimit) EstimatedLimit() int {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return int(l.estimatedLimit)
+}
+
+// NotifyOnChange will register a callback to receive notification whenever the limit is updated to a new value.
+func (l *BBRLimit) NotifyOnChange(consumer core.LimitChangeListener) core.LimitChangeSubscription {
+	return l.listeners.add(consumer)
+}
+
+// OnSample the concurrency limit using a new rtt sample.
+func (l *BBRLimit) OnSample(startTime int64, rtt int64, inFlight int, didDrop bool) {
+	if newLimit, ok := l.onSample(startTime, rtt, inFlight, didDrop); ok {
+		l.listeners.notify(newLimit)
+	}
+}
+
+// onSample updates the estimated limit under the lock, returning the limit to notify listeners of.
+func (l *BBRLimit) onSample(startTime int64, rtt int64, inFlight int, didDrop bool) (int, bool) {
+	if rtt <= 0 {
+		return 0, false
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	// refresh the min rtt filter, tracking how long it's been since the min rtt was last confirmed
+	minRTT := l.minRTT.Get()
+	if minRTT == 0 || float64(rtt) <= minRTT {
+		l.samplesSinceMinRTT = 0
+	} else {
+		l.samplesSinceMinRTT++
+	}
+	minRTT, _ = l.minRTT.Add(float64(rtt))
+
+	// dropped samples do not represent a delivery so are excluded from the delivery rate
+	var maxDeliveryRate float64
+	if didDrop {
+		maxDeliveryRate = l.maxDeliveryRate.Get()
+	} else {
+		maxDeliveryRate, _ = l.maxDeliveryRate.Add(float64(inFlight) * 1e9 / float64(rtt))
+	}
+	bdp := maxDeliveryRate * minRTT / 1e9
+
+	l.updateMode(inFlight, bdp, maxDeliveryRate, didDrop)
+
+	var newLimit float64
+	switch l.mode {
+	case BBRModeProbeRTT:
+		newLimit = float64(l.minLimit)
+	case BBRModeStartup:
+		// Startup must never shrink the limit, app limited samples would otherwise collapse the initial limit.
+		newLimit = math.Max(l.estimatedLimit, bbrHighGain*bdp)
+	default:
+		if !didDrop && float64(inFlight)*2 < l.estimatedLimit {
+			// Don't change the limit if we are app limited
+			return 0, false
+		}
+		newLimit = l.pacingGain() * bdp
+	}
+	newLimit = math.Max(float64(l.minLimit), math.Min(float64(l.maxLimit), newLimit))
+
+	changed := int(newLimit) != int(l.estimatedLimit)
+	if changed && l.logger.IsDebugEnabled() {
+		l.logger.Debugf("%s new limit=%d, mode=%s, minRTT=%d ms, maxDeliveryRate=%0.2f/s, bdp=%0.2f",
+			l.name, int(newLimit), l.mode, int64(minRTT)/1e6, maxDeliveryRate, bdp)
+	}
+
+	l.estimatedLimit = newLimit
+	return int(l.estimatedLimit), changed
+}
+
+// updateMode runs the BBR state machine for a single sample.
+func (l *BBRLimit) updateMode(inFlight int, bdp float64, maxDeliveryRate float64, didDrop bool) {
+	switch l.mode {
+	case BBRModeStartup:
+		if didDrop {
+			l.mode = BBRModeDrain
+			break
+		}
+		if maxDeliveryRate >= l.fullBandwidth*bbrFullBandwidthThreshold {
+			l.fullBandwidth = maxDeliveryRate
+			l.fullBandwidthCount = 0
+			break
+		}
+		l.fullBandwidthCount++
+		if l.fullBandwidthCount >= bbrFullBandwidthRounds {
+			l.mode = BBRModeDrain
+		}
+	case BBRModeDrain:
+		if float64(inFlight) <= bdp {
+			l.enterProbeBW()
+		}
+	case BBRModeProbeBW:
+		l.cycleIndex = (l.cycleIndex + 1) % len(bbrPacingGainCycle)
+	case BBRModeProbeRTT:
+		l.probeRTTRoundsRemain--
+		if l.probeRTTRoundsRemain <= 0 {
+			l.samplesSinceMinRTT = 0
+			l.enterProbeBW()
+		}
+		return
+	}
+
+	if l.mode != BBRModeStartup && l.samplesSinceMinRTT >= l.probeRTTInterval {
+		l.logger.Debugf("%s min rtt expired, entering ProbeRTT", l.name)
+		l.mode = BBRModeProbeRTT
+		l.probeRTTRoundsRemain = l.probeRTTRounds
+	}
+}
+
+func (l *BBRLimit) enterProbeBW() {
+	l.mode = BBRModeProbeBW
+	l.cycleIndex = 0
+}
+
+func (l *BBRLimit) pacingGain() float64 {
+	switch l.mode {
+	case BBRModeStartup:
+		return bbrHighGain
+	case BBRModeDrain:
+		return 1 / bbrHighGain
+	case BBRModeProbeBW:
+		return bbrPacingGainCycle[l.cycleIndex]
+	}
+	return 1
+}
+
+// Mode returns the current mode of the BBR state machine.
+func (l *BBRLimit) Mode() BBRMode {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return l.mode
+}
+
+// MinRTT returns the current windowed minimum RTT in nanoseconds.
+func (l *BBRLimit) MinRTT() int64 {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return int64(l.minRTT.Get())
+}
+
+// MaxDeliveryRate returns the current windowed maximum delivery rate in requests per second.
+func (l *BBRLimit) MaxDeliveryRate() float64 {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return l.maxDeliveryRate.Get()
+}
+
+// MarshalState returns a snapshot of the learned limit and min RTT.
+func (l *BBRLimit) MarshalState() ([]byte, error) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return json.Marshal(LimitState{
+		Algorithm:      "bbr",
+		EstimatedLimit: l.estimatedLimit,
+		RTTNoLoad:      int64(l.minRTT.Get()),
+	})
+}
+
+// RestoreState restores the learned limit and min RTT from a snapshot returned by MarshalState.  Startup never shrinks
+// the limit, so it resumes probing from the restored limit.
+func (l *BBRLimit) RestoreState(data []byte) error {
+	state, err := unmarshalLimitState("bbr", data)
+	if err != nil {
+		return err
+	}
+	l.mu.Lock()
+	l.estimatedLimit = clampLimit(state.EstimatedLimit, l.minLimit, l.maxLimit)
+	restoreMeasurement(l.minRTT, float64(state.RTTNoLoad))
+	newLimit := int(l.estimatedLimit)
+	l.mu.Unlock()
+
+	l.listeners.notify(newLimit)
+	return nil
+}
+
+func (l *BBRLimit) String() string {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return fmt.Sprintf("BBRLimit{name=%s, limit=%d, mode=%s, minRTT=%d ms}",
+		l.name, int(l.estimatedLimit), l.mode, int64(l.minRTT.Get())/1e6)
+}
@@ -0,0 +1,186 @@
+package limit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/platinummonkey/go-concurrency-limits/core"
+)
+
+// queueingModel is a simple model of a server that can process `capacity` requests concurrently with a `baseRTT`
+// latency, anything in excess queues and increases the latency proportionally.
+type queueingModel struct {
+	capacity int
+	baseRTT  time.Duration
+}
+
+func (m queueingModel) rtt(inFlight int) int64 {
+	if inFlight <= m.capacity {
+		return m.baseRTT.Nanoseconds()
+	}
+	return m.baseRTT.Nanoseconds() * int64(inFlight) / int64(m.capacity)
+}
+
+// runScenario saturates the limit against the model for the given number of rounds and returns the limit history.
+func runScenario(l core.Limit, model queueingModel, rounds int) []int {
+	history := make([]int, 0, rounds)
+	startTime := int64(0)
+	for i := 0; i < rounds; i++ {
+		inFlight := l.EstimatedLimit()
+		rtt := model.rtt(inFlight)
+		l.OnSample(startTime, rtt, inFlight, false)
+		startTime += rtt
+		history = append(history, l.EstimatedLimit())
+	}
+	return history
+}
+
+func averageInt(values []int) float64 {
+	sum := 0
+	for _, v := range values {
+		sum += v
+	}
+	return float64(sum) / float64(len(values))
+}
+
+func TestBBRLimit(t *testing.T) {
+	t.Parallel()
+
+	t.Run("Default", func(t2 *testing.T) {
+		t2.Parallel()
+		asrt := assert.New(t2)
+		l := NewDefaultBBRLimit("test", nil)
+		asrt.Equal(20, l.EstimatedLimit())
+		asrt.Equal(BBRModeStartup, l.Mode())
+		asrt.Equal("BBRLimit{name=test, limit=20, mode=Startup, minRTT=0 ms}", l.String())
+	})
+
+	t.Run("InvalidBounds", func(t2 *testing.T) {
+		t2.Parallel()
+		asrt := assert.New(t2)
+		_, err := NewBBRLimit("test", 10, 100, 10, 0, 0, 0, nil)
+		asrt.Error(err)
+	})
+
+	t.Run("StartupGrowsAndIgnoresAppLimited", func(t2 *testing.T) {
+		t2.Parallel()
+		asrt := assert.New(t2)
+		l := NewDefaultBBRLimit("test", nil)
+		listener := testNotifyListener{}
+		l.NotifyOnChange(listener.updater())
+
+		// app limited samples don't shrink the limit during startup
+		l.OnSample(0, (time.Millisecond * 10).Nanoseconds(), 1, false)
+		asrt.Equal(20, l.EstimatedLimit())
+
+		// saturated sample with no queuing grows exponentially
+		l.OnSample(0, (time.Millisecond * 10).Nanoseconds(), 20, false)
+		asrt.Equal(57, l.EstimatedLimit())
+		asrt.Equal(57, listener.changes[len(listener.changes)-1])
+		asrt.Equal(int64(10*time.Millisecond), l.MinRTT())
+		asrt.InDelta(2000.0, l.MaxDeliveryRate(), 0.001)
+	})
+
+	t.Run("DropExitsStartup", func(t2 *testing.T) {
+		t2.Parallel()
+		asrt := assert.New(t2)
+		l := NewDefaultBBRLimit("test", nil)
+		l.OnSample(0, (time.Millisecond * 10).Nanoseconds(), 20, false)
+		l.OnSample(0, (time.Millisecond * 10).Nanoseconds(), 20, true)
+		asrt.Equal(BBRModeDrain, l.Mode())
+	})
+
+	t.Run("ConvergesToBDP", func(t2 *testing.T) {
+		t2.Parallel()
+		asrt := assert.New(t2)
+		l, err := NewBBRLimit("test", 10, 4, 1000, 10, 50, 2, nil)
+		asrt.NoError(err)
+		model := queueingModel{capacity: 100, baseRTT: time.Millisecond * 10}
+		history := runScenario(l, model, 40)
+		asrt.Equal(BBRModeProbeBW, l.Mode())
+		for _, v := range history[20:] {
+			asrt.True(v >= 75 && v <= 125, "expected limit near bdp, got %d", v)
+		}
+	})
+
+	t.Run("ProbeRTT", func(t2 *testing.T) {
+		t2.Parallel()
+		asrt := assert.New(t2)
+		l, err := NewBBRLimit("test", 100, 4, 1000, 10, 5, 2, nil)
+		asrt.NoError(err)
+		l.OnSample(0, (time.Millisecond * 10).Nanoseconds(), 100, true)
+		asrt.Equal(BBRModeDrain, l.Mode())
+
+		// latency never returns to the minimum, the min rtt expires and we must drain to refresh it.
+		for i := 0; i < 5; i++ {
+			l.OnSample(0, (time.Millisecond * 20).Nanoseconds(), 100, false)
+		}
+		asrt.Equal(BBRModeProbeRTT, l.Mode())
+		asrt.Equal(4, l.EstimatedLimit())
+
+		// drained samples refresh the min rtt and we return to probing bandwidth
+		l.OnSample(0, (time.Millisecond * 8).Nanoseconds(), 4, false)
+		l.OnSample(0, (time.Millisecond * 8).Nanoseconds(), 4, false)
+		asrt.Equal(BBRModeProbeBW, l.Mode())
+		asrt.Equal(int64(8*time.Millisecond), l.MinRTT())
+	})
+
+	t.Run("NotifiesOnlyOnChange", func(t2 *testing.T) {
+		t2.Parallel()
+		asrt := assert.New(t2)
+		l, err := NewBBRLimit("test", 10, 4, 1000, 10, 50, 2, nil)
+		asrt.NoError(err)
+		listener := testNotifyListener{}
+		l.NotifyOnChange(listener.updater())
+		model := queueingModel{capacity: 100, baseRTT: time.Millisecond * 10}
+		runScenario(l, model, 40)
+
+		asrt.NotEmpty(listener.changes)
+		previous := 10
+		for _, v := range listener.changes {
+			asrt.NotEqual(previous, v, "expected only changes to be notified")
+			previous = v
+		}
+	})
+}
+
+func TestBBRLimitScenarios(t *testing.T) {
+	t.Parallel()
+
+	model := queueingModel{capacity: 100, baseRTT: time.Millisecond * 10}
+	rounds := 300
+
+	bbr, _ := NewBBRLimit("test", 20, 4, 1000, 10, 100, 2, nil)
+	vegas := NewVegasLimitWithRegistry("test", 20, nil, 1000, -1, nil, nil, nil, nil, nil, ProbeDisabled, nil)
+	gradient2, _ := NewGradient2Limit("test", 20, 1000, 4, nil, -1, -1, nil)
+
+	bbrHistory := runScenario(bbr, model, rounds)
+	vegasHistory := runScenario(vegas, model, rounds)
+	gradient2History := runScenario(gradient2, model, rounds)
+
+	t.Run("ConvergenceSpeed", func(t2 *testing.T) {
+		asrt := assert.New(t2)
+		// BBR finds the capacity exponentially, the delay based algorithms grow additively.
+		firstAbove := func(history []int, value int) int {
+			for i, v := range history {
+				if v >= value {
+					return i
+				}
+			}
+			return len(history)
+		}
+		asrt.True(firstAbove(bbrHistory, 75) < firstAbove(vegasHistory, 75))
+		asrt.True(firstAbove(bbrHistory, 75) < firstAbove(gradient2History, 75))
+	})
+
+	t.Run("SteadyState", func(t2 *testing.T) {
+		asrt := assert.New(t2)
+		bbrAvg := averageInt(bbrHistory[rounds/2:])
+		vegasAvg := averageInt(vegasHistory[rounds/2:])
+		asrt.InDelta(float64(model.capacity), bbrAvg, 20)
+		// all algorithms should settle in the same ballpark for a simple queueing system
+		asrt.InDelta(vegasAvg, bbrAvg, 0.5*vegasAvg)
+	})
+}
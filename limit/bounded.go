@@ -0,0 +1,147 @@
+package limit
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/platinummonkey/go-concurrency-limits/core"
+)
+
+// BoundedLimit wraps a delegate Limit, clamping its estimate to [minLimit, maxLimit] and bounding how much the limit
+// may change per sample.  This gives every algorithm the same min/max handling and protects downstream strategies from
+// sudden swings, for example an AIMDLimit halving on a single drop.  When the delegate moves further than allowed, the
+// limit keeps stepping towards the clamped delegate estimate on each subsequent sample.
+type BoundedLimit struct {
+	delegate    core.Limit
+	minLimit    int
+	maxLimit    int
+	maxStepUp   int
+	maxStepDown int
+
+	target int
+	limit  int
+
+	listeners listenerRegistry
+	mu        sync.RWMutex
+}
+
+// NewBoundedLimit will create a new BoundedLimit.
+// @param delegate: The limit to bound.
+// @param minLimit: Minimum limit, accepts >= 1.
+// @param maxLimit: Maximum limit, accepts >= minLimit.
+// @param maxStepUp: Maximum increase of the limit per sample, 0 for unbounded.
+// @param maxStepDown: Maximum decrease of the limit per sample, 0 for unbounded.
+func NewBoundedLimit(
+	delegate core.Limit,
+	minLimit int,
+	maxLimit int,
+	maxStepUp int,
+	maxStepDown int,
+) (*BoundedLimit, error) {
+	if delegate == nil {
+		return nil, fmt.Errorf("delegate must be specified")
+	}
+	if minLimit < 1 {
+		return nil, fmt.Errorf("minLimit must be >= 1")
+	}
+	if maxLimit < minLimit {
+		return nil, fmt.Errorf("maxLimit must be >= minLimit")
+	}
+	if maxStepUp < 0 || maxStepDown < 0 {
+		return nil, fmt.Errorf("maxStepUp and maxStepDown must be >= 0")
+	}
+
+	l := &BoundedLimit{
+		delegate:    delegate,
+		minLimit:    minLimit,
+		maxLimit:    maxLimit,
+		maxStepUp:   maxStepUp,
+		maxStepDown: maxStepDown,
+	}
+	l.target = l.clamp(delegate.EstimatedLimit())
+	l.limit = l.target
+	delegate.NotifyOnChange(l.onDelegateChange)
+	return l, nil
+}
+
+// onDelegateChange records the delegate's current limit as the target, the limit itself is stepped in OnSample.  The
+// limit is read back from the delegate since notifications may be delivered out of order.
+func (l *BoundedLimit) onDelegateChange(_ int) {
+	limit := l.delegate.EstimatedLimit()
+	l.mu.Lock()
+	l.target = l.clamp(limit)
+	l.mu.Unlock()
+}
+
+func (l *BoundedLimit) clamp(limit int) int {
+	if limit < l.minLimit {
+		return l.minLimit
+	}
+	if limit > l.maxLimit {
+		return l.maxLimit
+	}
+	return limit
+}
+
+// EstimatedLimit returns the current bounded limit.
+func (l *BoundedLimit) EstimatedLimit() int {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return l.limit
+}
+
+// NotifyOnChange will register a callback to receive notification whenever the limit is updated to a new value.
+func (l *BoundedLimit) NotifyOnChange(consumer core.LimitChangeListener) core.LimitChangeSubscription {
+	return l.listeners.add(consumer)
+}
+
+// OnSample will delegate the sample and then step the limit towards the clamped delegate estimate.
+func (l *BoundedLimit) OnSample(startTime int64, rtt int64, inFlight int, didDrop bool) {
+	l.delegate.OnSample(startTime, rtt, inFlight, didDrop)
+
+	l.mu.Lock()
+	newLimit := l.target
+	if l.maxStepUp > 0 && newLimit > l.limit+l.maxStepUp {
+		newLimit = l.limit + l.maxStepUp
+	}
+	if l.maxStepDown > 0 && newLimit < l.limit-l.maxStepDown {
+		newLimit = l.limit - l.maxStepDown
+	}
+	if newLimit == l.limit {
+		l.mu.Unlock()
+		return
+	}
+	l.limit = newLimit
+	l.mu.Unlock()
+
+	l.listeners.notify(newLimit)
+}
+
+// MarshalState returns a snapshot of the delegate's learned state, the delegate must be a StatefulLimit.
+func (l *BoundedLimit) MarshalState() ([]byte, error) {
+	return marshalDelegateState(l.delegate)
+}
+
+// RestoreState restores the delegate's learned state from a snapshot returned by MarshalState.  The bounded limit
+// resumes at the clamped restored limit rather than stepping towards it.
+func (l *BoundedLimit) RestoreState(data []byte) error {
+	if err := restoreDelegateState(l.delegate, data); err != nil {
+		return err
+	}
+
+	l.mu.Lock()
+	l.target = l.clamp(l.delegate.EstimatedLimit())
+	l.limit = l.target
+	newLimit := l.limit
+	l.mu.Unlock()
+
+	l.listeners.notify(newLimit)
+	return nil
+}
+
+func (l *BoundedLimit) String() string {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return fmt.Sprintf("BoundedLimit{limit=%d, minLimit=%d, maxLimit=%d, delegate=%v}",
+		l.limit, l.minLimit, l.maxLimit, l.delegate)
+}
@@ -0,0 +1,86 @@
+package limit
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBoundedLimit(t *testing.T) {
+	t.Parallel()
+
+	t.Run("InvalidArguments", func(t2 *testing.T) {
+		t2.Parallel()
+		asrt := assert.New(t2)
+		_, err := NewBoundedLimit(nil, 1, 10, 0, 0)
+		asrt.Error(err)
+		_, err = NewBoundedLimit(NewSettableLimit("test", 5), 0, 10, 0, 0)
+		asrt.Error(err)
+		_, err = NewBoundedLimit(NewSettableLimit("test", 5), 10, 5, 0, 0)
+		asrt.Error(err)
+		_, err = NewBoundedLimit(NewSettableLimit("test", 5), 1, 10, -1, 0)
+		asrt.Error(err)
+	})
+
+	t.Run("Clamp", func(t2 *testing.T) {
+		t2.Parallel()
+		asrt := assert.New(t2)
+		delegate := NewSettableLimit("test", 50)
+		l, err := NewBoundedLimit(delegate, 5, 20, 0, 0)
+		asrt.NoError(err)
+		asrt.Equal(20, l.EstimatedLimit())
+
+		listener := testNotifyListener{changes: make([]int, 0)}
+		l.NotifyOnChange(listener.updater())
+
+		delegate.SetLimit(1)
+		// only applied on the next sample
+		asrt.Equal(20, l.EstimatedLimit())
+		l.OnSample(0, 10, 1, false)
+		asrt.Equal(5, l.EstimatedLimit())
+
+		delegate.SetLimit(10)
+		l.OnSample(0, 10, 1, false)
+		asrt.Equal(10, l.EstimatedLimit())
+
+		// no change, no notification
+		l.OnSample(0, 10, 1, false)
+		asrt.Equal([]int{5, 10}, listener.changes)
+		asrt.Equal("BoundedLimit{limit=10, minLimit=5, maxLimit=20, delegate=SettableLimit{limit=10}}", l.String())
+	})
+
+	t.Run("StepLimits", func(t2 *testing.T) {
+		t2.Parallel()
+		asrt := assert.New(t2)
+		delegate := NewAIMDLimit("test", 40, 0.5)
+		l, err := NewBoundedLimit(delegate, 1, 100, 2, 5)
+		asrt.NoError(err)
+		asrt.Equal(40, l.EstimatedLimit())
+
+		listener := testNotifyListener{changes: make([]int, 0)}
+		l.NotifyOnChange(listener.updater())
+
+		// aimd halves to 20, the bounded limit may only step down by 5 per sample
+		l.OnSample(0, 10, 40, true)
+		asrt.Equal(20, delegate.EstimatedLimit())
+		asrt.Equal(35, l.EstimatedLimit())
+		l.OnSample(0, 10, 1, false)
+		l.OnSample(0, 10, 1, false)
+		l.OnSample(0, 10, 1, false)
+		asrt.Equal(20, l.EstimatedLimit())
+		l.OnSample(0, 10, 1, false)
+		asrt.Equal(20, l.EstimatedLimit())
+		asrt.Equal([]int{35, 30, 25, 20}, listener.changes)
+
+		// stepping up by at most 2
+		settable := NewSettableLimit("test", 10)
+		l, err = NewBoundedLimit(settable, 1, 100, 2, 5)
+		asrt.NoError(err)
+		settable.SetLimit(15)
+		l.OnSample(0, 10, 1, false)
+		asrt.Equal(12, l.EstimatedLimit())
+		l.OnSample(0, 10, 1, false)
+		l.OnSample(0, 10, 1, false)
+		asrt.Equal(15, l.EstimatedLimit())
+	})
+}
@@ -0,0 +1,14 @@
+package limit
+
+import (
+	"time"
+)
+
+// Clock returns the current epoch time in nanoseconds.  Limits that depend on wall clock time accept a Clock so
+// tests can control the passage of time.
+type Clock func() int64
+
+// SystemClock is the default Clock backed by time.Now.
+func SystemClock() int64 {
+	return time.Now().UnixNano()
+}
@@ -0,0 +1,190 @@
+package limit
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sync"
+
+	"github.com/platinummonkey/go-concurrency-limits/core"
+)
+
+// LimitReducer aggregates the estimated limits of several limits into a single limit.
+type LimitReducer func(limits []int) int
+
+// MinLimitReducer returns the smallest limit, the most conservative of the algorithms wins.
+func MinLimitReducer(limits []int) int {
+	result := limits[0]
+	for _, v := range limits[1:] {
+		if v < result {
+			result = v
+		}
+	}
+	return result
+}
+
+// MaxLimitReducer returns the largest limit, the most permissive of the algorithms wins.
+func MaxLimitReducer(limits []int) int {
+	result := limits[0]
+	for _, v := range limits[1:] {
+		if v > result {
+			result = v
+		}
+	}
+	return result
+}
+
+// WeightedAverageLimitReducer returns a reducer computing the weighted average of the limits, weights are matched to
+// limits by position.  Missing weights default to 1.
+func WeightedAverageLimitReducer(weights ...float64) LimitReducer {
+	return func(limits []int) int {
+		sum := 0.0
+		totalWeight := 0.0
+		for i, v := range limits {
+			weight := 1.0
+			if i < len(weights) {
+				weight = weights[i]
+			}
+			sum += float64(v) * weight
+			totalWeight += weight
+		}
+		if totalWeight <= 0 {
+			return MinLimitReducer(limits)
+		}
+		return int(math.Round(sum / totalWeight))
+	}
+}
+
+// CompositeLimit runs several limit algorithms side by side, for example a delay based VegasLimit together with a loss
+// based AIMDLimit.  Every sample is fanned out to all the limits and the estimated limit is the aggregate of their
+// estimates as computed by the reducer.  Listeners are only notified when the aggregate changes.
+type CompositeLimit struct {
+	limits    []core.Limit
+	reducer   LimitReducer
+	estimates []int
+	limit     int
+
+	listeners listenerRegistry
+	mu        sync.RWMutex
+}
+
+// NewCompositeLimit will create a new CompositeLimit.  The reducer defaults to MinLimitReducer when nil.
+func NewCompositeLimit(
+	name string,
+	reducer LimitReducer,
+	limits ...core.Limit,
+) (*CompositeLimit, error) {
+	if len(limits) == 0 {
+		return nil, fmt.Errorf("at least one limit must be specified")
+	}
+	if reducer == nil {
+		reducer = MinLimitReducer
+	}
+
+	estimates := make([]int, len(limits))
+	for i, delegate := range limits {
+		if delegate == nil {
+			return nil, fmt.Errorf("limit %d must not be nil", i)
+		}
+		estimates[i] = delegate.EstimatedLimit()
+	}
+
+	l := &CompositeLimit{
+		limits:    limits,
+		reducer:   reducer,
+		estimates: estimates,
+		limit:     reducer(estimates),
+	}
+	for i, delegate := range limits {
+		delegate.NotifyOnChange(l.updater(i))
+	}
+	return l, nil
+}
+
+// updater returns the listener registered on the delegate at the given index.  The delegate's estimate is read back
+// rather than cached from the notification since delegates notify outside of their locks, so concurrent notifications
+// may be delivered out of order and the notified value may already be stale.
+func (l *CompositeLimit) updater(idx int) core.LimitChangeListener {
+	return func(_ int) {
+		estimate := l.limits[idx].EstimatedLimit()
+		l.mu.Lock()
+		l.estimates[idx] = estimate
+		newLimit := l.reducer(l.estimates)
+		if newLimit == l.limit {
+			l.mu.Unlock()
+			return
+		}
+		l.limit = newLimit
+		l.mu.Unlock()
+
+		l.listeners.notify(newLimit)
+	}
+}
+
+// EstimatedLimit returns the current aggregated estimated limit.
+func (l *CompositeLimit) EstimatedLimit() int {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return l.limit
+}
+
+// NotifyOnChange will register a callback to receive notification whenever the limit is updated to a new value.
+func (l *CompositeLimit) NotifyOnChange(consumer core.LimitChangeListener) core.LimitChangeSubscription {
+	return l.listeners.add(consumer)
+}
+
+// OnSample fans the sample out to all the limits.
+func (l *CompositeLimit) OnSample(startTime int64, rtt int64, inFlight int, didDrop bool) {
+	for _, delegate := range l.limits {
+		delegate.OnSample(startTime, rtt, inFlight, didDrop)
+	}
+}
+
+// MarshalState returns a snapshot of the learned state of the limits as a JSON array, matched to the limits by position.
+// Limits that are not a StatefulLimit have a null entry.
+func (l *CompositeLimit) MarshalState() ([]byte, error) {
+	states := make([]json.RawMessage, len(l.limits))
+	for i, delegate := range l.limits {
+		stateful, ok := delegate.(StatefulLimit)
+		if !ok {
+			continue
+		}
+		data, err := stateful.MarshalState()
+		if err != nil {
+			return nil, err
+		}
+		states[i] = data
+	}
+	return json.Marshal(states)
+}
+
+// RestoreState restores the learned state of the limits from a snapshot returned by MarshalState.
+func (l *CompositeLimit) RestoreState(data []byte) error {
+	var states []json.RawMessage
+	if err := json.Unmarshal(data, &states); err != nil {
+		return fmt.Errorf("invalid composite limit state: %v", err)
+	}
+	if len(states) != len(l.limits) {
+		return fmt.Errorf("composite limit state has %d limits, expected %d", len(states), len(l.limits))
+	}
+	for i, state := range states {
+		if state == nil || string(state) == "null" {
+			continue
+		}
+		if err := restoreDelegateState(l.limits[i], state); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Limits returns the underlying limits.
+func (l *CompositeLimit) Limits() []core.Limit {
+	return l.limits
+}
+
+func (l *CompositeLimit) String() string {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return fmt.Sprintf("CompositeLimit{limit=%d, limits=%v}", l.limit, l.limits)
+}
@@ -0,0 +1,137 @@
+package limit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/platinummonkey/go-concurrency-limits/core"
+)
+
+func TestLimitReducers(t *testing.T) {
+	t.Parallel()
+	asrt := assert.New(t)
+	asrt.Equal(1, MinLimitReducer([]int{3, 1, 2}))
+	asrt.Equal(3, MaxLimitReducer([]int{3, 1, 2}))
+	asrt.Equal(2, WeightedAverageLimitReducer()([]int{3, 1, 2}))
+	asrt.Equal(25, WeightedAverageLimitReducer(3, 1)([]int{30, 10}))
+	asrt.Equal(10, WeightedAverageLimitReducer(0, 0)([]int{30, 10}))
+}
+
+// staleNotifyLimit notifies listeners of a stale limit, as an out of order notification would.
+type staleNotifyLimit struct {
+	*SettableLimit
+}
+
+func (l staleNotifyLimit) NotifyOnChange(consumer core.LimitChangeListener) core.LimitChangeSubscription {
+	return l.SettableLimit.NotifyOnChange(func(int) {
+		consumer(1)
+	})
+}
+
+func TestCompositeLimit(t *testing.T) {
+	t.Parallel()
+
+	t.Run("InvalidArguments", func(t2 *testing.T) {
+		t2.Parallel()
+		asrt := assert.New(t2)
+		_, err := NewCompositeLimit("test", nil)
+		asrt.Error(err)
+		_, err = NewCompositeLimit("test", nil, NewFixedLimit("test", 10), nil)
+		asrt.Error(err)
+	})
+
+	t.Run("Aggregate", func(t2 *testing.T) {
+		t2.Parallel()
+		asrt := assert.New(t2)
+		a := NewSettableLimit("a", 10)
+		b := NewSettableLimit("b", 20)
+		l, err := NewCompositeLimit("test", nil, a, b)
+		asrt.NoError(err)
+		asrt.Equal(10, l.EstimatedLimit())
+		asrt.Len(l.Limits(), 2)
+		asrt.Equal("CompositeLimit{limit=10, limits=[SettableLimit{limit=10} SettableLimit{limit=20}]}", l.String())
+
+		listener := testNotifyListener{}
+		l.NotifyOnChange(listener.updater())
+
+		// the aggregate doesn't change, no notification
+		b.SetLimit(30)
+		asrt.Equal(10, l.EstimatedLimit())
+		asrt.Len(listener.changes, 0)
+
+		a.SetLimit(5)
+		asrt.Equal(5, l.EstimatedLimit())
+		asrt.Equal([]int{5}, listener.changes)
+
+		a.SetLimit(50)
+		asrt.Equal(30, l.EstimatedLimit())
+		asrt.Equal([]int{5, 30}, listener.changes)
+	})
+
+	t.Run("FanOut", func(t2 *testing.T) {
+		t2.Parallel()
+		asrt := assert.New(t2)
+		vegas := createVegasLimit()
+		aimd := NewAIMDLimit("test", 10, 0.5)
+		l, _ := NewCompositeLimit("test", MinLimitReducer, vegas, aimd)
+		listener := testNotifyListener{}
+		l.NotifyOnChange(listener.updater())
+
+		l.OnSample(0, 10, 10, false)
+		// vegas picks up its rtt no load, AIMD grows
+		asrt.Equal(10, vegas.EstimatedLimit())
+		asrt.Equal(11, aimd.EstimatedLimit())
+		asrt.Equal(10, l.EstimatedLimit())
+
+		// a drop shrinks the loss based limit
+		l.OnSample(10, 10, 11, true)
+		asrt.Equal(5, aimd.EstimatedLimit())
+		asrt.Equal(5, l.EstimatedLimit())
+		asrt.Equal(5, listener.changes[len(listener.changes)-1])
+	})
+
+	t.Run("ConcurrentSamples", func(t2 *testing.T) {
+		t2.Parallel()
+		gradient, _ := NewGradient2Limit("test", 20, 100, 4, nil, -1, -1, nil)
+		l, _ := NewCompositeLimit("test", WeightedAverageLimitReducer(), NewDefaultVegasLimit("test", nil), gradient)
+		done := make(chan struct{})
+		for i := 0; i < 4; i++ {
+			go func(l core.Limit) {
+				for j := 0; j < 100; j++ {
+					l.OnSample(int64(j), int64(j+1), j, j%10 == 0)
+					l.EstimatedLimit()
+				}
+				done <- struct{}{}
+			}(l)
+		}
+		for i := 0; i < 4; i++ {
+			<-done
+		}
+	})
+
+	t.Run("StaleNotification", func(t2 *testing.T) {
+		t2.Parallel()
+		asrt := assert.New(t2)
+		delegate := staleNotifyLimit{NewSettableLimit("test", 10)}
+		l, err := NewCompositeLimit("test", nil, delegate)
+		asrt.NoError(err)
+		delegate.SetLimit(30)
+		asrt.Equal(30, l.EstimatedLimit())
+
+		bounded, _ := NewBoundedLimit(delegate, 1, 100, 0, 0)
+		delegate.SetLimit(40)
+		bounded.OnSample(0, 10, 1, false)
+		asrt.Equal(40, bounded.EstimatedLimit())
+
+		warmup, _ := NewWarmupLimit("test", delegate, 10, WarmupRampLinear, 0, 1, nil, nil)
+		warmup.OnSample(0, 10, 1, false)
+		delegate.SetLimit(50)
+		asrt.Equal(50, warmup.EstimatedLimit())
+
+		cpu, _ := NewCPUPressureLimit("test", delegate, &testCPUStatsReader{}, 0.8, 0.2, 0.1, time.Second, nil, nil)
+		delegate.SetLimit(60)
+		asrt.Equal(60, cpu.EstimatedLimit())
+	})
+}
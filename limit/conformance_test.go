@@ -0,0 +1,216 @@
+package limit
+
+import (
+	"io/ioutil"
+	"testing"
+	"time"
+
+	"github.com/platinummonkey/go-concurrency-limits/core"
+	"github.com/platinummonkey/go-concurrency-limits/limit/limittest"
+)
+
+func TestConformance(t *testing.T) {
+	t.Parallel()
+
+	// algorithms are configured with a large maximum so the step continuity is checked around the lookup tables
+	adaptive := limittest.Config{MinLimit: 1, MaxLimit: 20000}
+	delayBased := limittest.Config{MinLimit: 1, MaxLimit: 20000, IgnoresDrops: true}
+	lossBased := limittest.Config{MinLimit: 1, MaxLimit: 20000, IgnoresLatency: true}
+	// wrappers delegate to VegasLimit as it reacts to both latency and drops
+	newDelegate := func(initialLimit int) (core.Limit, error) {
+		return NewVegasLimitWithRegistry("test", initialLimit, nil, 20000, -1, nil, nil, nil, nil, nil, -1, nil), nil
+	}
+
+	for _, tc := range []struct {
+		name    string
+		factory limittest.Factory
+		config  limittest.Config
+	}{
+		{
+			name: "AIMDLimit",
+			factory: func(initialLimit int, clock func() int64) (core.Limit, error) {
+				return NewAIMDLimit("test", initialLimit, 0.9), nil
+			},
+			config: lossBased,
+		},
+		{
+			name: "BBRLimit",
+			factory: func(initialLimit int, clock func() int64) (core.Limit, error) {
+				return NewBBRLimit("test", initialLimit, 1, 20000, 0, 0, 0, nil)
+			},
+			config: delayBased,
+		},
+		{
+			name: "CubicLimit",
+			factory: func(initialLimit int, clock func() int64) (core.Limit, error) {
+				return NewCubicLimit("test", initialLimit, 1, 20000, 0, 0, true, clock, nil)
+			},
+			config: lossBased,
+		},
+		{
+			name: "ErrorRateLimit",
+			factory: func(initialLimit int, clock func() int64) (core.Limit, error) {
+				return NewErrorRateLimit("test", initialLimit, 1, 20000, 0, 0.05, nil, nil, nil)
+			},
+			config: lossBased,
+		},
+		{
+			name: "FixedLimit",
+			factory: func(initialLimit int, clock func() int64) (core.Limit, error) {
+				return NewFixedLimit("test", initialLimit), nil
+			},
+			config: limittest.Config{MinLimit: 20, MaxLimit: 20, Static: true},
+		},
+		{
+			name: "GradientLimit",
+			factory: func(initialLimit int, clock func() int64) (core.Limit, error) {
+				return NewGradientLimitWithRegistry("test", initialLimit, 1, 20000, -1, nil, -1, 0, nil), nil
+			},
+			config: adaptive,
+		},
+		{
+			name: "Gradient2Limit",
+			factory: func(initialLimit int, clock func() int64) (core.Limit, error) {
+				return NewGradient2Limit("test", initialLimit, 20000, 1, nil, -1, 600, nil)
+			},
+			config: delayBased,
+		},
+		{
+			name: "LatencySLOLimit",
+			factory: func(initialLimit int, clock func() int64) (core.Limit, error) {
+				return NewLatencySLOLimit("test", initialLimit, 20000, 1, 0.9, (time.Millisecond * 20).Nanoseconds(),
+					0.9, 0.1, nil, 1.0, nil)
+			},
+			config: adaptive,
+		},
+		{
+			name: "PIDLimit",
+			factory: func(initialLimit int, clock func() int64) (core.Limit, error) {
+				return NewPIDLimit("test", initialLimit, 1, 20000, PIDQueueingDelay, float64(time.Millisecond*5),
+					10, 1, 0, nil)
+			},
+			config: adaptive,
+		},
+		{
+			name: "SettableLimit",
+			factory: func(initialLimit int, clock func() int64) (core.Limit, error) {
+				return NewSettableLimit("test", initialLimit), nil
+			},
+			config: limittest.Config{MinLimit: 20, MaxLimit: 20, Static: true},
+		},
+		{
+			name: "VegasLimit",
+			factory: func(initialLimit int, clock func() int64) (core.Limit, error) {
+				return NewVegasLimitWithRegistry("test", initialLimit, nil, 20000, -1, nil, nil, nil, nil, nil, -1,
+					nil), nil
+			},
+			config: adaptive,
+		},
+		{
+			name: "BoundedLimit",
+			factory: func(initialLimit int, clock func() int64) (core.Limit, error) {
+				delegate := NewVegasLimitWithRegistry("test", initialLimit, nil, 1000, -1, nil, nil, nil, nil, nil, -1, nil)
+				return NewBoundedLimit(delegate, 5, 500, 10, 0)
+			},
+			config: limittest.Config{MinLimit: 5, MaxLimit: 500},
+		},
+		{
+			name: "CompositeLimit",
+			factory: func(initialLimit int, clock func() int64) (core.Limit, error) {
+				delegate, err := newDelegate(initialLimit)
+				if err != nil {
+					return nil, err
+				}
+				return NewCompositeLimit("test", nil, delegate, NewAIMDLimit("test", initialLimit, 0.9))
+			},
+			config: adaptive,
+		},
+		{
+			name: "CPUPressureLimit",
+			factory: func(initialLimit int, clock func() int64) (core.Limit, error) {
+				delegate, err := newDelegate(initialLimit)
+				if err != nil {
+					return nil, err
+				}
+				reader := &testCPUStatsReader{stats: CPUStats{Utilization: 0.5}}
+				return NewCPUPressureLimit("test", delegate, reader, 0.9, 0.2, 0.1, time.Second, clock, nil)
+			},
+			config: adaptive,
+		},
+		{
+			name: "HistoryLimit",
+			factory: func(initialLimit int, clock func() int64) (core.Limit, error) {
+				delegate, err := newDelegate(initialLimit)
+				if err != nil {
+					return nil, err
+				}
+				return NewHistoryLimit("test", delegate, 100, clock)
+			},
+			config: adaptive,
+		},
+		{
+			name: "RecordingLimit",
+			factory: func(initialLimit int, clock func() int64) (core.Limit, error) {
+				delegate, err := newDelegate(initialLimit)
+				if err != nil {
+					return nil, err
+				}
+				return NewRecordingLimit("test", delegate, ioutil.Discard, clock)
+			},
+			config: adaptive,
+		},
+		{
+			name: "TracedLimit",
+			factory: func(initialLimit int, clock func() int64) (core.Limit, error) {
+				delegate, err := newDelegate(initialLimit)
+				if err != nil {
+					return nil, err
+				}
+				return NewTracedLimit(delegate, NoopLimitLogger{}), nil
+			},
+			config: adaptive,
+		},
+		{
+			name: "WarmupLimit",
+			factory: func(initialLimit int, clock func() int64) (core.Limit, error) {
+				delegate, err := newDelegate(initialLimit)
+				if err != nil {
+					return nil, err
+				}
+				return NewWarmupLimit("test", delegate, 1, WarmupRampLinear, time.Second, 0, clock, nil)
+			},
+			config: adaptive,
+		},
+		{
+			name: "WindowedLimit",
+			factory: func(initialLimit int, clock func() int64) (core.Limit, error) {
+				delegate, err := newDelegate(initialLimit)
+				if err != nil {
+					return nil, err
+				}
+				return NewWindowedLimit("test", (time.Millisecond * 100).Nanoseconds(), time.Second.Nanoseconds(), 10,
+					0, delegate)
+			},
+			config: adaptive,
+		},
+		{
+			// windows fill up by sample count rather than concurrency, a limit below the window size still adapts
+			name: "WindowedLimitLowConcurrency",
+			factory: func(initialLimit int, clock func() int64) (core.Limit, error) {
+				delegate, err := newDelegate(initialLimit)
+				if err != nil {
+					return nil, err
+				}
+				return NewWindowedLimit("test", (time.Millisecond * 100).Nanoseconds(), time.Second.Nanoseconds(), 10,
+					0, delegate)
+			},
+			config: limittest.Config{InitialLimit: 4, MinLimit: 1, MaxLimit: 20000},
+		},
+	} {
+		tc := tc
+		t.Run(tc.name, func(t2 *testing.T) {
+			t2.Parallel()
+			limittest.Run(t2, tc.factory, tc.config)
+		})
+	}
+}
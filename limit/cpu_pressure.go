@@ -0,0 +1,227 @@
+package limit
+
+import (
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/platinummonkey/go-concurrency-limits/core"
+)
+
+// CPUPressureLimit wraps a delegate Limit and scales its estimate down when the CPU is saturated.  RTT alone does not
+// reliably detect saturation of compute bound handlers, so the CPU utilization and pressure stall information are read
+// every interval and compared against their thresholds.  The estimate is scaled by
+//
+//	excess = max((utilization - utilizationThreshold) / (1 - utilizationThreshold),
+//	             (pressure - pressureThreshold) / (1 - pressureThreshold))
+//	limit = delegateLimit * max(minScale, 1 - excess)
+//
+// Stats are refreshed lazily on OnSample once the interval has elapsed.
+type CPUPressureLimit struct {
+	delegate             core.Limit
+	reader               CPUStatsReader
+	utilizationThreshold float64
+	pressureThreshold    float64
+	minScale             float64
+	interval             int64
+
+	stats         CPUStats
+	scale         float64
+	delegateLimit int
+	nextRead      int64
+
+	clock     Clock
+	listeners listenerRegistry
+	logger    Logger
+	mu        sync.RWMutex
+}
+
+// NewDefaultCPUPressureLimit will create a new CPUPressureLimit reading /proc every second, scaling down above 90%
+// utilization or 20% pressure.
+func NewDefaultCPUPressureLimit(
+	name string,
+	delegate core.Limit,
+	logger Logger,
+) (*CPUPressureLimit, error) {
+	return NewCPUPressureLimit(
+		name,
+		delegate,
+		NewProcCPUStatsReader(""),
+		0.9,
+		0.2,
+		0.1,
+		time.Second,
+		nil,
+		logger,
+	)
+}
+
+// NewCPUPressureLimit will create a new CPUPressureLimit.
+// @param delegate: The limit to scale.
+// @param reader: Source of the CPU stats.
+// @param utilizationThreshold: Utilization, accepts [0,1), above which the limit is scaled down.
+// @param pressureThreshold: Pressure, accepts [0,1), above which the limit is scaled down.
+// @param minScale: The minimum scale applied to the delegate's limit, accepts (0,1].
+// @param interval: How often the stats are refreshed.
+// @param clock: Source of the current time, defaults to SystemClock.
+func NewCPUPressureLimit(
+	name string,
+	delegate core.Limit,
+	reader CPUStatsReader,
+	utilizationThreshold float64,
+	pressureThreshold float64,
+	minScale float64,
+	interval time.Duration,
+	clock Clock,
+	logger Logger,
+) (*CPUPressureLimit, error) {
+	if delegate == nil {
+		return nil, fmt.Errorf("delegate must be specified")
+	}
+	if reader == nil {
+		return nil, fmt.Errorf("reader must be specified")
+	}
+	if utilizationThreshold < 0 || utilizationThreshold >= 1 {
+		return nil, fmt.Errorf("utilizationThreshold must be between [0,1)")
+	}
+	if pressureThreshold < 0 || pressureThreshold >= 1 {
+		return nil, fmt.Errorf("pressureThreshold must be between [0,1)")
+	}
+	if minScale <= 0 || minScale > 1 {
+		minScale = 0.1
+	}
+	if interval <= 0 {
+		interval = time.Second
+	}
+	if clock == nil {
+		clock = SystemClock
+	}
+	if logger == nil {
+		logger = NoopLimitLogger{}
+	}
+
+	l := &CPUPressureLimit{
+		delegate:             delegate,
+		reader:               reader,
+		utilizationThreshold: utilizationThreshold,
+		pressureThreshold:    pressureThreshold,
+		minScale:             minScale,
+		interval:             interval.Nanoseconds(),
+		scale:                1.0,
+		delegateLimit:        delegate.EstimatedLimit(),
+		clock:                clock,
+		logger:               logger,
+	}
+	delegate.NotifyOnChange(l.onDelegateChange)
+	return l, nil
+}
+
+// onDelegateChange reads back the delegate's current limit, notifications may be delivered out of order.
+func (l *CPUPressureLimit) onDelegateChange(_ int) {
+	limit := l.delegate.EstimatedLimit()
+	l.mu.Lock()
+	l.delegateLimit = limit
+	newLimit := l.scaledLimit()
+	l.mu.Unlock()
+
+	l.listeners.notify(newLimit)
+}
+
+// scaledLimit returns the delegate's limit scaled by the current scale.
+// note: not thread safe.
+func (l *CPUPressureLimit) scaledLimit() int {
+	return int(math.Max(1, math.Floor(float64(l.delegateLimit)*l.scale)))
+}
+
+// EstimatedLimit returns the delegate's estimated limit scaled down by the CPU signals.
+func (l *CPUPressureLimit) EstimatedLimit() int {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return l.scaledLimit()
+}
+
+// NotifyOnChange will register a callback to receive notification whenever the limit is updated to a new value.
+func (l *CPUPressureLimit) NotifyOnChange(consumer core.LimitChangeListener) core.LimitChangeSubscription {
+	return l.listeners.add(consumer)
+}
+
+// OnSample will refresh the CPU stats if the interval has elapsed and delegate the sample.
+func (l *CPUPressureLimit) OnSample(startTime int64, rtt int64, inFlight int, didDrop bool) {
+	l.refresh()
+	l.delegate.OnSample(startTime, rtt, inFlight, didDrop)
+}
+
+// refresh reads the CPU stats and notifies listeners when the scaled limit changes as a result.
+func (l *CPUPressureLimit) refresh() {
+	now := l.clock()
+	l.mu.Lock()
+	if now < l.nextRead {
+		l.mu.Unlock()
+		return
+	}
+	// claim this interval's read so the stats are read outside of the lock by a single caller
+	l.nextRead = now + l.interval
+	l.mu.Unlock()
+
+	stats, err := l.reader.Read()
+	if err != nil {
+		l.logger.Debugf("failed to read cpu stats: %v", err)
+		return
+	}
+	scale := l.computeScale(stats)
+
+	l.mu.Lock()
+	oldLimit := l.scaledLimit()
+	l.stats = stats
+	l.scale = scale
+	newLimit := l.scaledLimit()
+	l.mu.Unlock()
+
+	if newLimit == oldLimit {
+		return
+	}
+	if l.logger.IsDebugEnabled() {
+		l.logger.Debugf("new limit=%d, utilization=%0.4f, pressure=%0.4f", newLimit, stats.Utilization, stats.Pressure)
+	}
+	l.listeners.notify(newLimit)
+}
+
+func (l *CPUPressureLimit) computeScale(stats CPUStats) float64 {
+	excess := math.Max(
+		(stats.Utilization-l.utilizationThreshold)/(1-l.utilizationThreshold),
+		(stats.Pressure-l.pressureThreshold)/(1-l.pressureThreshold),
+	)
+	return math.Max(l.minScale, math.Min(1, 1-excess))
+}
+
+// MarshalState returns a snapshot of the delegate's learned state, the delegate must be a StatefulLimit.
+func (l *CPUPressureLimit) MarshalState() ([]byte, error) {
+	return marshalDelegateState(l.delegate)
+}
+
+// RestoreState restores the delegate's learned state from a snapshot returned by MarshalState, the restored
+// limit is scaled by the current CPU pressure.
+func (l *CPUPressureLimit) RestoreState(data []byte) error {
+	return restoreDelegateState(l.delegate, data)
+}
+
+// Stats returns the last CPU stats read.
+func (l *CPUPressureLimit) Stats() CPUStats {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return l.stats
+}
+
+// Scale returns the current scale applied to the delegate's limit.
+func (l *CPUPressureLimit) Scale() float64 {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return l.scale
+}
+
+func (l *CPUPressureLimit) String() string {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return fmt.Sprintf("CPUPressureLimit{limit=%d, scale=%0.4f, delegate=%v}", l.scaledLimit(), l.scale, l.delegate)
+}
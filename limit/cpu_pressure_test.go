@@ -0,0 +1,137 @@
+package limit
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type testCPUStatsReader struct {
+	stats  CPUStats
+	err    error
+	reads  int
+	onRead func()
+}
+
+func (r *testCPUStatsReader) Read() (CPUStats, error) {
+	r.reads++
+	if r.onRead != nil {
+		r.onRead()
+	}
+	return r.stats, r.err
+}
+
+func TestCPUPressureLimit(t *testing.T) {
+	t.Parallel()
+
+	t.Run("InvalidArguments", func(t2 *testing.T) {
+		t2.Parallel()
+		asrt := assert.New(t2)
+		reader := &testCPUStatsReader{}
+		_, err := NewCPUPressureLimit("test", nil, reader, 0.9, 0.2, 0.1, time.Second, nil, nil)
+		asrt.Error(err)
+		_, err = NewCPUPressureLimit("test", NewFixedLimit("test", 10), nil, 0.9, 0.2, 0.1, time.Second, nil, nil)
+		asrt.Error(err)
+		_, err = NewCPUPressureLimit("test", NewFixedLimit("test", 10), reader, 1, 0.2, 0.1, time.Second, nil, nil)
+		asrt.Error(err)
+		_, err = NewCPUPressureLimit("test", NewFixedLimit("test", 10), reader, 0.9, -1, 0.1, time.Second, nil, nil)
+		asrt.Error(err)
+		l, err := NewDefaultCPUPressureLimit("test", NewFixedLimit("test", 10), nil)
+		asrt.NoError(err)
+		asrt.Equal(10, l.EstimatedLimit())
+	})
+
+	t.Run("ScalesDown", func(t2 *testing.T) {
+		t2.Parallel()
+		asrt := assert.New(t2)
+		clk := &testClock{}
+		reader := &testCPUStatsReader{}
+		delegate := NewSettableLimit("test", 100)
+		l, err := NewCPUPressureLimit("test", delegate, reader, 0.8, 0.2, 0.1, time.Second, clk.clock, nil)
+		asrt.NoError(err)
+		listener := testNotifyListener{}
+		l.NotifyOnChange(listener.updater())
+
+		// healthy
+		l.OnSample(0, 10, 10, false)
+		asrt.Equal(100, l.EstimatedLimit())
+		asrt.Len(listener.changes, 0)
+
+		// utilization half way between the threshold and saturation
+		reader.stats = CPUStats{Utilization: 0.9}
+		l.OnSample(0, 10, 10, false)
+		// interval not elapsed yet
+		asrt.Equal(100, l.EstimatedLimit())
+		asrt.Equal(1, reader.reads)
+
+		clk.advance(time.Second)
+		l.OnSample(0, 10, 10, false)
+		asrt.Equal(50, l.EstimatedLimit())
+		asrt.Equal([]int{50}, listener.changes)
+		asrt.Equal(CPUStats{Utilization: 0.9}, l.Stats())
+
+		// pressure dominates
+		reader.stats = CPUStats{Utilization: 0.9, Pressure: 0.7}
+		clk.advance(time.Second)
+		l.OnSample(0, 10, 10, false)
+		asrt.Equal(37, l.EstimatedLimit())
+
+		// bounded by the min scale
+		reader.stats = CPUStats{Utilization: 1, Pressure: 1}
+		clk.advance(time.Second)
+		l.OnSample(0, 10, 10, false)
+		asrt.Equal(0.1, l.Scale())
+		asrt.Equal(10, l.EstimatedLimit())
+
+		// delegate changes are propagated scaled
+		delegate.SetLimit(50)
+		asrt.Equal(5, l.EstimatedLimit())
+		asrt.Equal(5, listener.changes[len(listener.changes)-1])
+		asrt.Equal("CPUPressureLimit{limit=5, scale=0.1000, delegate=SettableLimit{limit=50}}", l.String())
+	})
+
+	t.Run("ReadErrorKeepsScale", func(t2 *testing.T) {
+		t2.Parallel()
+		asrt := assert.New(t2)
+		clk := &testClock{}
+		reader := &testCPUStatsReader{stats: CPUStats{Utilization: 0.9}}
+		l, _ := NewCPUPressureLimit("test", NewFixedLimit("test", 100), reader, 0.8, 0.2, 0.1, time.Second, clk.clock, nil)
+		l.OnSample(0, 10, 10, false)
+		asrt.Equal(50, l.EstimatedLimit())
+		reader.err = fmt.Errorf("unavailable")
+		clk.advance(time.Second)
+		l.OnSample(0, 10, 10, false)
+		asrt.Equal(50, l.EstimatedLimit())
+	})
+
+	t.Run("ReadsOutsideLock", func(t2 *testing.T) {
+		t2.Parallel()
+		asrt := assert.New(t2)
+		reader := &testCPUStatsReader{stats: CPUStats{Utilization: 0.9}}
+		l, _ := NewCPUPressureLimit("test", NewFixedLimit("test", 100), reader, 0.8, 0.2, 0.1, time.Second, nil, nil)
+		// a slow read must not block readers of the limit
+		estimated := -1
+		reader.onRead = func() {
+			estimated = l.EstimatedLimit()
+		}
+		l.OnSample(0, 10, 10, false)
+		asrt.Equal(100, estimated)
+		asrt.Equal(50, l.EstimatedLimit())
+	})
+
+	t.Run("State", func(t2 *testing.T) {
+		t2.Parallel()
+		asrt := assert.New(t2)
+		reader := &testCPUStatsReader{stats: CPUStats{Utilization: 0.9}}
+		l, _ := NewCPUPressureLimit("test", NewSettableLimit("test", 10), reader, 0.8, 0.2, 0.1, time.Second, nil, nil)
+		l.OnSample(0, 10, 10, false)
+		// the restored limit is scaled
+		asrt.NoError(l.RestoreState([]byte(`{"algorithm":"settable","estimatedLimit":30}`)))
+		asrt.Equal(15, l.EstimatedLimit())
+		data, err := l.MarshalState()
+		asrt.NoError(err)
+		asrt.Equal(`{"algorithm":"settable","estimatedLimit":30}`, string(data))
+	})
+}
@@ -0,0 +1,152 @@
+package limit
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// CPUStats is a snapshot of the CPU resource signals.
+type CPUStats struct {
+	// Utilization is the fraction of non-idle CPU time, [0,1].
+	Utilization float64
+	// Pressure is the fraction of time some tasks were stalled waiting on CPU, [0,1].
+	Pressure float64
+}
+
+// CPUStatsReader reads the current CPU resource signals.
+type CPUStatsReader interface {
+	// Read returns the current CPU stats.
+	Read() (CPUStats, error)
+}
+
+// ProcCPUStatsReader implements CPUStatsReader for Linux using /proc/stat for utilization and the pressure stall
+// information in /proc/pressure/cpu.  Utilization is computed from the change between consecutive reads, the first
+// read reports the utilization since boot.  Pressure is reported as 0 when PSI is not available.
+type ProcCPUStatsReader struct {
+	procRoot  string
+	lastIdle  uint64
+	lastTotal uint64
+	mu        sync.Mutex
+}
+
+// NewProcCPUStatsReader will create a new ProcCPUStatsReader reading from the given proc root, defaults to /proc.
+func NewProcCPUStatsReader(procRoot string) *ProcCPUStatsReader {
+	if procRoot == "" {
+		procRoot = "/proc"
+	}
+	return &ProcCPUStatsReader{
+		procRoot: procRoot,
+	}
+}
+
+// Read returns the current CPU stats, it is safe for concurrent use.
+func (r *ProcCPUStatsReader) Read() (CPUStats, error) {
+	utilization, err := r.readUtilization()
+	if err != nil {
+		return CPUStats{}, err
+	}
+	pressure, err := readCPUPressure(filepath.Join(r.procRoot, "pressure", "cpu"))
+	if err != nil && !os.IsNotExist(err) {
+		return CPUStats{}, err
+	}
+	return CPUStats{
+		Utilization: utilization,
+		Pressure:    pressure,
+	}, nil
+}
+
+// readUtilization returns the utilization since the previous read.  The counters are read under the lock so
+// concurrent reads are ordered, counters going backwards report no utilization rather than underflowing.
+func (r *ProcCPUStatsReader) readUtilization() (float64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	idle, total, err := readProcStat(filepath.Join(r.procRoot, "stat"))
+	if err != nil {
+		return 0, err
+	}
+	lastIdle, lastTotal := r.lastIdle, r.lastTotal
+	r.lastIdle = idle
+	r.lastTotal = total
+	if idle < lastIdle || total <= lastTotal {
+		return 0, nil
+	}
+	deltaIdle := idle - lastIdle
+	deltaTotal := total - lastTotal
+	if deltaIdle > deltaTotal {
+		return 0, nil
+	}
+	return 1 - float64(deltaIdle)/float64(deltaTotal), nil
+}
+
+// readProcStat returns the idle and total jiffies from the aggregate cpu line of /proc/stat.
+func readProcStat(path string) (uint64, uint64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 5 || fields[0] != "cpu" {
+			continue
+		}
+		var idle, total uint64
+		// user nice system idle iowait irq softirq steal, guest time is already accounted for in user/nice.
+		for i, field := range fields[1:] {
+			if i >= 8 {
+				break
+			}
+			value, err := strconv.ParseUint(field, 10, 64)
+			if err != nil {
+				return 0, 0, fmt.Errorf("invalid cpu field %q: %v", field, err)
+			}
+			total += value
+			if i == 3 || i == 4 {
+				idle += value
+			}
+		}
+		return idle, total, nil
+	}
+	if err := scanner.Err(); err != nil {
+		return 0, 0, err
+	}
+	return 0, 0, fmt.Errorf("no cpu line found in %s", path)
+}
+
+// readCPUPressure returns the `some avg10` pressure as a fraction.
+func readCPUPressure(path string) (float64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 || fields[0] != "some" {
+			continue
+		}
+		for _, field := range fields[1:] {
+			if !strings.HasPrefix(field, "avg10=") {
+				continue
+			}
+			value, err := strconv.ParseFloat(strings.TrimPrefix(field, "avg10="), 64)
+			if err != nil {
+				return 0, fmt.Errorf("invalid pressure field %q: %v", field, err)
+			}
+			return value / 100, nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return 0, err
+	}
+	return 0, fmt.Errorf("no some avg10 found in %s", path)
+}
@@ -0,0 +1,109 @@
+package limit
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func writeProcFixture(t *testing.T, root string, stat string, pressure string) {
+	if err := ioutil.WriteFile(filepath.Join(root, "stat"), []byte(stat), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if pressure == "" {
+		return
+	}
+	if err := os.MkdirAll(filepath.Join(root, "pressure"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(root, "pressure", "cpu"), []byte(pressure), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestProcCPUStatsReader(t *testing.T) {
+	t.Parallel()
+
+	t.Run("UtilizationAndPressure", func(t2 *testing.T) {
+		t2.Parallel()
+		asrt := assert.New(t2)
+		root, err := ioutil.TempDir("", "proc")
+		asrt.NoError(err)
+		defer os.RemoveAll(root)
+
+		writeProcFixture(t2, root,
+			"cpu  100 0 100 700 100 0 0 0 0 0\ncpu0 100 0 100 700 100 0 0 0 0 0\nintr 1\n",
+			"some avg10=12.50 avg60=1.00 avg300=0.00 total=100\nfull avg10=0.00 avg60=0.00 avg300=0.00 total=0\n",
+		)
+		r := NewProcCPUStatsReader(root)
+		stats, err := r.Read()
+		asrt.NoError(err)
+		asrt.InDelta(0.2, stats.Utilization, 0.0001)
+		asrt.InDelta(0.125, stats.Pressure, 0.0001)
+
+		// 900 busy out of 1000 jiffies since the last read
+		writeProcFixture(t2, root, "cpu  600 0 500 800 100 0 0 0 0 0\n", "some avg10=0.00 avg60=0.00 avg300=0.00 total=0\n")
+		stats, err = r.Read()
+		asrt.NoError(err)
+		asrt.InDelta(0.9, stats.Utilization, 0.0001)
+		asrt.Equal(0.0, stats.Pressure)
+	})
+
+	t.Run("NoPressure", func(t2 *testing.T) {
+		t2.Parallel()
+		asrt := assert.New(t2)
+		root, err := ioutil.TempDir("", "proc")
+		asrt.NoError(err)
+		defer os.RemoveAll(root)
+
+		writeProcFixture(t2, root, "cpu  100 0 100 800 0 0 0 0 0 0\n", "")
+		stats, err := NewProcCPUStatsReader(root).Read()
+		asrt.NoError(err)
+		asrt.InDelta(0.2, stats.Utilization, 0.0001)
+		asrt.Equal(0.0, stats.Pressure)
+	})
+
+	t.Run("Invalid", func(t2 *testing.T) {
+		t2.Parallel()
+		asrt := assert.New(t2)
+		root, err := ioutil.TempDir("", "proc")
+		asrt.NoError(err)
+		defer os.RemoveAll(root)
+
+		_, err = NewProcCPUStatsReader(root).Read()
+		asrt.Error(err)
+
+		writeProcFixture(t2, root, "intr 1\n", "")
+		_, err = NewProcCPUStatsReader(root).Read()
+		asrt.Error(err)
+
+		writeProcFixture(t2, root, "cpu  a 0 100 800 0 0 0 0 0 0\n", "")
+		_, err = NewProcCPUStatsReader(root).Read()
+		asrt.Error(err)
+
+		writeProcFixture(t2, root, "cpu  1 0 100 800 0 0 0 0 0 0\n", "some avg10=x\n")
+		_, err = NewProcCPUStatsReader(root).Read()
+		asrt.Error(err)
+	})
+
+	t.Run("CountersGoingBackwards", func(t2 *testing.T) {
+		t2.Parallel()
+		asrt := assert.New(t2)
+		root, err := ioutil.TempDir("", "proc")
+		asrt.NoError(err)
+		defer os.RemoveAll(root)
+
+		writeProcFixture(t2, root, "cpu  600 0 500 800 100 0 0 0 0 0\n", "")
+		r := NewProcCPUStatsReader(root)
+		_, err = r.Read()
+		asrt.NoError(err)
+		// an older snapshot must not underflow into a huge utilization
+		writeProcFixture(t2, root, "cpu  100 0 100 700 100 0 0 0 0 0\n", "")
+		stats, err := r.Read()
+		asrt.NoError(err)
+		asrt.Equal(0.0, stats.Utilization)
+	})
+}
@@ -0,0 +1,221 @@
+package limit
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sync"
+
+	"github.com/platinummonkey/go-concurrency-limits/core"
+)
+
+// CubicLimit implements a loss based limit using the TCP CUBIC growth function (RFC 8312).  After a reduction the
+// limit grows as a cubic function of the time elapsed since that reduction, quickly reclaiming capacity up to the limit
+// at which the last drop was observed (wMax), flattening around wMax and then probing beyond it.
+//
+//	K = cbrt(wMax * (1 - beta) / C)
+//	W(t) = C * (t - K)^3 + wMax
+//
+// Where t is in seconds.  On a drop the limit is reduced to limit * beta.  With fast convergence enabled, if the limit
+// was still below the previous wMax when the drop occurred, wMax is further reduced to release capacity to competing
+// clients.  The limit never grows slower than an equivalent AIMD limit would (the TCP friendly region).
+type CubicLimit struct {
+	estimatedLimit  float64
+	minLimit        int
+	maxLimit        int
+	beta            float64
+	scale           float64
+	fastConvergence bool
+
+	wMax       float64
+	wEst       float64
+	k          float64
+	epochStart int64
+
+	clock     Clock
+	listeners listenerRegistry
+	logger    Logger
+	mu        sync.RWMutex
+}
+
+// NewDefaultCubicLimit will create a new CubicLimit with defaults.
+func NewDefaultCubicLimit(
+	name string,
+	logger Logger,
+) *CubicLimit {
+	l, _ := NewCubicLimit(
+		name,
+		10,
+		1,
+		1000,
+		0.7,
+		0.4,
+		true,
+		nil,
+		logger,
+	)
+	return l
+}
+
+// NewCubicLimit will create a new CubicLimit.
+// @param initialLimit: Initial limit used by the limiter.
+// @param minLimit: Minimum concurrency limit allowed.
+// @param maxConcurrency: Maximum allowable concurrency.  Any estimated concurrency will be capped.
+// @param beta: Multiplicative decrease factor applied on a drop, accepts (0,1).
+// @param scale: The CUBIC scaling constant C controlling the aggressiveness of growth.
+// @param fastConvergence: Release capacity faster when the limit is reduced before reaching the previous wMax.
+// @param clock: Source of the current time, defaults to SystemClock.
+func NewCubicLimit(
+	name string,
+	initialLimit int,
+	minLimit int,
+	maxConcurrency int,
+	beta float64,
+	scale float64,
+	fastConvergence bool,
+	clock Clock,
+	logger Logger,
+) (*CubicLimit, error) {
+	if beta <= 0 || beta >= 1 {
+		beta = 0.7
+	}
+	if scale <= 0 {
+		scale = 0.4
+	}
+	if minLimit <= 0 {
+		minLimit = 1
+	}
+	if maxConcurrency <= 0 {
+		maxConcurrency = 1000
+	}
+	if minLimit > maxConcurrency {
+		return nil, fmt.Errorf("minLimit must be <= maxConcurrency")
+	}
+	if initialLimit <= 0 {
+		initialLimit = 10
+	}
+	if clock == nil {
+		clock = SystemClock
+	}
+	if logger == nil {
+		logger = NoopLimitLogger{}
+	}
+
+	limit := math.Max(float64(minLimit), math.Min(float64(maxConcurrency), float64(initialLimit)))
+	l := &CubicLimit{
+		estimatedLimit:  limit,
+		minLimit:        minLimit,
+		maxLimit:        maxConcurrency,
+		beta:            beta,
+		scale:           scale,
+		fastConvergence: fastConvergence,
+		wMax:            limit,
+		wEst:            limit,
+		k:               0,
+		epochStart:      clock(),
+		clock:           clock,
+		logger:          logger,
+	}
+	return l, nil
+}
+
+// EstimatedLimit returns the current estimated limit.
+func (l *CubicLimit) EstimatedLimit() int {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return int(l.estimatedLimit)
+}
+
+// NotifyOnChange will register a callback to receive notification whenever the limit is updated to a new value.
+func (l *CubicLimit) NotifyOnChange(consumer core.LimitChangeListener) core.LimitChangeSubscription {
+	return l.listeners.add(consumer)
+}
+
+// OnSample the concurrency limit using a new rtt sample.
+func (l *CubicLimit) OnSample(startTime int64, rtt int64, inFlight int, didDrop bool) {
+	if newLimit, ok := l.onSample(startTime, rtt, inFlight, didDrop); ok {
+		l.listeners.notify(newLimit)
+	}
+}
+
+// onSample updates the estimated limit under the lock, returning the limit to notify listeners of.
+func (l *CubicLimit) onSample(startTime int64, rtt int64, inFlight int, didDrop bool) (int, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.clock()
+	var newLimit float64
+	if didDrop {
+		if l.fastConvergence && l.estimatedLimit < l.wMax {
+			l.wMax = l.estimatedLimit * (1 + l.beta) / 2
+		} else {
+			l.wMax = l.estimatedLimit
+		}
+		newLimit = math.Max(float64(l.minLimit), l.estimatedLimit*l.beta)
+		l.k = math.Cbrt(l.wMax * (1 - l.beta) / l.scale)
+		l.epochStart = now
+		l.wEst = newLimit
+	} else if float64(inFlight) < l.estimatedLimit/2 {
+		// Don't grow the limit if we are app limited
+		return 0, false
+	} else {
+		// target the cubic window one rtt into the future
+		t := float64(now-l.epochStart+rtt) / 1e9
+		cubic := l.scale*math.Pow(t-l.k, 3) + l.wMax
+		// AIMD equivalent growth, one increment per sample
+		l.wEst += 3 * (1 - l.beta) / (1 + l.beta)
+		newLimit = math.Max(cubic, l.wEst)
+		// never grow by more than half the current limit in a single sample
+		newLimit = math.Max(l.estimatedLimit, math.Min(newLimit, l.estimatedLimit*1.5+1))
+	}
+	newLimit = math.Max(float64(l.minLimit), math.Min(float64(l.maxLimit), newLimit))
+
+	if int(newLimit) != int(l.estimatedLimit) && l.logger.IsDebugEnabled() {
+		l.logger.Debugf("new limit=%0.2f, wMax=%0.2f, K=%0.4f s, didDrop=%t", newLimit, l.wMax, l.k, didDrop)
+	}
+
+	l.estimatedLimit = newLimit
+	return int(l.estimatedLimit), true
+}
+
+// WMax returns the limit at which the last reduction occurred.
+func (l *CubicLimit) WMax() float64 {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return l.wMax
+}
+
+// MarshalState returns a snapshot of the learned limit.
+func (l *CubicLimit) MarshalState() ([]byte, error) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return json.Marshal(LimitState{
+		Algorithm:      "cubic",
+		EstimatedLimit: l.estimatedLimit,
+	})
+}
+
+// RestoreState restores the learned limit from a snapshot returned by MarshalState, starting a new epoch around it.
+func (l *CubicLimit) RestoreState(data []byte) error {
+	state, err := unmarshalLimitState("cubic", data)
+	if err != nil {
+		return err
+	}
+	l.mu.Lock()
+	l.estimatedLimit = clampLimit(state.EstimatedLimit, l.minLimit, l.maxLimit)
+	l.wMax = l.estimatedLimit
+	l.wEst = l.estimatedLimit
+	l.k = 0
+	l.epochStart = l.clock()
+	newLimit := int(l.estimatedLimit)
+	l.mu.Unlock()
+
+	l.listeners.notify(newLimit)
+	return nil
+}
+
+func (l *CubicLimit) String() string {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return fmt.Sprintf("CubicLimit{limit=%d, wMax=%0.2f, beta=%0.4f}", int(l.estimatedLimit), l.wMax, l.beta)
+}
@@ -0,0 +1,121 @@
+package limit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type testClock struct {
+	now int64
+}
+
+func (c *testClock) clock() int64 {
+	return c.now
+}
+
+func (c *testClock) advance(d time.Duration) {
+	c.now += d.Nanoseconds()
+}
+
+func TestCubicLimit(t *testing.T) {
+	t.Parallel()
+
+	t.Run("Default", func(t2 *testing.T) {
+		t2.Parallel()
+		asrt := assert.New(t2)
+		l := NewDefaultCubicLimit("test", nil)
+		asrt.Equal(10, l.EstimatedLimit())
+		asrt.Equal("CubicLimit{limit=10, wMax=10.00, beta=0.7000}", l.String())
+	})
+
+	t.Run("InvalidBounds", func(t2 *testing.T) {
+		t2.Parallel()
+		_, err := NewCubicLimit("test", 10, 100, 10, 0.7, 0.4, true, nil, nil)
+		assert.Error(t2, err)
+	})
+
+	t.Run("DecreaseOnDrop", func(t2 *testing.T) {
+		t2.Parallel()
+		asrt := assert.New(t2)
+		clk := &testClock{}
+		l, _ := NewCubicLimit("test", 100, 1, 1000, 0.7, 0.4, false, clk.clock, nil)
+		listener := testNotifyListener{}
+		l.NotifyOnChange(listener.updater())
+		l.OnSample(0, (time.Millisecond * 10).Nanoseconds(), 100, true)
+		asrt.Equal(70, l.EstimatedLimit())
+		asrt.Equal(70, listener.changes[0])
+		asrt.Equal(100.0, l.WMax())
+	})
+
+	t.Run("FastConvergence", func(t2 *testing.T) {
+		t2.Parallel()
+		asrt := assert.New(t2)
+		clk := &testClock{}
+		l, _ := NewCubicLimit("test", 100, 1, 1000, 0.7, 0.4, true, clk.clock, nil)
+		l.OnSample(0, (time.Millisecond * 10).Nanoseconds(), 100, true)
+		asrt.Equal(100.0, l.WMax())
+		// dropped again before reaching wMax, release capacity
+		l.OnSample(0, (time.Millisecond * 10).Nanoseconds(), 70, true)
+		asrt.Equal(49, l.EstimatedLimit())
+		asrt.InDelta(59.5, l.WMax(), 0.001)
+	})
+
+	t.Run("AppLimited", func(t2 *testing.T) {
+		t2.Parallel()
+		asrt := assert.New(t2)
+		clk := &testClock{}
+		l, _ := NewCubicLimit("test", 100, 1, 1000, 0.7, 0.4, true, clk.clock, nil)
+		clk.advance(time.Second * 10)
+		l.OnSample(0, (time.Millisecond * 10).Nanoseconds(), 10, false)
+		asrt.Equal(100, l.EstimatedLimit())
+	})
+
+	t.Run("CubicRecovery", func(t2 *testing.T) {
+		t2.Parallel()
+		asrt := assert.New(t2)
+		clk := &testClock{}
+		l, _ := NewCubicLimit("test", 1000, 1, 2000, 0.7, 0.4, false, clk.clock, nil)
+		l.OnSample(0, (time.Millisecond * 10).Nanoseconds(), 1000, true)
+		asrt.Equal(700, l.EstimatedLimit())
+
+		// concave growth, fast at first and plateauing around wMax after K = cbrt(1000 * 0.3 / 0.4) ~= 9.1s
+		previous := l.EstimatedLimit()
+		previousGrowth := 1 << 30
+		for i := 0; i < 9; i++ {
+			clk.advance(time.Second)
+			l.OnSample(0, (time.Millisecond * 10).Nanoseconds(), l.EstimatedLimit(), false)
+			growth := l.EstimatedLimit() - previous
+			asrt.True(growth <= previousGrowth, "expected concave growth")
+			previous, previousGrowth = l.EstimatedLimit(), growth
+		}
+		asrt.InDelta(1000, l.EstimatedLimit(), 10)
+
+		// convex growth beyond wMax
+		for i := 0; i < 5; i++ {
+			clk.advance(time.Second)
+			l.OnSample(0, (time.Millisecond * 10).Nanoseconds(), l.EstimatedLimit(), false)
+		}
+		asrt.True(l.EstimatedLimit() > 1040)
+	})
+
+	t.Run("RecoversFasterThanAIMD", func(t2 *testing.T) {
+		t2.Parallel()
+		asrt := assert.New(t2)
+		clk := &testClock{}
+		cubic, _ := NewCubicLimit("test", 1000, 1, 2000, 0.7, 0.4, true, clk.clock, nil)
+		aimd := NewAIMDLimit("test", 1000, 0.7)
+		cubic.OnSample(0, (time.Millisecond * 10).Nanoseconds(), 1000, true)
+		aimd.OnSample(0, (time.Millisecond * 10).Nanoseconds(), 1000, true)
+
+		// one sample window per second
+		for i := 0; i < 15; i++ {
+			clk.advance(time.Second)
+			cubic.OnSample(0, (time.Millisecond * 10).Nanoseconds(), cubic.EstimatedLimit(), false)
+			aimd.OnSample(0, (time.Millisecond * 10).Nanoseconds(), aimd.EstimatedLimit(), false)
+		}
+		asrt.True(cubic.EstimatedLimit() >= 990)
+		asrt.True(aimd.EstimatedLimit() < 750)
+	})
+}
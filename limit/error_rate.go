@@ -0,0 +1,228 @@
+package limit
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sync"
+
+	"github.com/platinummonkey/go-concurrency-limits/core"
+	"github.com/platinummonkey/go-concurrency-limits/limit/functions"
+)
+
+// ErrorRateLimit implements a loss based limit that tolerates a background error rate.  Unlike AIMDLimit, which
+// backs off on every single drop, samples are aggregated into windows of windowSize samples and the limit is only
+// decreased when the fraction of dropped samples in the window exceeds the threshold.  Otherwise the limit is
+// increased as long as the window was not app limited.
+//
+// Each OnSample call counts as a single request.  Limiters aggregating requests into a sample window, such as
+// DefaultLimiter, report the individual sample and drop counts of the window through OnSampleWindow instead, since
+// the didDrop of an aggregated sample only tells whether the window had any drop at all.
+type ErrorRateLimit struct {
+	estimatedLimit float64
+	minLimit       int
+	maxLimit       int
+	windowSize     int
+	threshold      float64
+	increaseFunc   func(estimatedLimit float64) float64
+	decreaseFunc   func(estimatedLimit float64) float64
+
+	sampleCount int
+	dropCount   int
+	maxInFlight int
+	lastRatio   float64
+
+	listeners listenerRegistry
+	logger    Logger
+	mu        sync.RWMutex
+}
+
+// NewDefaultErrorRateLimit will create a new ErrorRateLimit tolerating up to 5% of dropped samples.
+func NewDefaultErrorRateLimit(
+	name string,
+	logger Logger,
+) *ErrorRateLimit {
+	l, _ := NewErrorRateLimit(name, 20, 1, 1000, 20, 0.05, nil, nil, logger)
+	return l
+}
+
+// NewErrorRateLimit will create a new ErrorRateLimit.
+// @param initialLimit: Initial limit used by the limiter.
+// @param minLimit: Minimum concurrency limit allowed.
+// @param maxConcurrency: Maximum allowable concurrency.  Any estimated concurrency will be capped.
+// @param windowSize: Number of samples aggregated before the limit is updated.
+// @param threshold: The drop ratio, accepts [0,1), above which the limit is decreased.
+// @param increaseFunc: Function to increase the limit when the drop ratio is within the threshold.
+// @param decreaseFunc: Function to decrease the limit when the drop ratio exceeds the threshold.
+func NewErrorRateLimit(
+	name string,
+	initialLimit int,
+	minLimit int,
+	maxConcurrency int,
+	windowSize int,
+	threshold float64,
+	increaseFunc func(estimatedLimit float64) float64,
+	decreaseFunc func(estimatedLimit float64) float64,
+	logger Logger,
+) (*ErrorRateLimit, error) {
+	if threshold < 0 || threshold >= 1 {
+		return nil, fmt.Errorf("threshold must be between [0,1)")
+	}
+	if minLimit <= 0 {
+		minLimit = 1
+	}
+	if maxConcurrency <= 0 {
+		maxConcurrency = 1000
+	}
+	if minLimit > maxConcurrency {
+		return nil, fmt.Errorf("minLimit must be <= maxConcurrency")
+	}
+	if initialLimit <= 0 {
+		initialLimit = 20
+	}
+	if windowSize <= 0 {
+		windowSize = 20
+	}
+	defaultLogFloatFunc := functions.Log10RootFloatFunction(1)
+	if increaseFunc == nil {
+		increaseFunc = func(limit float64) float64 {
+			return limit + defaultLogFloatFunc(limit)
+		}
+	}
+	if decreaseFunc == nil {
+		decreaseFunc = func(limit float64) float64 {
+			return limit * 0.9
+		}
+	}
+	if logger == nil {
+		logger = NoopLimitLogger{}
+	}
+
+	l := &ErrorRateLimit{
+		estimatedLimit: clampLimit(float64(initialLimit), minLimit, maxConcurrency),
+		minLimit:       minLimit,
+		maxLimit:       maxConcurrency,
+		windowSize:     windowSize,
+		threshold:      threshold,
+		increaseFunc:   increaseFunc,
+		decreaseFunc:   decreaseFunc,
+		logger:         logger,
+	}
+	return l, nil
+}
+
+// EstimatedLimit returns the current estimated limit.
+func (l *ErrorRateLimit) EstimatedLimit() int {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return int(l.estimatedLimit)
+}
+
+// NotifyOnChange will register a callback to receive notification whenever the limit is updated to a new value.
+func (l *ErrorRateLimit) NotifyOnChange(consumer core.LimitChangeListener) core.LimitChangeSubscription {
+	return l.listeners.add(consumer)
+}
+
+// OnSample the concurrency limit using a new rtt sample.
+func (l *ErrorRateLimit) OnSample(startTime int64, rtt int64, inFlight int, didDrop bool) {
+	drops := 0
+	if didDrop {
+		drops = 1
+	}
+	if newLimit, ok := l.onSamples(1, drops, inFlight); ok {
+		l.listeners.notify(newLimit)
+	}
+}
+
+// OnSampleWindow updates the concurrency limit using the sample and drop counts of an aggregated window.  A window that
+// doesn't implement core.SampleWindowDropCounter counts as a single drop if it had any.
+func (l *ErrorRateLimit) OnSampleWindow(startTime int64, window core.SampleWindow) {
+	drops := 0
+	if counter, ok := window.(core.SampleWindowDropCounter); ok {
+		drops = counter.DropCount()
+	} else if window.DidDrop() {
+		drops = 1
+	}
+	if newLimit, ok := l.onSamples(window.SampleCount()+drops, drops, window.MaxInFlight()); ok {
+		l.listeners.notify(newLimit)
+	}
+}
+
+// onSamples updates the estimated limit under the lock, returning the limit to notify listeners of.
+func (l *ErrorRateLimit) onSamples(samples int, drops int, inFlight int) (int, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.sampleCount += samples
+	l.dropCount += drops
+	if inFlight > l.maxInFlight {
+		l.maxInFlight = inFlight
+	}
+	if l.sampleCount < l.windowSize {
+		return 0, false
+	}
+
+	ratio := float64(l.dropCount) / float64(l.sampleCount)
+	maxInFlight := l.maxInFlight
+	l.lastRatio = ratio
+	l.sampleCount = 0
+	l.dropCount = 0
+	l.maxInFlight = 0
+
+	var newLimit float64
+	if ratio > l.threshold {
+		newLimit = l.decreaseFunc(l.estimatedLimit)
+	} else if float64(maxInFlight) < l.estimatedLimit/2 {
+		// Don't grow the limit if we are app limited
+		return 0, false
+	} else {
+		newLimit = l.increaseFunc(l.estimatedLimit)
+	}
+	newLimit = math.Max(float64(l.minLimit), math.Min(float64(l.maxLimit), newLimit))
+
+	if int(newLimit) != int(l.estimatedLimit) && l.logger.IsDebugEnabled() {
+		l.logger.Debugf("new limit=%0.2f, dropRatio=%0.4f, threshold=%0.4f", newLimit, ratio, l.threshold)
+	}
+
+	l.estimatedLimit = newLimit
+	return int(l.estimatedLimit), true
+}
+
+// DropRatio returns the drop ratio of the last completed window.
+func (l *ErrorRateLimit) DropRatio() float64 {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return l.lastRatio
+}
+
+// MarshalState returns a snapshot of the learned limit.
+func (l *ErrorRateLimit) MarshalState() ([]byte, error) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return json.Marshal(LimitState{
+		Algorithm:      "error_rate",
+		EstimatedLimit: l.estimatedLimit,
+	})
+}
+
+// RestoreState restores the learned limit from a snapshot returned by MarshalState.
+func (l *ErrorRateLimit) RestoreState(data []byte) error {
+	state, err := unmarshalLimitState("error_rate", data)
+	if err != nil {
+		return err
+	}
+	l.mu.Lock()
+	l.estimatedLimit = clampLimit(state.EstimatedLimit, l.minLimit, l.maxLimit)
+	newLimit := int(l.estimatedLimit)
+	l.mu.Unlock()
+
+	l.listeners.notify(newLimit)
+	return nil
+}
+
+func (l *ErrorRateLimit) String() string {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return fmt.Sprintf("ErrorRateLimit{limit=%d, threshold=%0.4f, dropRatio=%0.4f}",
+		int(l.estimatedLimit), l.threshold, l.lastRatio)
+}
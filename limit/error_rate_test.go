@@ -0,0 +1,120 @@
+package limit
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/platinummonkey/go-concurrency-limits/core"
+	"github.com/platinummonkey/go-concurrency-limits/measurements"
+)
+
+// plainSampleWindow hides the drop count of the wrapped window.
+type plainSampleWindow struct {
+	core.SampleWindow
+}
+
+func TestErrorRateLimit(t *testing.T) {
+	t.Parallel()
+
+	t.Run("Default", func(t2 *testing.T) {
+		t2.Parallel()
+		asrt := assert.New(t2)
+		l := NewDefaultErrorRateLimit("test", nil)
+		asrt.Equal(20, l.EstimatedLimit())
+		asrt.Equal("ErrorRateLimit{limit=20, threshold=0.0500, dropRatio=0.0000}", l.String())
+	})
+
+	t.Run("InvalidArguments", func(t2 *testing.T) {
+		t2.Parallel()
+		asrt := assert.New(t2)
+		_, err := NewErrorRateLimit("test", 10, 1, 100, 10, 1.0, nil, nil, nil)
+		asrt.Error(err)
+		_, err = NewErrorRateLimit("test", 10, 100, 10, 10, 0.1, nil, nil, nil)
+		asrt.Error(err)
+	})
+
+	t.Run("ToleratesBackgroundErrors", func(t2 *testing.T) {
+		t2.Parallel()
+		asrt := assert.New(t2)
+		l, _ := NewErrorRateLimit("test", 20, 1, 100, 10, 0.1, nil, nil, nil)
+		listener := testNotifyListener{}
+		l.NotifyOnChange(listener.updater())
+		for i := 0; i < 10; i++ {
+			// a single drop in the window is within the threshold
+			l.OnSample(0, 10, 20, i == 0)
+			if i < 9 {
+				asrt.Equal(20, l.EstimatedLimit())
+			}
+		}
+		asrt.Equal(21, l.EstimatedLimit())
+		asrt.Equal(21, listener.changes[0])
+		asrt.Equal(0.1, l.DropRatio())
+	})
+
+	t.Run("DecreaseOverThreshold", func(t2 *testing.T) {
+		t2.Parallel()
+		asrt := assert.New(t2)
+		l, _ := NewErrorRateLimit("test", 20, 1, 100, 10, 0.1, nil, func(limit float64) float64 {
+			return limit / 2
+		}, nil)
+		for i := 0; i < 10; i++ {
+			l.OnSample(0, 10, 20, i < 2)
+		}
+		asrt.Equal(10, l.EstimatedLimit())
+		asrt.Equal(0.2, l.DropRatio())
+	})
+
+	t.Run("AppLimited", func(t2 *testing.T) {
+		t2.Parallel()
+		asrt := assert.New(t2)
+		l, _ := NewErrorRateLimit("test", 20, 1, 100, 10, 0.1, nil, nil, nil)
+		for i := 0; i < 10; i++ {
+			l.OnSample(0, 10, 5, false)
+		}
+		asrt.Equal(20, l.EstimatedLimit())
+	})
+
+	t.Run("Bounds", func(t2 *testing.T) {
+		t2.Parallel()
+		asrt := assert.New(t2)
+		l, _ := NewErrorRateLimit("test", 4, 2, 5, 1, 0, func(limit float64) float64 {
+			return limit * 10
+		}, func(limit float64) float64 {
+			return 0
+		}, nil)
+		l.OnSample(0, 10, 4, false)
+		asrt.Equal(5, l.EstimatedLimit())
+		l.OnSample(0, 10, 4, true)
+		asrt.Equal(2, l.EstimatedLimit())
+	})
+
+	t.Run("InitialLimitClamped", func(t2 *testing.T) {
+		t2.Parallel()
+		asrt := assert.New(t2)
+		l, _ := NewErrorRateLimit("test", 500, 2, 100, 10, 0.1, nil, nil, nil)
+		asrt.Equal(100, l.EstimatedLimit())
+		l, _ = NewErrorRateLimit("test", 1, 2, 100, 10, 0.1, nil, nil, nil)
+		asrt.Equal(2, l.EstimatedLimit())
+	})
+
+	t.Run("SampleWindow", func(t2 *testing.T) {
+		t2.Parallel()
+		asrt := assert.New(t2)
+		window := measurements.NewDefaultImmutableSampleWindow()
+		for i := 0; i < 18; i++ {
+			window = window.AddSample(-1, 10, 20)
+		}
+		window = window.AddDroppedSample(-1, 20).AddDroppedSample(-1, 20)
+
+		// 2 drops in 20 requests is over the threshold
+		l, _ := NewErrorRateLimit("test", 20, 1, 100, 19, 0.06, nil, nil, nil)
+		l.OnSampleWindow(0, window)
+		asrt.Equal(18, l.EstimatedLimit())
+
+		// without a drop count the window counts as a single drop, which is within the threshold
+		l, _ = NewErrorRateLimit("test", 20, 1, 100, 19, 0.06, nil, nil, nil)
+		l.OnSampleWindow(0, plainSampleWindow{window})
+		asrt.Equal(21, l.EstimatedLimit())
+	})
+}
@@ -30,8 +30,9 @@ func (l *FixedLimit) EstimatedLimit() int {
 }
 
 // NotifyOnChange will register a callback to receive notification whenever the limit is updated to a new value.
-func (l *FixedLimit) NotifyOnChange(consumer core.LimitChangeListener) {
+func (l *FixedLimit) NotifyOnChange(consumer core.LimitChangeListener) core.LimitChangeSubscription {
 	// noop for fixed limit
+	return noopSubscription{}
 }
 
 // OnSample will update the limit with the sample.
@@ -17,14 +17,15 @@ func TestLog10RootFunction(t *testing.T) {
 
 	t.Run("MaxIndex", func(t2 *testing.T) {
 		t2.Parallel()
-		f := Log10RootFunction(4)
-		assert.Equal(t2, 31, f(1000))
+		f := Log10RootFunction(1)
+		assert.Equal(t2, 2, f(999))
+		assert.Equal(t2, 3, f(1000))
 	})
 
 	t.Run("OutOfLookupRange", func(t2 *testing.T) {
 		t2.Parallel()
-		f := Log10RootFunction(4)
-		assert.Equal(t2, 50, f(2500))
+		f := Log10RootFunction(1)
+		assert.Equal(t2, 4, f(25000))
 	})
 }
 
@@ -39,13 +40,14 @@ func TestLog10RootFloatFunction(t *testing.T) {
 
 	t.Run("MaxIndex", func(t2 *testing.T) {
 		t2.Parallel()
-		f := Log10RootFloatFunction(4)
-		assert.InDelta(t2, 31.623, f(1000), 0.001)
+		f := Log10RootFloatFunction(1)
+		assert.Equal(t2, 2.0, f(999))
+		assert.Equal(t2, 3.0, f(1000))
 	})
 
 	t.Run("OutOfLookupRange", func(t2 *testing.T) {
 		t2.Parallel()
-		f := Log10RootFloatFunction(4)
-		assert.Equal(t2, 50.0, f(2500))
+		f := Log10RootFloatFunction(1)
+		assert.InDelta(t2, 4.398, f(25000), 0.001)
 	})
 }
@@ -1,6 +1,7 @@
 package limit
 
 import (
+	"encoding/json"
 	"fmt"
 	"math"
 	"math/rand"
@@ -25,7 +26,7 @@ type GradientLimit struct {
 	probeInterval        int
 	resetRTTCounter      int
 	rttNoLoadMeasurement core.MeasurementInterface
-	listeners            []core.LimitChangeListener
+	listeners            listenerRegistry
 	logger               Logger
 
 	mu sync.RWMutex
@@ -85,7 +86,6 @@ func NewGradientLimitWithRegistry(
 		probeInterval:        probeInterval,
 		resetRTTCounter:      nextProbeCountdown(probeInterval),
 		rttNoLoadMeasurement: &measurements.MinimumMeasurement{},
-		listeners:            make([]core.LimitChangeListener, 0),
 		logger:               logger,
 	}
 
@@ -107,21 +107,19 @@ func (l *GradientLimit) RTTNoLoad() int64 {
 }
 
 // NotifyOnChange will register a callback to receive notification whenever the limit is updated to a new value.
-func (l *GradientLimit) NotifyOnChange(consumer core.LimitChangeListener) {
-	l.mu.Lock()
-	l.listeners = append(l.listeners, consumer)
-	l.mu.Unlock()
+func (l *GradientLimit) NotifyOnChange(consumer core.LimitChangeListener) core.LimitChangeSubscription {
+	return l.listeners.add(consumer)
 }
 
-// notifyListeners will call the callbacks on limit changes
-func (l *GradientLimit) notifyListeners(newLimit float64) {
-	for _, listener := range l.listeners {
-		listener(int(newLimit))
+// OnSample the concurrency limit using a new rtt sample.
+func (l *GradientLimit) OnSample(startTime int64, rtt int64, inFlight int, didDrop bool) {
+	if newLimit, ok := l.onSample(startTime, rtt, inFlight, didDrop); ok {
+		l.listeners.notify(newLimit)
 	}
 }
 
-// OnSample the concurrency limit using a new rtt sample.
-func (l *GradientLimit) OnSample(startTime int64, rtt int64, inFlight int, didDrop bool) {
+// onSample updates the estimated limit under the lock, returning the limit to notify listeners of.
+func (l *GradientLimit) onSample(startTime int64, rtt int64, inFlight int, didDrop bool) (int, bool) {
 	l.mu.Lock()
 	defer l.mu.Unlock()
 
@@ -138,8 +136,7 @@ func (l *GradientLimit) OnSample(startTime int64, rtt int64, inFlight int, didDr
 			l.estimatedLimit = math.Max(float64(l.minLimit), float64(queueSize))
 			l.rttNoLoadMeasurement.Reset()
 			l.logger.Debugf("probe minRTT limit=%d", int(l.estimatedLimit))
-			l.notifyListeners(l.estimatedLimit)
-			return
+			return int(l.estimatedLimit), true
 		}
 	}
 
@@ -158,10 +155,10 @@ func (l *GradientLimit) OnSample(startTime int64, rtt int64, inFlight int, didDr
 		newLimit = l.estimatedLimit / 2
 	} else if float64(inFlight) < l.estimatedLimit/2 {
 		// Don't grow the limit if we are app limited
-		return
+		return 0, false
 	} else {
 		// Normal update to the limit
-		newLimit = l.estimatedLimit * gradient * float64(queueSize)
+		newLimit = l.estimatedLimit*gradient + float64(queueSize)
 	}
 
 	if newLimit < l.estimatedLimit {
@@ -176,7 +173,34 @@ func (l *GradientLimit) OnSample(startTime int64, rtt int64, inFlight int, didDr
 	}
 
 	l.estimatedLimit = newLimit
-	l.notifyListeners(l.estimatedLimit)
+	return int(l.estimatedLimit), true
+}
+
+// MarshalState returns a snapshot of the learned limit and RTT No Load.
+func (l *GradientLimit) MarshalState() ([]byte, error) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return json.Marshal(LimitState{
+		Algorithm:      "gradient",
+		EstimatedLimit: l.estimatedLimit,
+		RTTNoLoad:      int64(l.rttNoLoadMeasurement.Get()),
+	})
+}
+
+// RestoreState restores the learned limit and RTT No Load from a snapshot returned by MarshalState.
+func (l *GradientLimit) RestoreState(data []byte) error {
+	state, err := unmarshalLimitState("gradient", data)
+	if err != nil {
+		return err
+	}
+	l.mu.Lock()
+	l.estimatedLimit = clampLimit(state.EstimatedLimit, l.minLimit, l.maxLimit)
+	restoreMeasurement(l.rttNoLoadMeasurement, float64(state.RTTNoLoad))
+	newLimit := int(l.estimatedLimit)
+	l.mu.Unlock()
+
+	l.listeners.notify(newLimit)
+	return nil
 }
 
 func (l *GradientLimit) String() string {
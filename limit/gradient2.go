@@ -1,6 +1,7 @@
 package limit
 
 import (
+	"encoding/json"
 	"fmt"
 	"math"
 	"sync"
@@ -56,7 +57,7 @@ type Gradient2Limit struct {
 	smoothing     float64
 
 	mu        sync.RWMutex
-	listeners []core.LimitChangeListener
+	listeners listenerRegistry
 	logger    Logger
 }
 
@@ -134,7 +135,6 @@ func NewGradient2Limit(
 		smoothing:      smoothing,
 		shortRTT:       &measurements.SingleMeasurement{},
 		longRTT:        measurements.NewExponentialAverageMeasurement(longWindow, 10),
-		listeners:      make([]core.LimitChangeListener, 0),
 		logger:         logger,
 	}
 
@@ -149,21 +149,19 @@ func (l *Gradient2Limit) EstimatedLimit() int {
 }
 
 // NotifyOnChange will register a callback to receive notification whenever the limit is updated to a new value.
-func (l *Gradient2Limit) NotifyOnChange(consumer core.LimitChangeListener) {
-	l.mu.Lock()
-	l.listeners = append(l.listeners, consumer)
-	l.mu.Unlock()
+func (l *Gradient2Limit) NotifyOnChange(consumer core.LimitChangeListener) core.LimitChangeSubscription {
+	return l.listeners.add(consumer)
 }
 
-// notifyListeners will call the callbacks on limit changes
-func (l *Gradient2Limit) notifyListeners(newLimit int) {
-	for _, listener := range l.listeners {
-		listener(newLimit)
+// OnSample the concurrency limit using a new rtt sample.
+func (l *Gradient2Limit) OnSample(startTime int64, rtt int64, inFlight int, didDrop bool) {
+	if newLimit, ok := l.onSample(startTime, rtt, inFlight, didDrop); ok {
+		l.listeners.notify(newLimit)
 	}
 }
 
-// OnSample the concurrency limit using a new rtt sample.
-func (l *Gradient2Limit) OnSample(startTime int64, rtt int64, inFlight int, didDrop bool) {
+// onSample updates the estimated limit under the lock, returning the limit to notify listeners of.
+func (l *Gradient2Limit) onSample(startTime int64, rtt int64, inFlight int, didDrop bool) (int, bool) {
 	l.mu.Lock()
 	defer l.mu.Unlock()
 
@@ -183,7 +181,7 @@ func (l *Gradient2Limit) OnSample(startTime int64, rtt int64, inFlight int, didD
 
 	// Don't grow the limit if we are app limited
 	if float64(inFlight) < l.estimatedLimit/2 {
-		return
+		return 0, false
 	}
 
 	// Rtt could be higher than rtt_noload because of smoothing rtt noload updates
@@ -201,7 +199,36 @@ func (l *Gradient2Limit) OnSample(startTime int64, rtt int64, inFlight int, didD
 	}
 
 	l.estimatedLimit = newLimit
-	l.notifyListeners(int(l.estimatedLimit))
+	return int(l.estimatedLimit), true
+}
+
+// MarshalState returns a snapshot of the learned limit and the long and short RTT.
+func (l *Gradient2Limit) MarshalState() ([]byte, error) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return json.Marshal(LimitState{
+		Algorithm:      "gradient2",
+		EstimatedLimit: l.estimatedLimit,
+		LongRTT:        l.longRTT.Get(),
+		ShortRTT:       l.shortRTT.Get(),
+	})
+}
+
+// RestoreState restores the learned limit and the long and short RTT from a snapshot returned by MarshalState.
+func (l *Gradient2Limit) RestoreState(data []byte) error {
+	state, err := unmarshalLimitState("gradient2", data)
+	if err != nil {
+		return err
+	}
+	l.mu.Lock()
+	l.estimatedLimit = clampLimit(state.EstimatedLimit, l.minLimit, l.maxLimit)
+	restoreMeasurement(l.longRTT, state.LongRTT)
+	restoreMeasurement(l.shortRTT, state.ShortRTT)
+	newLimit := int(l.estimatedLimit)
+	l.mu.Unlock()
+
+	l.listeners.notify(newLimit)
+	return nil
 }
 
 func (l *Gradient2Limit) String() string {
@@ -0,0 +1,140 @@
+package limit
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/platinummonkey/go-concurrency-limits/core"
+)
+
+// LimitHistoryEntry is a single sample recorded by HistoryLimit.
+type LimitHistoryEntry struct {
+	// Timestamp is when the sample was recorded.
+	Timestamp time.Time `json:"timestamp"`
+	// RTT is the round trip time of the sample, in nanoseconds.
+	RTT int64 `json:"rtt"`
+	// InFlight is the in flight count observed during the sample.
+	InFlight int `json:"inFlight"`
+	// DidDrop is true if the sample was dropped.
+	DidDrop bool `json:"didDrop"`
+	// Limit is the delegate's estimated limit after the sample.
+	Limit int `json:"limit"`
+}
+
+// HistoryLimit wraps a delegate Limit and records the last samples together with the resulting limit in a ring
+// buffer, so it can be established after the fact why the limit moved.  The history can be retrieved via History,
+// written as JSON via WriteJSON or served over HTTP as HistoryLimit implements http.Handler.
+type HistoryLimit struct {
+	delegate core.Limit
+	entries  []LimitHistoryEntry
+	next     int
+	count    int
+	clock    Clock
+	mu       sync.RWMutex
+}
+
+// NewHistoryLimit will create a new HistoryLimit.
+// @param delegate: The limit to record.
+// @param size: The number of samples retained.
+// @param clock: Source of the sample timestamps, defaults to SystemClock.
+func NewHistoryLimit(
+	name string,
+	delegate core.Limit,
+	size int,
+	clock Clock,
+) (*HistoryLimit, error) {
+	if delegate == nil {
+		return nil, fmt.Errorf("delegate must be specified")
+	}
+	if size <= 0 {
+		return nil, fmt.Errorf("size must be > 0")
+	}
+	if clock == nil {
+		clock = SystemClock
+	}
+	return &HistoryLimit{
+		delegate: delegate,
+		entries:  make([]LimitHistoryEntry, size),
+		clock:    clock,
+	}, nil
+}
+
+// EstimatedLimit returns the delegate's estimated limit.
+func (l *HistoryLimit) EstimatedLimit() int {
+	return l.delegate.EstimatedLimit()
+}
+
+// NotifyOnChange will register a callback to receive notification whenever the limit is updated to a new value.
+func (l *HistoryLimit) NotifyOnChange(consumer core.LimitChangeListener) core.LimitChangeSubscription {
+	return l.delegate.NotifyOnChange(consumer)
+}
+
+// OnSample will delegate the sample and record it together with the resulting limit.
+func (l *HistoryLimit) OnSample(startTime int64, rtt int64, inFlight int, didDrop bool) {
+	l.delegate.OnSample(startTime, rtt, inFlight, didDrop)
+	entry := LimitHistoryEntry{
+		Timestamp: time.Unix(0, l.clock()).UTC(),
+		RTT:       rtt,
+		InFlight:  inFlight,
+		DidDrop:   didDrop,
+		Limit:     l.delegate.EstimatedLimit(),
+	}
+
+	l.mu.Lock()
+	l.entries[l.next] = entry
+	l.next = (l.next + 1) % len(l.entries)
+	if l.count < len(l.entries) {
+		l.count++
+	}
+	l.mu.Unlock()
+}
+
+// MarshalState returns a snapshot of the delegate's learned state, the delegate must be a StatefulLimit.
+func (l *HistoryLimit) MarshalState() ([]byte, error) {
+	return marshalDelegateState(l.delegate)
+}
+
+// RestoreState restores the delegate's learned state from a snapshot returned by MarshalState.
+func (l *HistoryLimit) RestoreState(data []byte) error {
+	return restoreDelegateState(l.delegate, data)
+}
+
+// History returns the recorded samples, oldest first.
+func (l *HistoryLimit) History() []LimitHistoryEntry {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	history := make([]LimitHistoryEntry, 0, l.count)
+	start := (l.next - l.count + len(l.entries)) % len(l.entries)
+	for i := 0; i < l.count; i++ {
+		history = append(history, l.entries[(start+i)%len(l.entries)])
+	}
+	return history
+}
+
+// WriteJSON writes the recorded samples, oldest first, as a JSON array.
+func (l *HistoryLimit) WriteJSON(w io.Writer) error {
+	return json.NewEncoder(w).Encode(l.History())
+}
+
+// ServeHTTP serves the recorded samples, oldest first, as a JSON array.
+func (l *HistoryLimit) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := l.WriteJSON(w); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+func (l *HistoryLimit) String() string {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return fmt.Sprintf("HistoryLimit{size=%d, recorded=%d, delegate=%v}", len(l.entries), l.count, l.delegate)
+}
@@ -0,0 +1,96 @@
+package limit
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHistoryLimit(t *testing.T) {
+	t.Parallel()
+
+	t.Run("InvalidArguments", func(t2 *testing.T) {
+		t2.Parallel()
+		asrt := assert.New(t2)
+		_, err := NewHistoryLimit("test", nil, 10, nil)
+		asrt.Error(err)
+		_, err = NewHistoryLimit("test", NewFixedLimit("test", 10), 0, nil)
+		asrt.Error(err)
+	})
+
+	t.Run("RingBuffer", func(t2 *testing.T) {
+		t2.Parallel()
+		asrt := assert.New(t2)
+		clk := &testClock{}
+		delegate := NewAIMDLimit("test", 10, 0.5)
+		l, err := NewHistoryLimit("test", delegate, 3, clk.clock)
+		asrt.NoError(err)
+		asrt.Len(l.History(), 0)
+
+		listener := testNotifyListener{}
+		l.NotifyOnChange(listener.updater())
+
+		l.OnSample(0, 10, 10, false)
+		clk.advance(time.Second)
+		l.OnSample(0, 20, 1, true)
+		asrt.Equal([]LimitHistoryEntry{
+			{Timestamp: time.Unix(0, 0).UTC(), RTT: 10, InFlight: 10, DidDrop: false, Limit: 11},
+			{Timestamp: time.Unix(1, 0).UTC(), RTT: 20, InFlight: 1, DidDrop: true, Limit: 5},
+		}, l.History())
+		asrt.Equal([]int{11, 5}, listener.changes)
+		asrt.Equal(5, l.EstimatedLimit())
+
+		// the oldest samples are overwritten
+		l.OnSample(0, 30, 1, false)
+		l.OnSample(0, 40, 1, false)
+		history := l.History()
+		asrt.Len(history, 3)
+		asrt.Equal(int64(20), history[0].RTT)
+		asrt.Equal(int64(40), history[2].RTT)
+		asrt.Equal("HistoryLimit{size=3, recorded=3, delegate=AIMDLimit{limit=5, backOffRatio=0.5000}}", l.String())
+	})
+
+	t.Run("JSON", func(t2 *testing.T) {
+		t2.Parallel()
+		asrt := assert.New(t2)
+		clk := &testClock{}
+		l, err := NewHistoryLimit("test", NewFixedLimit("test", 10), 10, clk.clock)
+		asrt.NoError(err)
+		l.OnSample(0, 10, 2, true)
+
+		expected := `[{"timestamp":"1970-01-01T00:00:00Z","rtt":10,"inFlight":2,"didDrop":true,"limit":10}]`
+		var buf bytes.Buffer
+		asrt.NoError(l.WriteJSON(&buf))
+		asrt.JSONEq(expected, buf.String())
+
+		recorder := httptest.NewRecorder()
+		l.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/debug/limit", nil))
+		asrt.Equal(http.StatusOK, recorder.Code)
+		asrt.Equal("application/json", recorder.Header().Get("Content-Type"))
+		asrt.JSONEq(expected, recorder.Body.String())
+
+		var history []LimitHistoryEntry
+		asrt.NoError(json.Unmarshal(recorder.Body.Bytes(), &history))
+		asrt.Equal(l.History(), history)
+
+		recorder = httptest.NewRecorder()
+		l.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/debug/limit", nil))
+		asrt.Equal(http.StatusMethodNotAllowed, recorder.Code)
+	})
+
+	t.Run("State", func(t2 *testing.T) {
+		t2.Parallel()
+		asrt := assert.New(t2)
+		l, _ := NewHistoryLimit("test", NewSettableLimit("test", 10), 10, nil)
+		asrt.NoError(l.RestoreState([]byte(`{"algorithm":"settable","estimatedLimit":30}`)))
+		asrt.Equal(30, l.EstimatedLimit())
+		data, err := l.MarshalState()
+		asrt.NoError(err)
+		asrt.Equal(`{"algorithm":"settable","estimatedLimit":30}`, string(data))
+	})
+}
@@ -0,0 +1,248 @@
+package limit
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sync"
+
+	"github.com/platinummonkey/go-concurrency-limits/core"
+	"github.com/platinummonkey/go-concurrency-limits/limit/functions"
+	"github.com/platinummonkey/go-concurrency-limits/measurements"
+)
+
+// LatencySLOLimit implements a concurrency limit that targets an explicit latency SLO, for example p99 < 200ms,
+// rather than a baseline RTT.  A moving percentile of the RTT samples is tracked and compared to the target.
+//
+// The limit is updated with each sample using
+//
+//	if percentileRtt > targetRtt || didDrop:
+//	    newLimit = currentLimit * backOffRatio
+//	else if percentileRtt < targetRtt * (1 - headroom):
+//	    newLimit = currentLimit + increaseFunc(currentLimit)
+//
+//	// Update the limit using a smoothing factor (default 0.2)
+//	newLimit = currentLimit * (1-smoothing) + newLimit * smoothing
+//
+// When the percentile is within the headroom band just below the target the limit is held steady.
+type LatencySLOLimit struct {
+	// Estimated concurrency limit based on our algorithm
+	estimatedLimit float64
+	// Tracks the moving percentile of the RTT samples
+	percentileRTT core.MeasurementInterface
+	// The percentile RTT target in nanoseconds
+	targetRTT int64
+	// Maximum allowed limit providing an upper bound failsafe
+	maxLimit int
+	// Minimum allowed limit providing a lower bound failsafe
+	minLimit     int
+	backOffRatio float64
+	headroom     float64
+	increaseFunc func(limit int) int
+	smoothing    float64
+
+	mu        sync.RWMutex
+	listeners listenerRegistry
+	logger    Logger
+}
+
+// NewDefaultLatencySLOLimit creates a default LatencySLOLimit targeting the given percentile and RTT in nanoseconds.
+func NewDefaultLatencySLOLimit(
+	name string,
+	percentile float64,
+	targetRTT int64,
+	logger Logger,
+) (*LatencySLOLimit, error) {
+	return NewLatencySLOLimit(
+		name,
+		20,
+		1000,
+		4,
+		percentile,
+		targetRTT,
+		0.9,
+		0.1,
+		nil,
+		0.2,
+		logger,
+	)
+}
+
+// NewLatencySLOLimit will create a new LatencySLOLimit
+// @param initialLimit: Initial limit used by the limiter.
+// @param maxConcurrency: Maximum allowable concurrency.  Any estimated concurrency will be capped.
+// @param minLimit: Minimum concurrency limit allowed.
+// @param percentile: The RTT percentile to track, accepts (0,1), for example 0.99.
+// @param targetRTT: The target for the tracked RTT percentile in nanoseconds.
+// @param backOffRatio: Multiplicative decrease applied when the percentile exceeds the target, accepts (0,1).
+// @param headroom: Fraction of the target below which the limit is increased, accepts [0,1).
+// @param increaseFunc: Function to determine the additive increase as a function of the current limit.
+// @param smoothing: Smoothing factor, value of 0.0 to 1.0 where 1.0 means the limit is completely replicated by the
+// new estimate.  Defaults to 0.2 like Gradient2Limit: the moving percentile is noisy, especially while it is warming up,
+// and a single estimate over the target should not cut the limit by the full backOffRatio.
+func NewLatencySLOLimit(
+	name string,
+	initialLimit int,
+	maxConcurrency int,
+	minLimit int,
+	percentile float64,
+	targetRTT int64,
+	backOffRatio float64,
+	headroom float64,
+	increaseFunc func(limit int) int,
+	smoothing float64,
+	logger Logger,
+) (*LatencySLOLimit, error) {
+	if targetRTT <= 0 {
+		return nil, fmt.Errorf("targetRTT must be > 0 ns")
+	}
+	if smoothing > 1.0 || smoothing <= 0 {
+		smoothing = 0.2
+	}
+	if backOffRatio <= 0 || backOffRatio >= 1.0 {
+		backOffRatio = 0.9
+	}
+	if headroom < 0 || headroom >= 1.0 {
+		headroom = 0.1
+	}
+	if maxConcurrency <= 0 {
+		maxConcurrency = 1000
+	}
+	if minLimit <= 0 {
+		minLimit = 4
+	}
+	if minLimit > maxConcurrency {
+		return nil, fmt.Errorf("minLimit must be <= maxConcurrency")
+	}
+	if initialLimit <= 0 {
+		initialLimit = 20
+	}
+	if increaseFunc == nil {
+		increaseFunc = functions.Log10RootFunction(1)
+	}
+	if logger == nil {
+		logger = NoopLimitLogger{}
+	}
+
+	percentileRTT, err := measurements.NewWindowlessMovingPercentile(percentile, 0.01, 0.05, 0.05)
+	if err != nil {
+		return nil, err
+	}
+
+	l := &LatencySLOLimit{
+		estimatedLimit: clampLimit(float64(initialLimit), minLimit, maxConcurrency),
+		percentileRTT:  percentileRTT,
+		targetRTT:      targetRTT,
+		maxLimit:       maxConcurrency,
+		minLimit:       minLimit,
+		backOffRatio:   backOffRatio,
+		headroom:       headroom,
+		increaseFunc:   increaseFunc,
+		smoothing:      smoothing,
+		logger:         logger,
+	}
+
+	return l, nil
+}
+
+// EstimatedLimit returns the current estimated limit.
+func (l *LatencySLOLimit) EstimatedLimit() int {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return int(l.estimatedLimit)
+}
+
+// NotifyOnChange will register a callback to receive notification whenever the limit is updated to a new value.
+func (l *LatencySLOLimit) NotifyOnChange(consumer core.LimitChangeListener) core.LimitChangeSubscription {
+	return l.listeners.add(consumer)
+}
+
+// OnSample the concurrency limit using a new rtt sample.
+func (l *LatencySLOLimit) OnSample(startTime int64, rtt int64, inFlight int, didDrop bool) {
+	if newLimit, ok := l.onSample(startTime, rtt, inFlight, didDrop); ok {
+		l.listeners.notify(newLimit)
+	}
+}
+
+// onSample updates the estimated limit under the lock, returning the limit to notify listeners of.
+func (l *LatencySLOLimit) onSample(startTime int64, rtt int64, inFlight int, didDrop bool) (int, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	var percentileRTT float64
+	if didDrop {
+		percentileRTT = l.percentileRTT.Get()
+	} else {
+		percentileRTT, _ = l.percentileRTT.Add(float64(rtt))
+	}
+	target := float64(l.targetRTT)
+
+	var newLimit float64
+	if didDrop || percentileRTT > target {
+		newLimit = l.estimatedLimit * l.backOffRatio
+	} else if percentileRTT < target*(1-l.headroom) {
+		// Don't grow the limit if we are app limited
+		if float64(inFlight) < l.estimatedLimit/2 {
+			return 0, false
+		}
+		newLimit = l.estimatedLimit + float64(l.increaseFunc(int(l.estimatedLimit)))
+	} else {
+		// otherwise we're within the headroom so nothing to do
+		return 0, false
+	}
+
+	newLimit = l.estimatedLimit*(1-l.smoothing) + newLimit*l.smoothing
+	newLimit = math.Max(float64(l.minLimit), math.Min(float64(l.maxLimit), newLimit))
+
+	if int(newLimit) != int(l.estimatedLimit) && l.logger.IsDebugEnabled() {
+		l.logger.Debugf("new limit=%0.2f, percentileRTT=%d ms, targetRTT=%d ms",
+			newLimit, int64(percentileRTT)/1e6, l.targetRTT/1e6)
+	}
+
+	l.estimatedLimit = newLimit
+	return int(l.estimatedLimit), true
+}
+
+// PercentileRTT returns the current tracked RTT percentile in nanoseconds.
+func (l *LatencySLOLimit) PercentileRTT() int64 {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return int64(l.percentileRTT.Get())
+}
+
+// TargetRTT returns the configured target for the tracked RTT percentile in nanoseconds.
+func (l *LatencySLOLimit) TargetRTT() int64 {
+	return l.targetRTT
+}
+
+// MarshalState returns a snapshot of the learned limit.
+func (l *LatencySLOLimit) MarshalState() ([]byte, error) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return json.Marshal(LimitState{
+		Algorithm:      "latency_slo",
+		EstimatedLimit: l.estimatedLimit,
+	})
+}
+
+// RestoreState restores the learned limit from a snapshot returned by MarshalState.
+func (l *LatencySLOLimit) RestoreState(data []byte) error {
+	state, err := unmarshalLimitState("latency_slo", data)
+	if err != nil {
+		return err
+	}
+	l.mu.Lock()
+	l.estimatedLimit = clampLimit(state.EstimatedLimit, l.minLimit, l.maxLimit)
+	newLimit := int(l.estimatedLimit)
+	l.mu.Unlock()
+
+	l.listeners.notify(newLimit)
+	return nil
+}
+
+func (l *LatencySLOLimit) String() string {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return fmt.Sprintf("LatencySLOLimit{limit=%d, percentileRTT=%d ms, targetRTT=%d ms}",
+		int(l.estimatedLimit), int64(l.percentileRTT.Get())/1e6, l.targetRTT/1e6)
+}
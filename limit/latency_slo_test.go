@@ -0,0 +1,119 @@
+package limit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLatencySLOLimit(t *testing.T) {
+	t.Parallel()
+
+	t.Run("Default", func(t2 *testing.T) {
+		t2.Parallel()
+		asrt := assert.New(t2)
+		l, err := NewDefaultLatencySLOLimit("test", 0.99, (time.Millisecond * 200).Nanoseconds(), nil)
+		asrt.NoError(err)
+		asrt.Equal(20, l.EstimatedLimit())
+		asrt.Equal((time.Millisecond * 200).Nanoseconds(), l.TargetRTT())
+		asrt.Equal("LatencySLOLimit{limit=20, percentileRTT=0 ms, targetRTT=200 ms}", l.String())
+	})
+
+	t.Run("InvalidArguments", func(t2 *testing.T) {
+		t2.Parallel()
+		asrt := assert.New(t2)
+		_, err := NewDefaultLatencySLOLimit("test", 0.99, 0, nil)
+		asrt.Error(err)
+		_, err = NewDefaultLatencySLOLimit("test", 1.5, 100, nil)
+		asrt.Error(err)
+		_, err = NewLatencySLOLimit("test", 10, 10, 20, 0.99, 100, 0.9, 0.1, nil, 1.0, nil)
+		asrt.Error(err)
+	})
+
+	t.Run("InitialLimitClamped", func(t2 *testing.T) {
+		t2.Parallel()
+		asrt := assert.New(t2)
+		l, err := NewLatencySLOLimit("test", 2, 100, 4, 0.99, 100, 0.9, 0.1, nil, 1.0, nil)
+		asrt.NoError(err)
+		asrt.Equal(4, l.EstimatedLimit())
+		l, err = NewLatencySLOLimit("test", 500, 100, 4, 0.99, 100, 0.9, 0.1, nil, 1.0, nil)
+		asrt.NoError(err)
+		asrt.Equal(100, l.EstimatedLimit())
+	})
+
+	t.Run("IncreaseUnderTarget", func(t2 *testing.T) {
+		t2.Parallel()
+		asrt := assert.New(t2)
+		l, _ := NewLatencySLOLimit("test", 10, 100, 4, 0.9, (time.Millisecond * 100).Nanoseconds(), 0.5, 0.1,
+			func(limit int) int { return 2 }, 1.0, nil)
+		listener := testNotifyListener{}
+		l.NotifyOnChange(listener.updater())
+
+		// app limited, nothing should change
+		l.OnSample(0, (time.Millisecond * 10).Nanoseconds(), 1, false)
+		asrt.Equal(10, l.EstimatedLimit())
+
+		l.OnSample(0, (time.Millisecond * 10).Nanoseconds(), 10, false)
+		asrt.Equal(12, l.EstimatedLimit())
+		asrt.Equal(12, listener.changes[0])
+		asrt.Equal((time.Millisecond * 10).Nanoseconds(), l.PercentileRTT())
+	})
+
+	t.Run("HoldWithinHeadroom", func(t2 *testing.T) {
+		t2.Parallel()
+		asrt := assert.New(t2)
+		l, _ := NewLatencySLOLimit("test", 10, 100, 4, 0.9, (time.Millisecond * 100).Nanoseconds(), 0.5, 0.1,
+			nil, 1.0, nil)
+		l.OnSample(0, (time.Millisecond * 95).Nanoseconds(), 10, false)
+		asrt.Equal(10, l.EstimatedLimit())
+	})
+
+	t.Run("DecreaseOverTarget", func(t2 *testing.T) {
+		t2.Parallel()
+		asrt := assert.New(t2)
+		l, _ := NewLatencySLOLimit("test", 40, 100, 4, 0.9, (time.Millisecond * 100).Nanoseconds(), 0.5, 0.1,
+			nil, 1.0, nil)
+		l.OnSample(0, (time.Millisecond * 150).Nanoseconds(), 1, false)
+		asrt.Equal(20, l.EstimatedLimit())
+
+		// drops decrease regardless of latency
+		l.OnSample(0, 0, 1, true)
+		asrt.Equal(10, l.EstimatedLimit())
+
+		// bounded by the min limit
+		for i := 0; i < 10; i++ {
+			l.OnSample(0, (time.Millisecond * 150).Nanoseconds(), 1, false)
+		}
+		asrt.Equal(4, l.EstimatedLimit())
+	})
+
+	t.Run("Smoothing", func(t2 *testing.T) {
+		t2.Parallel()
+		asrt := assert.New(t2)
+		l, _ := NewLatencySLOLimit("test", 40, 100, 4, 0.9, (time.Millisecond * 100).Nanoseconds(), 0.5, 0.1,
+			nil, 0.5, nil)
+		l.OnSample(0, (time.Millisecond * 150).Nanoseconds(), 40, false)
+		asrt.Equal(30, l.EstimatedLimit())
+
+		// invalid values default to 0.2
+		l, _ = NewLatencySLOLimit("test", 40, 100, 4, 0.9, (time.Millisecond * 100).Nanoseconds(), 0.5, 0.1,
+			nil, 0, nil)
+		l.OnSample(0, (time.Millisecond * 150).Nanoseconds(), 40, false)
+		asrt.Equal(36, l.EstimatedLimit())
+	})
+
+	t.Run("ConvergesBelowTarget", func(t2 *testing.T) {
+		t2.Parallel()
+		asrt := assert.New(t2)
+		target := (time.Millisecond * 50).Nanoseconds()
+		l, _ := NewDefaultLatencySLOLimit("test", 0.9, target, nil)
+		model := queueingModel{capacity: 100, baseRTT: time.Millisecond * 10}
+		history := runScenario(l, model, 500)
+		for _, v := range history[400:] {
+			// the limit where the model latency reaches the target
+			asrt.True(v <= 550, "expected limit to keep latency near target, got %d", v)
+			asrt.True(v >= 100, "expected limit to use available headroom, got %d", v)
+		}
+	})
+}
@@ -0,0 +1,375 @@
+// Package limittest provides a conformance suite for core.Limit implementations.  The suite drives the limit under
+// test with synthetic samples and verifies the behavior every limit is expected to have:
+//   - the limit stays within its configured bounds
+//   - the limit increases under low latency
+//   - the limit decreases under rising latency and under drops
+//   - listeners are notified of every change and no longer once cancelled
+//   - concurrent calls are safe, run the tests with -race
+//   - the size of a step does not jump with the limit, i.e. a lookup table falling back to a different function
+//
+// Usage:
+//
+//	func TestConformance(t *testing.T) {
+//		limittest.Run(t, func(initialLimit int, clock func() int64) (core.Limit, error) {
+//			return NewMyLimit("test", initialLimit, 1, 1000)
+//		}, limittest.Config{MinLimit: 1, MaxLimit: 1000})
+//	}
+package limittest
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/platinummonkey/go-concurrency-limits/core"
+)
+
+// continuityPivots are the limits around which the step continuity is checked, these are the boundaries of lookup
+// tables in limit/functions.
+var continuityPivots = []int{100, 1000, 10000}
+
+const (
+	// continuitySpan is how far, as a factor of the pivot, the limits on either side of a pivot start.
+	continuitySpan = 2
+	// continuityFactor is the maximum ratio between the growth of the limits on either side of a pivot, growth may
+	// scale at most linearly with the limit.
+	continuityFactor = 2 * continuitySpan * continuitySpan
+)
+
+// Factory creates a new instance of the limit under test.
+// @param initialLimit: The initial limit the limit must be created with.
+// @param clock: Source of the current time in nanoseconds, advancing with the samples, for time based limits.
+type Factory func(initialLimit int, clock func() int64) (core.Limit, error)
+
+// Config describes the limit under test.
+type Config struct {
+	// InitialLimit is the limit passed to the factory, defaults to 20.
+	InitialLimit int
+	// MinLimit is the lower bound the limit must respect, defaults to 1.
+	MinLimit int
+	// MaxLimit is the upper bound the limit must respect, defaults to 1000.
+	MaxLimit int
+	// RTT is the round trip time of samples under low latency, defaults to 10ms.
+	RTT time.Duration
+	// Samples is the number of samples fed in each phase, defaults to 1000.
+	Samples int
+	// Static is set for limits that do not adapt to samples, i.e. FixedLimit.  Only the bounds, listeners and
+	// concurrency are checked.
+	Static bool
+	// IgnoresLatency is set for loss based limits which do not decrease under rising latency, i.e. AIMDLimit.
+	IgnoresLatency bool
+	// IgnoresDrops is set for delay based limits which do not decrease on drops.
+	IgnoresDrops bool
+}
+
+func (c Config) withDefaults() Config {
+	if c.InitialLimit <= 0 {
+		c.InitialLimit = 20
+	}
+	if c.MinLimit <= 0 {
+		c.MinLimit = 1
+	}
+	if c.MaxLimit <= 0 {
+		c.MaxLimit = 1000
+	}
+	if c.RTT <= 0 {
+		c.RTT = 10 * time.Millisecond
+	}
+	if c.Samples <= 0 {
+		c.Samples = 1000
+	}
+	return c
+}
+
+// Run runs the conformance suite against the limits created by the factory, each check as a subtest on a new limit.
+func Run(t *testing.T, factory Factory, config Config) {
+	config = config.withDefaults()
+	t.Run("WithinBounds", func(t *testing.T) {
+		testWithinBounds(t, factory, config)
+	})
+	t.Run("IncreasesUnderLowLatency", func(t *testing.T) {
+		if config.Static {
+			t.Skip("static limit")
+		}
+		testIncreasesUnderLowLatency(t, factory, config)
+	})
+	t.Run("DecreasesUnderRisingLatency", func(t *testing.T) {
+		if config.Static || config.IgnoresLatency {
+			t.Skip("limit ignores latency")
+		}
+		testDecreasesUnderRisingLatency(t, factory, config)
+	})
+	t.Run("DecreasesOnDrops", func(t *testing.T) {
+		if config.Static || config.IgnoresDrops {
+			t.Skip("limit ignores drops")
+		}
+		testDecreasesOnDrops(t, factory, config)
+	})
+	t.Run("NotifiesListeners", func(t *testing.T) {
+		testNotifiesListeners(t, factory, config)
+	})
+	t.Run("ConcurrentSamples", func(t *testing.T) {
+		testConcurrentSamples(t, factory, config)
+	})
+	t.Run("StepContinuity", func(t *testing.T) {
+		if config.Static {
+			t.Skip("static limit")
+		}
+		testStepContinuity(t, factory, config)
+	})
+}
+
+// driver feeds samples to the limit under test, advancing the clock by the RTT of every sample.
+type driver struct {
+	t      *testing.T
+	limit  core.Limit
+	config Config
+	now    int64
+	// onSample is called with the limit after every sample.
+	onSample func(limit int)
+}
+
+func newDriver(t *testing.T, factory Factory, config Config, initialLimit int) *driver {
+	d := &driver{
+		t:      t,
+		config: config,
+		// start away from zero, limits may treat a zero time as unset
+		now: time.Hour.Nanoseconds(),
+	}
+	l, err := factory(initialLimit, d.clock)
+	if err != nil {
+		t.Fatalf("failed to create limit: %v", err)
+	}
+	if l == nil {
+		t.Fatalf("factory returned a nil limit")
+	}
+	d.limit = l
+	d.checkBounds("initial")
+	return d
+}
+
+func (d *driver) clock() int64 {
+	return atomic.LoadInt64(&d.now)
+}
+
+// sample feeds a sample at full utilization, in flight is the current limit.
+func (d *driver) sample(rtt time.Duration, didDrop bool) int {
+	end := atomic.AddInt64(&d.now, rtt.Nanoseconds())
+	d.limit.OnSample(end-rtt.Nanoseconds(), rtt.Nanoseconds(), d.limit.EstimatedLimit(), didDrop)
+	newLimit := d.limit.EstimatedLimit()
+	if d.onSample != nil {
+		d.onSample(newLimit)
+	}
+	return newLimit
+}
+
+// lowLatency feeds samples at the RTT.
+func (d *driver) lowLatency(samples int) int {
+	limit := d.limit.EstimatedLimit()
+	for i := 0; i < samples; i++ {
+		limit = d.sample(d.config.RTT, false)
+	}
+	return limit
+}
+
+// risingLatency feeds samples with the RTT rising linearly up to 4x.
+func (d *driver) risingLatency(samples int) int {
+	limit := d.limit.EstimatedLimit()
+	for i := 0; i < samples; i++ {
+		factor := 1 + 3*float64(i+1)/float64(samples)
+		limit = d.sample(time.Duration(float64(d.config.RTT)*factor), false)
+	}
+	return limit
+}
+
+// drops feeds dropped samples at the RTT.
+func (d *driver) drops(samples int) int {
+	limit := d.limit.EstimatedLimit()
+	for i := 0; i < samples; i++ {
+		limit = d.sample(d.config.RTT, true)
+	}
+	return limit
+}
+
+// checkBounds fails the test if the limit is outside of the configured bounds.
+func (d *driver) checkBounds(phase string) bool {
+	limit := d.limit.EstimatedLimit()
+	if limit < d.config.MinLimit || limit > d.config.MaxLimit {
+		d.t.Errorf("%s: limit %d outside of bounds [%d, %d]", phase, limit, d.config.MinLimit, d.config.MaxLimit)
+		return false
+	}
+	return true
+}
+
+func testWithinBounds(t *testing.T, factory Factory, config Config) {
+	d := newDriver(t, factory, config, config.InitialLimit)
+	phase := ""
+	failed := false
+	d.onSample = func(limit int) {
+		if !failed && !d.checkBounds(phase) {
+			// report the first violation only
+			failed = true
+		}
+	}
+	phase = "low latency"
+	d.lowLatency(config.Samples)
+	phase = "drops"
+	d.drops(config.Samples)
+	phase = "recovery"
+	d.lowLatency(config.Samples)
+	phase = "rising latency"
+	d.risingLatency(config.Samples)
+	phase = "latency spike"
+	for i := 0; i < config.Samples; i++ {
+		d.sample(100*config.RTT, i%2 == 0)
+	}
+	phase = "recovery after spike"
+	d.lowLatency(config.Samples)
+}
+
+func testIncreasesUnderLowLatency(t *testing.T, factory Factory, config Config) {
+	d := newDriver(t, factory, config, config.InitialLimit)
+	if limit := d.lowLatency(config.Samples); limit <= config.InitialLimit {
+		t.Errorf("limit %d did not increase from %d under low latency", limit, config.InitialLimit)
+	}
+}
+
+func testDecreasesUnderRisingLatency(t *testing.T, factory Factory, config Config) {
+	d := newDriver(t, factory, config, config.InitialLimit)
+	before := d.lowLatency(config.Samples)
+	if limit := d.risingLatency(config.Samples); limit >= before {
+		t.Errorf("limit %d did not decrease from %d under rising latency", limit, before)
+	}
+}
+
+func testDecreasesOnDrops(t *testing.T, factory Factory, config Config) {
+	d := newDriver(t, factory, config, config.InitialLimit)
+	before := d.lowLatency(config.Samples)
+	if limit := d.drops(config.Samples); limit >= before {
+		t.Errorf("limit %d did not decrease from %d on drops", limit, before)
+	}
+}
+
+func testNotifiesListeners(t *testing.T, factory Factory, config Config) {
+	d := newDriver(t, factory, config, config.InitialLimit)
+	var mu sync.Mutex
+	var notified []int
+	subscription := d.limit.NotifyOnChange(func(limit int) {
+		mu.Lock()
+		notified = append(notified, limit)
+		mu.Unlock()
+	})
+	if subscription == nil {
+		t.Fatalf("NotifyOnChange returned a nil subscription")
+	}
+
+	last := d.limit.EstimatedLimit()
+	failed := false
+	d.onSample = func(limit int) {
+		if limit == last || failed {
+			return
+		}
+		last = limit
+		mu.Lock()
+		defer mu.Unlock()
+		if len(notified) == 0 || notified[len(notified)-1] != limit {
+			t.Errorf("limit changed to %d without notifying listeners, notified %v", limit, tail(notified))
+			failed = true
+		}
+	}
+	d.lowLatency(config.Samples)
+	d.drops(config.Samples)
+	d.risingLatency(config.Samples)
+
+	subscription.Cancel()
+	// cancelling again must be safe
+	subscription.Cancel()
+	mu.Lock()
+	count := len(notified)
+	mu.Unlock()
+	d.onSample = nil
+	d.lowLatency(config.Samples)
+	d.drops(config.Samples)
+	mu.Lock()
+	defer mu.Unlock()
+	if len(notified) != count {
+		t.Errorf("cancelled listener notified of %v", notified[count:])
+	}
+}
+
+// tail returns the last few values, for error messages.
+func tail(values []int) []int {
+	if len(values) > 5 {
+		return values[len(values)-5:]
+	}
+	return values
+}
+
+func testConcurrentSamples(t *testing.T, factory Factory, config Config) {
+	const workers = 8
+	d := newDriver(t, factory, config, config.InitialLimit)
+	samples := config.Samples / workers
+	if samples < 10 {
+		samples = 10
+	}
+
+	var wg sync.WaitGroup
+	done := make(chan struct{})
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func(worker int) {
+			defer wg.Done()
+			for j := 0; j < samples; j++ {
+				d.sample(d.config.RTT*time.Duration(1+j%3), (worker+j)%5 == 0)
+			}
+		}(i)
+	}
+	readers := make(chan struct{})
+	go func() {
+		defer close(readers)
+		for {
+			select {
+			case <-done:
+				return
+			default:
+			}
+			subscription := d.limit.NotifyOnChange(func(limit int) {})
+			d.limit.EstimatedLimit()
+			subscription.Cancel()
+		}
+	}()
+	wg.Wait()
+	close(done)
+	<-readers
+	d.checkBounds("concurrent samples")
+}
+
+func testStepContinuity(t *testing.T, factory Factory, config Config) {
+	const samples = 20
+	tested := false
+	for _, pivot := range continuityPivots {
+		below, above := pivot/continuitySpan, pivot*continuitySpan
+		// leave room to grow without hitting the bounds
+		if below < config.MinLimit || above*2 > config.MaxLimit {
+			continue
+		}
+		tested = true
+		belowGrowth := newDriver(t, factory, config, below).lowLatency(samples) - below
+		aboveGrowth := newDriver(t, factory, config, above).lowLatency(samples) - above
+		smaller, larger := belowGrowth, aboveGrowth
+		if smaller > larger {
+			smaller, larger = larger, smaller
+		}
+		if smaller < 1 {
+			smaller = 1
+		}
+		if larger > continuityFactor*smaller {
+			t.Errorf("growth jumps around a limit of %d, grew by %d from %d and by %d from %d",
+				pivot, belowGrowth, below, aboveGrowth, above)
+		}
+	}
+	if !tested {
+		t.Skipf("MaxLimit %d too small to check around %v", config.MaxLimit, continuityPivots)
+	}
+}
@@ -0,0 +1,134 @@
+package limit
+
+import (
+	"sync"
+
+	"github.com/platinummonkey/go-concurrency-limits/core"
+)
+
+// listenerRegistry tracks the listeners registered via NotifyOnChange.  Limits update their state under their own
+// lock and notify the registry once the lock is released, so a slow or re-entrant listener can't stall or deadlock
+// sampling.  As a consequence concurrent updates may be delivered out of order, listeners needing the latest value
+// should read EstimatedLimit.
+type listenerRegistry struct {
+	nextID  uint64
+	entries []listenerEntry
+	mu      sync.RWMutex
+}
+
+type listenerEntry struct {
+	id       uint64
+	listener core.LimitChangeListener
+}
+
+// add registers the listener and returns the subscription to cancel it.
+func (r *listenerRegistry) add(listener core.LimitChangeListener) core.LimitChangeSubscription {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.nextID++
+	id := r.nextID
+	// copy on write so notify can iterate a snapshot without holding the lock
+	entries := make([]listenerEntry, len(r.entries), len(r.entries)+1)
+	copy(entries, r.entries)
+	r.entries = append(entries, listenerEntry{id: id, listener: listener})
+	return &listenerSubscription{registry: r, id: id}
+}
+
+func (r *listenerRegistry) remove(id uint64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	entries := make([]listenerEntry, 0, len(r.entries))
+	for _, entry := range r.entries {
+		if entry.id != id {
+			entries = append(entries, entry)
+		}
+	}
+	r.entries = entries
+}
+
+// notify calls the registered listeners with the new limit.
+// note: must not be called while holding the limit's lock.
+func (r *listenerRegistry) notify(newLimit int) {
+	r.mu.RLock()
+	entries := r.entries
+	r.mu.RUnlock()
+	for _, entry := range entries {
+		entry.listener(newLimit)
+	}
+}
+
+type listenerSubscription struct {
+	registry *listenerRegistry
+	id       uint64
+	once     sync.Once
+}
+
+// Cancel unregisters the listener.
+func (s *listenerSubscription) Cancel() {
+	s.once.Do(func() {
+		s.registry.remove(s.id)
+	})
+}
+
+// noopSubscription is returned by limits that never change.
+type noopSubscription struct{}
+
+// Cancel is a noop.
+func (noopSubscription) Cancel() {}
+
+// LimitChangeChannel delivers limit changes over a channel rather than a callback.  Sends never block the limit, when
+// the consumer falls behind the oldest buffered value is discarded in favor of the newest.
+type LimitChangeChannel struct {
+	ch           chan int
+	subscription core.LimitChangeSubscription
+	closed       bool
+	mu           sync.Mutex
+}
+
+// NewLimitChangeChannel will subscribe to the limit's changes, buffering up to buffer values, defaults to 1.
+func NewLimitChangeChannel(limit core.Limit, buffer int) *LimitChangeChannel {
+	if buffer < 1 {
+		buffer = 1
+	}
+	c := &LimitChangeChannel{
+		ch: make(chan int, buffer),
+	}
+	c.subscription = limit.NotifyOnChange(c.send)
+	return c
+}
+
+func (c *LimitChangeChannel) send(newLimit int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return
+	}
+	for {
+		select {
+		case c.ch <- newLimit:
+			return
+		default:
+		}
+		// full, drop the oldest value
+		select {
+		case <-c.ch:
+		default:
+		}
+	}
+}
+
+// C returns the channel the limit changes are delivered on, it's closed on Cancel.
+func (c *LimitChangeChannel) C() <-chan int {
+	return c.ch
+}
+
+// Cancel unregisters from the limit and closes the channel.
+func (c *LimitChangeChannel) Cancel() {
+	c.subscription.Cancel()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.closed {
+		c.closed = true
+		close(c.ch)
+	}
+}
@@ -0,0 +1,97 @@
+package limit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/platinummonkey/go-concurrency-limits/core"
+)
+
+func TestLimitChangeSubscription(t *testing.T) {
+	t.Parallel()
+
+	t.Run("Cancel", func(t2 *testing.T) {
+		t2.Parallel()
+		asrt := assert.New(t2)
+		l := NewSettableLimit("test", 10)
+		first := testNotifyListener{}
+		second := testNotifyListener{}
+		subscription := l.NotifyOnChange(first.updater())
+		l.NotifyOnChange(second.updater())
+
+		l.SetLimit(5)
+		subscription.Cancel()
+		// cancelling twice is a noop
+		subscription.Cancel()
+		l.SetLimit(6)
+		asrt.Equal([]int{5}, first.changes)
+		asrt.Equal([]int{5, 6}, second.changes)
+	})
+
+	t.Run("ReentrantListener", func(t2 *testing.T) {
+		t2.Parallel()
+		asrt := assert.New(t2)
+		limits := []core.Limit{
+			NewDefaultAIMLimit("test"),
+			NewDefaultGradient2Limit("test", nil),
+			NewDefaultVegasLimit("test", nil),
+			NewDefaultBBRLimit("test", nil),
+			NewDefaultErrorRateLimit("test", nil),
+		}
+		for _, l := range limits {
+			l := l
+			var observed []int
+			var subscription core.LimitChangeSubscription
+			subscription = l.NotifyOnChange(func(limit int) {
+				// reading back and unsubscribing from within the listener must not deadlock
+				observed = append(observed, l.EstimatedLimit())
+				subscription.Cancel()
+			})
+			done := make(chan struct{})
+			go func() {
+				defer close(done)
+				for i := 0; i < 100; i++ {
+					l.OnSample(0, 10e6, 100, i%10 == 0)
+				}
+			}()
+			select {
+			case <-done:
+			case <-time.After(5 * time.Second):
+				t2.Fatalf("deadlock notifying %v", l)
+			}
+			asrt.Len(observed, 1, "%v", l)
+		}
+	})
+
+	t.Run("Fixed", func(t2 *testing.T) {
+		t2.Parallel()
+		l := NewFixedLimit("test", 10)
+		l.NotifyOnChange(func(limit int) {}).Cancel()
+	})
+}
+
+func TestLimitChangeChannel(t *testing.T) {
+	t.Parallel()
+	asrt := assert.New(t)
+	l := NewSettableLimit("test", 10)
+	c := NewLimitChangeChannel(l, 2)
+
+	l.SetLimit(1)
+	l.SetLimit(2)
+	// the consumer fell behind, the oldest value is dropped without blocking the limit
+	l.SetLimit(3)
+	asrt.Equal(2, <-c.C())
+	asrt.Equal(3, <-c.C())
+
+	l.SetLimit(4)
+	asrt.Equal(4, <-c.C())
+
+	c.Cancel()
+	c.Cancel()
+	// no longer delivered, and sending on the closed channel doesn't panic
+	l.SetLimit(5)
+	_, ok := <-c.C()
+	asrt.False(ok)
+}
@@ -0,0 +1,286 @@
+package limit
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sync"
+
+	"github.com/platinummonkey/go-concurrency-limits/core"
+	"github.com/platinummonkey/go-concurrency-limits/measurements"
+)
+
+// PIDProcessVariable selects the metric a PIDLimit controls.
+type PIDProcessVariable int
+
+const (
+	// PIDQueueingDelay controls the queueing delay, measured as rtt - rttNoLoad in nanoseconds.
+	PIDQueueingDelay PIDProcessVariable = iota
+	// PIDDropRate controls the exponentially smoothed fraction of dropped samples.
+	PIDDropRate
+)
+
+func (v PIDProcessVariable) String() string {
+	switch v {
+	case PIDQueueingDelay:
+		return "QueueingDelay"
+	case PIDDropRate:
+		return "DropRate"
+	}
+	return "Unknown"
+}
+
+// PIDLimit implements a concurrency limit using a classic PID controller.  The process variable is either the
+// queueing delay or the drop rate and the controller drives it towards the configured setpoint.
+//
+// The error is normalized by the setpoint so the gains are independent of the unit of the process variable
+//
+//	error = (setpoint - processVariable) / setpoint
+//	integral = integral + ki * error
+//	newLimit = initialLimit + kp * error + integral + kd * (error - previousError)
+//
+// The output is clamped to [minLimit, maxLimit].  To avoid integral windup the integral is not accumulated while the
+// output is saturated in the direction of the error, nor while the limit is app limited and would otherwise grow.
+type PIDLimit struct {
+	estimatedLimit  float64
+	bias            float64
+	minLimit        int
+	maxLimit        int
+	processVariable PIDProcessVariable
+	setpoint        float64
+	kp              float64
+	ki              float64
+	kd              float64
+
+	integral      float64
+	previousError float64
+	hasPrevious   bool
+
+	rttNoLoad core.MeasurementInterface
+	dropRate  core.MeasurementInterface
+
+	listeners listenerRegistry
+	logger    Logger
+	mu        sync.RWMutex
+}
+
+// NewDefaultPIDLimit will create a new PIDLimit controlling the queueing delay towards the given target in
+// nanoseconds.
+func NewDefaultPIDLimit(
+	name string,
+	targetQueueingDelay int64,
+	logger Logger,
+) (*PIDLimit, error) {
+	return NewPIDLimit(
+		name,
+		20,
+		4,
+		1000,
+		PIDQueueingDelay,
+		float64(targetQueueingDelay),
+		10,
+		1,
+		0,
+		logger,
+	)
+}
+
+// NewPIDLimit will create a new PIDLimit.
+// @param initialLimit: Initial limit used by the limiter, this is also the bias of the controller output.
+// @param minLimit: Minimum concurrency limit allowed.
+// @param maxConcurrency: Maximum allowable concurrency.  Any estimated concurrency will be capped.
+// @param processVariable: The metric to control.
+// @param setpoint: The target for the process variable, nanoseconds for PIDQueueingDelay or a fraction for PIDDropRate.
+// @param kp: Proportional gain.
+// @param ki: Integral gain.
+// @param kd: Derivative gain.
+func NewPIDLimit(
+	name string,
+	initialLimit int,
+	minLimit int,
+	maxConcurrency int,
+	processVariable PIDProcessVariable,
+	setpoint float64,
+	kp float64,
+	ki float64,
+	kd float64,
+	logger Logger,
+) (*PIDLimit, error) {
+	if setpoint <= 0 {
+		return nil, fmt.Errorf("setpoint must be > 0")
+	}
+	if processVariable != PIDQueueingDelay && processVariable != PIDDropRate {
+		return nil, fmt.Errorf("unknown process variable %d", processVariable)
+	}
+	if kp < 0 || ki < 0 || kd < 0 {
+		return nil, fmt.Errorf("gains must be >= 0")
+	}
+	if minLimit <= 0 {
+		minLimit = 4
+	}
+	if maxConcurrency <= 0 {
+		maxConcurrency = 1000
+	}
+	if minLimit > maxConcurrency {
+		return nil, fmt.Errorf("minLimit must be <= maxConcurrency")
+	}
+	if initialLimit <= 0 {
+		initialLimit = 20
+	}
+	if logger == nil {
+		logger = NoopLimitLogger{}
+	}
+
+	dropRate, err := measurements.NewSimpleExponentialMovingAverage(0.1)
+	if err != nil {
+		return nil, err
+	}
+
+	bias := math.Max(float64(minLimit), math.Min(float64(maxConcurrency), float64(initialLimit)))
+	l := &PIDLimit{
+		estimatedLimit:  bias,
+		bias:            bias,
+		minLimit:        minLimit,
+		maxLimit:        maxConcurrency,
+		processVariable: processVariable,
+		setpoint:        setpoint,
+		kp:              kp,
+		ki:              ki,
+		kd:              kd,
+		rttNoLoad:       &measurements.MinimumMeasurement{},
+		dropRate:        dropRate,
+		logger:          logger,
+	}
+	return l, nil
+}
+
+// EstimatedLimit returns the current estimated limit.
+func (l *PIDLimit) EstimatedLimit() int {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return int(l.estimatedLimit)
+}
+
+// NotifyOnChange will register a callback to receive notification whenever the limit is updated to a new value.
+func (l *PIDLimit) NotifyOnChange(consumer core.LimitChangeListener) core.LimitChangeSubscription {
+	return l.listeners.add(consumer)
+}
+
+// OnSample the concurrency limit using a new rtt sample.
+func (l *PIDLimit) OnSample(startTime int64, rtt int64, inFlight int, didDrop bool) {
+	if newLimit, ok := l.onSample(startTime, rtt, inFlight, didDrop); ok {
+		l.listeners.notify(newLimit)
+	}
+}
+
+// onSample updates the estimated limit under the lock, returning the limit to notify listeners of.
+func (l *PIDLimit) onSample(startTime int64, rtt int64, inFlight int, didDrop bool) (int, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	pv := l.measure(rtt, didDrop)
+	e := (l.setpoint - pv) / l.setpoint
+
+	derivative := 0.0
+	if l.hasPrevious {
+		derivative = l.kd * (e - l.previousError)
+	}
+	l.previousError = e
+	l.hasPrevious = true
+
+	// Don't grow the limit if we are app limited
+	appLimited := e > 0 && float64(inFlight) < l.estimatedLimit/2
+
+	integral := l.integral + l.ki*e
+	output := l.bias + l.kp*e + integral + derivative
+	saturated := (output > float64(l.maxLimit) && e > 0) || (output < float64(l.minLimit) && e < 0)
+	if !saturated && !appLimited {
+		l.integral = integral
+	}
+	if appLimited {
+		return 0, false
+	}
+
+	output = l.bias + l.kp*e + l.integral + derivative
+	newLimit := math.Max(float64(l.minLimit), math.Min(float64(l.maxLimit), output))
+
+	if int(newLimit) != int(l.estimatedLimit) && l.logger.IsDebugEnabled() {
+		l.logger.Debugf("new limit=%0.2f, %s=%0.4f, error=%0.4f, integral=%0.4f",
+			newLimit, l.processVariable, pv, e, l.integral)
+	}
+
+	l.estimatedLimit = newLimit
+	return int(l.estimatedLimit), true
+}
+
+// measure returns the current value of the process variable for the sample.
+func (l *PIDLimit) measure(rtt int64, didDrop bool) float64 {
+	if l.processVariable == PIDDropRate {
+		sample := 0.0
+		if didDrop {
+			sample = 1.0
+		}
+		value, _ := l.dropRate.Add(sample)
+		return value
+	}
+
+	if didDrop {
+		// dropped samples have no meaningful rtt, treat them as significantly over the target
+		return 2 * l.setpoint
+	}
+	rttNoLoad, _ := l.rttNoLoad.Add(float64(rtt))
+	return math.Max(0, float64(rtt)-rttNoLoad)
+}
+
+// RTTNoLoad returns the current RTT No Load value.
+func (l *PIDLimit) RTTNoLoad() int64 {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return int64(l.rttNoLoad.Get())
+}
+
+// Integral returns the current accumulated integral term.
+func (l *PIDLimit) Integral() float64 {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return l.integral
+}
+
+// MarshalState returns a snapshot of the learned limit and RTT No Load.
+func (l *PIDLimit) MarshalState() ([]byte, error) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return json.Marshal(LimitState{
+		Algorithm:      "pid",
+		EstimatedLimit: l.estimatedLimit,
+		RTTNoLoad:      int64(l.rttNoLoad.Get()),
+	})
+}
+
+// RestoreState restores the learned limit and RTT No Load from a snapshot returned by MarshalState.  The restored limit
+// becomes the bias of the controller output and the controller terms are reset.
+func (l *PIDLimit) RestoreState(data []byte) error {
+	state, err := unmarshalLimitState("pid", data)
+	if err != nil {
+		return err
+	}
+	l.mu.Lock()
+	l.estimatedLimit = clampLimit(state.EstimatedLimit, l.minLimit, l.maxLimit)
+	l.bias = l.estimatedLimit
+	l.integral = 0
+	l.previousError = 0
+	l.hasPrevious = false
+	restoreMeasurement(l.rttNoLoad, float64(state.RTTNoLoad))
+	newLimit := int(l.estimatedLimit)
+	l.mu.Unlock()
+
+	l.listeners.notify(newLimit)
+	return nil
+}
+
+func (l *PIDLimit) String() string {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return fmt.Sprintf("PIDLimit{limit=%d, processVariable=%s, setpoint=%0.4f, kp=%0.4f, ki=%0.4f, kd=%0.4f}",
+		int(l.estimatedLimit), l.processVariable, l.setpoint, l.kp, l.ki, l.kd)
+}
@@ -0,0 +1,116 @@
+package limit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPIDLimit(t *testing.T) {
+	t.Parallel()
+
+	t.Run("Default", func(t2 *testing.T) {
+		t2.Parallel()
+		asrt := assert.New(t2)
+		l, err := NewDefaultPIDLimit("test", (time.Millisecond * 5).Nanoseconds(), nil)
+		asrt.NoError(err)
+		asrt.Equal(20, l.EstimatedLimit())
+		asrt.Equal(
+			"PIDLimit{limit=20, processVariable=QueueingDelay, setpoint=5000000.0000, kp=10.0000, ki=1.0000, kd=0.0000}",
+			l.String(),
+		)
+	})
+
+	t.Run("InvalidArguments", func(t2 *testing.T) {
+		t2.Parallel()
+		asrt := assert.New(t2)
+		_, err := NewDefaultPIDLimit("test", 0, nil)
+		asrt.Error(err)
+		_, err = NewPIDLimit("test", 10, 4, 100, PIDProcessVariable(5), 1, 1, 1, 1, nil)
+		asrt.Error(err)
+		_, err = NewPIDLimit("test", 10, 4, 100, PIDDropRate, 0.1, -1, 1, 1, nil)
+		asrt.Error(err)
+		_, err = NewPIDLimit("test", 10, 40, 10, PIDDropRate, 0.1, 1, 1, 1, nil)
+		asrt.Error(err)
+	})
+
+	t.Run("ProportionalResponse", func(t2 *testing.T) {
+		t2.Parallel()
+		asrt := assert.New(t2)
+		l, _ := NewPIDLimit("test", 20, 4, 100, PIDQueueingDelay, float64(time.Millisecond*10), 10, 0, 0, nil)
+		listener := testNotifyListener{}
+		l.NotifyOnChange(listener.updater())
+
+		// no queueing, full positive error
+		l.OnSample(0, (time.Millisecond * 10).Nanoseconds(), 20, false)
+		asrt.Equal(30, l.EstimatedLimit())
+		asrt.Equal(30, listener.changes[0])
+		asrt.Equal((time.Millisecond * 10).Nanoseconds(), l.RTTNoLoad())
+
+		// queueing delay at setpoint, no error
+		l.OnSample(0, (time.Millisecond * 20).Nanoseconds(), 20, false)
+		asrt.Equal(20, l.EstimatedLimit())
+
+		// queueing delay at twice the setpoint
+		l.OnSample(0, (time.Millisecond * 30).Nanoseconds(), 20, false)
+		asrt.Equal(10, l.EstimatedLimit())
+	})
+
+	t.Run("AppLimited", func(t2 *testing.T) {
+		t2.Parallel()
+		asrt := assert.New(t2)
+		l, _ := NewPIDLimit("test", 20, 4, 100, PIDQueueingDelay, float64(time.Millisecond*10), 10, 1, 0, nil)
+		for i := 0; i < 10; i++ {
+			l.OnSample(0, (time.Millisecond * 10).Nanoseconds(), 1, false)
+		}
+		asrt.Equal(20, l.EstimatedLimit())
+		asrt.Equal(0.0, l.Integral())
+	})
+
+	t.Run("AntiWindup", func(t2 *testing.T) {
+		t2.Parallel()
+		asrt := assert.New(t2)
+		l, _ := NewPIDLimit("test", 20, 4, 30, PIDQueueingDelay, float64(time.Millisecond*10), 0, 1, 0, nil)
+		for i := 0; i < 100; i++ {
+			l.OnSample(0, (time.Millisecond * 10).Nanoseconds(), 30, false)
+		}
+		asrt.Equal(30, l.EstimatedLimit())
+		// the integral stops accumulating once the output saturates
+		asrt.InDelta(10.0, l.Integral(), 0.001)
+
+		// recovers immediately once the error reverses
+		l.OnSample(0, (time.Millisecond * 30).Nanoseconds(), 30, false)
+		asrt.Equal(29, l.EstimatedLimit())
+	})
+
+	t.Run("DropRate", func(t2 *testing.T) {
+		t2.Parallel()
+		asrt := assert.New(t2)
+		l, _ := NewPIDLimit("test", 50, 4, 100, PIDDropRate, 0.1, 5, 1, 0, nil)
+		for i := 0; i < 20; i++ {
+			l.OnSample(0, (time.Millisecond * 10).Nanoseconds(), 100, true)
+		}
+		asrt.True(l.EstimatedLimit() < 50)
+	})
+
+	t.Run("StepResponse", func(t2 *testing.T) {
+		t2.Parallel()
+		asrt := assert.New(t2)
+		target := time.Millisecond * 5
+		l, _ := NewPIDLimit("test", 20, 4, 1000, PIDQueueingDelay, float64(target), 10, 2, 5, nil)
+		model := queueingModel{capacity: 100, baseRTT: time.Millisecond * 10}
+
+		// the queueing delay reaches the target at 1.5x the capacity
+		history := runScenario(l, model, 300)
+		asrt.InDelta(150, averageInt(history[200:]), 15)
+
+		// step down in capacity
+		model.capacity = 50
+		history = runScenario(l, model, 300)
+		asrt.InDelta(75, averageInt(history[200:]), 10)
+		for _, v := range history[200:] {
+			asrt.True(v >= 60 && v <= 90, "expected limit to settle, got %d", v)
+		}
+	})
+}
@@ -0,0 +1,223 @@
+package limit
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/platinummonkey/go-concurrency-limits/core"
+)
+
+// Listener outcomes recorded by a recording limiter in SampleRecord.Outcome.
+const (
+	// OutcomeSuccess is recorded for Listener.OnSuccess.
+	OutcomeSuccess = "success"
+	// OutcomeIgnore is recorded for Listener.OnIgnore.
+	OutcomeIgnore = "ignore"
+	// OutcomeDropped is recorded for Listener.OnDropped.
+	OutcomeDropped = "dropped"
+	// OutcomeRejected is recorded when the limiter rejected the request.
+	OutcomeRejected = "rejected"
+)
+
+// SampleRecord is a single OnSample call captured by RecordingLimit, or a single request outcome captured by a
+// recording limiter, encoded as one compact JSON object per line.
+type SampleRecord struct {
+	// Timestamp is when the sample was recorded, in epoch nanoseconds.
+	Timestamp int64 `json:"t"`
+	// StartTime is the start time passed to OnSample, in epoch nanoseconds.
+	StartTime int64 `json:"s,omitempty"`
+	// RTT is the round trip time of the sample, in nanoseconds.
+	RTT int64 `json:"r"`
+	// InFlight is the in flight count observed during the sample.
+	InFlight int `json:"i"`
+	// DidDrop is true if the request was dropped rather than successful.
+	DidDrop bool `json:"d,omitempty"`
+	// Limit is the recorded limit's estimate after the sample.
+	Limit int `json:"l"`
+	// Outcome is the listener outcome of a request, samples recorded by RecordingLimit leave it empty.
+	Outcome string `json:"o,omitempty"`
+}
+
+// SampleRecordWriter writes records as JSON lines, it is safe for concurrent use so the samples of a RecordingLimit
+// and the request outcomes of its limiter can be interleaved into a single capture.
+type SampleRecordWriter struct {
+	encoder *json.Encoder
+	err     error
+	mu      sync.Mutex
+}
+
+// NewSampleRecordWriter will create a new SampleRecordWriter writing to w.
+func NewSampleRecordWriter(w io.Writer) *SampleRecordWriter {
+	return &SampleRecordWriter{
+		encoder: json.NewEncoder(w),
+	}
+}
+
+// WriteRecord writes the record.  Writing stops at the first error, which is returned by every subsequent call.
+func (w *SampleRecordWriter) WriteRecord(record SampleRecord) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.err == nil {
+		w.err = w.encoder.Encode(record)
+	}
+	return w.err
+}
+
+// Err returns the first error writing the records, if any.
+func (w *SampleRecordWriter) Err() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.err
+}
+
+// RecordingLimit wraps a delegate Limit and writes every sample, together with the resulting limit, to a writer as
+// JSON lines.  Captures can be replayed through another algorithm or configuration with ReplaySamples, for example
+// using the limit-replay command, to evaluate tuning changes against real traffic.
+type RecordingLimit struct {
+	delegate core.Limit
+	writer   *SampleRecordWriter
+	clock    Clock
+}
+
+// NewRecordingLimit will create a new RecordingLimit writing to w.  Writes are not buffered, wrap w in a
+// bufio.Writer for high throughput and flush it once done.
+// @param delegate: The limit to record.
+// @param w: Destination of the records.
+// @param clock: Source of the record timestamps, defaults to SystemClock.
+func NewRecordingLimit(
+	name string,
+	delegate core.Limit,
+	w io.Writer,
+	clock Clock,
+) (*RecordingLimit, error) {
+	if delegate == nil {
+		return nil, fmt.Errorf("delegate must be specified")
+	}
+	if w == nil {
+		return nil, fmt.Errorf("writer must be specified")
+	}
+	if clock == nil {
+		clock = SystemClock
+	}
+	return &RecordingLimit{
+		delegate: delegate,
+		writer:   NewSampleRecordWriter(w),
+		clock:    clock,
+	}, nil
+}
+
+// EstimatedLimit returns the delegate's estimated limit.
+func (l *RecordingLimit) EstimatedLimit() int {
+	return l.delegate.EstimatedLimit()
+}
+
+// NotifyOnChange will register a callback to receive notification whenever the limit is updated to a new value.
+func (l *RecordingLimit) NotifyOnChange(consumer core.LimitChangeListener) core.LimitChangeSubscription {
+	return l.delegate.NotifyOnChange(consumer)
+}
+
+// OnSample will delegate the sample and record it.  Recording stops at the first write error, see Err.
+func (l *RecordingLimit) OnSample(startTime int64, rtt int64, inFlight int, didDrop bool) {
+	l.delegate.OnSample(startTime, rtt, inFlight, didDrop)
+	record := SampleRecord{
+		Timestamp: l.clock(),
+		StartTime: startTime,
+		RTT:       rtt,
+		InFlight:  inFlight,
+		DidDrop:   didDrop,
+		Limit:     l.delegate.EstimatedLimit(),
+	}
+	l.writer.WriteRecord(record)
+}
+
+// MarshalState returns a snapshot of the delegate's learned state, the delegate must be a StatefulLimit.
+func (l *RecordingLimit) MarshalState() ([]byte, error) {
+	return marshalDelegateState(l.delegate)
+}
+
+// RestoreState restores the delegate's learned state from a snapshot returned by MarshalState.
+func (l *RecordingLimit) RestoreState(data []byte) error {
+	return restoreDelegateState(l.delegate, data)
+}
+
+// Err returns the first error writing the records, if any.
+func (l *RecordingLimit) Err() error {
+	return l.writer.Err()
+}
+
+// Writer returns the writer the records are written to, pass it to a recording limiter to capture the request
+// outcomes alongside the samples.
+func (l *RecordingLimit) Writer() *SampleRecordWriter {
+	return l.writer
+}
+
+func (l *RecordingLimit) String() string {
+	return fmt.Sprintf("RecordingLimit{delegate=%v}", l.delegate)
+}
+
+// SampleRecordReader reads the records written by RecordingLimit.
+type SampleRecordReader struct {
+	scanner *bufio.Scanner
+	line    int
+}
+
+// NewSampleRecordReader will create a new SampleRecordReader reading from r.
+func NewSampleRecordReader(r io.Reader) *SampleRecordReader {
+	return &SampleRecordReader{
+		scanner: bufio.NewScanner(r),
+	}
+}
+
+// Next returns the next record, io.EOF once all the records were read.  Blank lines are skipped.
+func (r *SampleRecordReader) Next() (SampleRecord, error) {
+	var record SampleRecord
+	for r.scanner.Scan() {
+		r.line++
+		line := r.scanner.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+		if err := json.Unmarshal(line, &record); err != nil {
+			return record, fmt.Errorf("invalid record on line %d: %v", r.line, err)
+		}
+		return record, nil
+	}
+	if err := r.scanner.Err(); err != nil {
+		return record, err
+	}
+	return record, io.EOF
+}
+
+// ReplaySamples feeds every sample record read from r to the limit in order, calling onSample with each record and
+// the limit's estimate after the sample.  Request outcome records are skipped, they were not samples of the limit.
+// setTime, if not nil, is called with the record's timestamp before the sample is replayed so time based limits can
+// use the capture's clock.
+func ReplaySamples(
+	r *SampleRecordReader,
+	limit core.Limit,
+	setTime func(timestamp int64),
+	onSample func(record SampleRecord, limit int),
+) error {
+	for {
+		record, err := r.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if record.Outcome != "" {
+			continue
+		}
+		if setTime != nil {
+			setTime(record.Timestamp)
+		}
+		limit.OnSample(record.StartTime, record.RTT, record.InFlight, record.DidDrop)
+		if onSample != nil {
+			onSample(record, limit.EstimatedLimit())
+		}
+	}
+}
@@ -0,0 +1,96 @@
+package limit
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type failingWriter struct{}
+
+func (failingWriter) Write(p []byte) (int, error) {
+	return 0, errors.New("write failed")
+}
+
+func TestRecordingLimit(t *testing.T) {
+	t.Parallel()
+
+	t.Run("InvalidArguments", func(t2 *testing.T) {
+		t2.Parallel()
+		asrt := assert.New(t2)
+		_, err := NewRecordingLimit("test", nil, &bytes.Buffer{}, nil)
+		asrt.Error(err)
+		_, err = NewRecordingLimit("test", NewFixedLimit("test", 10), nil, nil)
+		asrt.Error(err)
+	})
+
+	t.Run("RecordAndReplay", func(t2 *testing.T) {
+		t2.Parallel()
+		asrt := assert.New(t2)
+		clk := &testClock{}
+		var buf bytes.Buffer
+		l, err := NewRecordingLimit("test", NewAIMDLimit("test", 10, 0.5), &buf, clk.clock)
+		asrt.NoError(err)
+
+		l.OnSample(0, 10, 10, false)
+		clk.advance(time.Millisecond)
+		l.OnSample(5, 20, 1, true)
+		asrt.NoError(l.Err())
+		asrt.Equal(5, l.EstimatedLimit())
+		asrt.Equal("{\"t\":0,\"r\":10,\"i\":10,\"l\":11}\n{\"t\":1000000,\"s\":5,\"r\":20,\"i\":1,\"d\":true,\"l\":5}\n",
+			buf.String())
+		asrt.Equal("RecordingLimit{delegate=AIMDLimit{limit=5, backOffRatio=0.5000}}", l.String())
+
+		// replaying through the same algorithm reproduces the recorded timeline
+		var timestamps []int64
+		var replayed []int
+		err = ReplaySamples(
+			NewSampleRecordReader(strings.NewReader(buf.String()+"\n{\"t\":2,\"r\":5,\"i\":1,\"l\":5,\"o\":\"success\"}\n")),
+			NewAIMDLimit("test", 10, 0.5),
+			func(timestamp int64) { timestamps = append(timestamps, timestamp) },
+			func(record SampleRecord, limit int) {
+				asrt.Equal(record.Limit, limit)
+				replayed = append(replayed, limit)
+			},
+		)
+		asrt.NoError(err)
+		asrt.Equal([]int64{0, 1000000}, timestamps)
+		asrt.Equal([]int{11, 5}, replayed)
+	})
+
+	t.Run("Errors", func(t2 *testing.T) {
+		t2.Parallel()
+		asrt := assert.New(t2)
+		l, err := NewRecordingLimit("test", NewFixedLimit("test", 10), failingWriter{}, nil)
+		asrt.NoError(err)
+		l.OnSample(0, 10, 1, false)
+		asrt.EqualError(l.Err(), "write failed")
+
+		r := NewSampleRecordReader(strings.NewReader("{\"t\":0,\"r\":10,\"i\":1,\"l\":10}\nnot json\n"))
+		_, err = r.Next()
+		asrt.NoError(err)
+		_, err = r.Next()
+		asrt.EqualError(err, "invalid record on line 2: invalid character 'o' in literal null (expecting 'u')")
+		_, err = r.Next()
+		asrt.Equal(io.EOF, err)
+
+		err = ReplaySamples(NewSampleRecordReader(strings.NewReader("garbage")), NewFixedLimit("test", 10), nil, nil)
+		asrt.Error(err)
+	})
+
+	t.Run("State", func(t2 *testing.T) {
+		t2.Parallel()
+		asrt := assert.New(t2)
+		l, _ := NewRecordingLimit("test", NewSettableLimit("test", 10), &bytes.Buffer{}, nil)
+		asrt.NoError(l.RestoreState([]byte(`{"algorithm":"settable","estimatedLimit":30}`)))
+		asrt.Equal(30, l.EstimatedLimit())
+		data, err := l.MarshalState()
+		asrt.NoError(err)
+		asrt.Equal(`{"algorithm":"settable","estimatedLimit":30}`, string(data))
+	})
+}
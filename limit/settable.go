@@ -1,6 +1,7 @@
 package limit
 
 import (
+	"encoding/json"
 	"fmt"
 	"sync"
 
@@ -12,7 +13,7 @@ import (
 type SettableLimit struct {
 	limit int32
 
-	listeners []core.LimitChangeListener
+	listeners listenerRegistry
 	mu        sync.RWMutex
 }
 
@@ -23,8 +24,7 @@ func NewSettableLimit(name string, limit int) *SettableLimit {
 	}
 
 	l := &SettableLimit{
-		limit:     int32(limit),
-		listeners: make([]core.LimitChangeListener, 0),
+		limit: int32(limit),
 	}
 	return l
 }
@@ -37,17 +37,8 @@ func (l *SettableLimit) EstimatedLimit() int {
 }
 
 // NotifyOnChange will register a callback to receive notification whenever the limit is updated to a new value.
-func (l *SettableLimit) NotifyOnChange(consumer core.LimitChangeListener) {
-	l.mu.Lock()
-	l.listeners = append(l.listeners, consumer)
-	l.mu.Unlock()
-}
-
-// notifyListeners will call the callbacks on limit changes
-func (l *SettableLimit) notifyListeners(newLimit int) {
-	for _, listener := range l.listeners {
-		listener(newLimit)
-	}
+func (l *SettableLimit) NotifyOnChange(consumer core.LimitChangeListener) core.LimitChangeSubscription {
+	return l.listeners.add(consumer)
 }
 
 // OnSample will update the limit with the given sample.
@@ -59,8 +50,27 @@ func (l *SettableLimit) OnSample(startTime int64, rtt int64, inFlight int, didDr
 func (l *SettableLimit) SetLimit(limit int) {
 	l.mu.Lock()
 	l.limit = int32(limit)
-	l.notifyListeners(limit)
 	l.mu.Unlock()
+
+	l.listeners.notify(limit)
+}
+
+// MarshalState returns a snapshot of the limit.
+func (l *SettableLimit) MarshalState() ([]byte, error) {
+	return json.Marshal(LimitState{
+		Algorithm:      "settable",
+		EstimatedLimit: float64(l.EstimatedLimit()),
+	})
+}
+
+// RestoreState restores the limit from a snapshot returned by MarshalState.
+func (l *SettableLimit) RestoreState(data []byte) error {
+	state, err := unmarshalLimitState("settable", data)
+	if err != nil {
+		return err
+	}
+	l.SetLimit(int(state.EstimatedLimit))
+	return nil
 }
 
 func (l *SettableLimit) String() string {
@@ -0,0 +1,81 @@
+package limit
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+
+	"github.com/platinummonkey/go-concurrency-limits/core"
+)
+
+// LimitState is a snapshot of the learned state of a limit algorithm, allowing an instance to resume from where it
+// left off after a restart instead of relearning its limit from the initial limit.  Fields an algorithm doesn't track
+// are left empty.
+type LimitState struct {
+	// Algorithm identifies the algorithm that produced the state, restoring into a different algorithm fails.
+	Algorithm string `json:"algorithm"`
+	// EstimatedLimit is the estimated limit.
+	EstimatedLimit float64 `json:"estimatedLimit"`
+	// RTTNoLoad is the baseline RTT, in nanoseconds.
+	RTTNoLoad int64 `json:"rttNoLoad,omitempty"`
+	// LongRTT is the long term RTT, in nanoseconds, tracked by Gradient2Limit.
+	LongRTT float64 `json:"longRtt,omitempty"`
+	// ShortRTT is the short term RTT, in nanoseconds, tracked by Gradient2Limit.
+	ShortRTT float64 `json:"shortRtt,omitempty"`
+}
+
+// StatefulLimit is a Limit whose learned state can be persisted and restored.
+type StatefulLimit interface {
+	core.Limit
+	// MarshalState returns a snapshot of the learned state.
+	MarshalState() ([]byte, error)
+	// RestoreState restores the learned state from a snapshot returned by MarshalState and notifies listeners of the
+	// restored limit.
+	RestoreState(data []byte) error
+}
+
+// marshalDelegateState returns the state of a wrapped limit, failing when the delegate is not a StatefulLimit.
+func marshalDelegateState(delegate core.Limit) ([]byte, error) {
+	stateful, ok := delegate.(StatefulLimit)
+	if !ok {
+		return nil, fmt.Errorf("limit %v is not a StatefulLimit", delegate)
+	}
+	return stateful.MarshalState()
+}
+
+// restoreDelegateState restores the state of a wrapped limit, failing when the delegate is not a StatefulLimit.
+func restoreDelegateState(delegate core.Limit, data []byte) error {
+	stateful, ok := delegate.(StatefulLimit)
+	if !ok {
+		return fmt.Errorf("limit %v is not a StatefulLimit", delegate)
+	}
+	return stateful.RestoreState(data)
+}
+
+// unmarshalLimitState decodes a LimitState and checks it was produced by the given algorithm.
+func unmarshalLimitState(algorithm string, data []byte) (LimitState, error) {
+	var state LimitState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return state, fmt.Errorf("invalid limit state: %v", err)
+	}
+	if state.Algorithm != algorithm {
+		return state, fmt.Errorf("limit state is for algorithm %q, expected %q", state.Algorithm, algorithm)
+	}
+	if state.EstimatedLimit <= 0 || math.IsNaN(state.EstimatedLimit) || math.IsInf(state.EstimatedLimit, 0) {
+		return state, fmt.Errorf("invalid estimated limit %v", state.EstimatedLimit)
+	}
+	return state, nil
+}
+
+// restoreMeasurement seeds the measurement with the value, a zero value leaves it empty.
+func restoreMeasurement(m core.MeasurementInterface, value float64) {
+	m.Reset()
+	if value > 0 {
+		m.Add(value)
+	}
+}
+
+// clampLimit clamps the limit to [minLimit, maxLimit].
+func clampLimit(limit float64, minLimit int, maxLimit int) float64 {
+	return math.Max(float64(minLimit), math.Min(float64(maxLimit), limit))
+}
@@ -0,0 +1,163 @@
+package limit
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// FileLimitStateStore periodically persists the state of a StatefulLimit to a file and seeds the limit from it on
+// start, so a restarted instance resumes from its last learned limit.  Writes go through a temporary file that is
+// synced and renamed into place, and the directory is synced after the rename, so a crash never leaves a partially
+// written state behind.
+type FileLimitStateStore struct {
+	path     string
+	limit    StatefulLimit
+	interval time.Duration
+	logger   Logger
+
+	stop chan struct{}
+	done chan struct{}
+	mu   sync.Mutex
+}
+
+// NewFileLimitStateStore will create a new FileLimitStateStore.
+// @param path: The file the state is persisted to.
+// @param limit: The limit whose state is persisted.
+// @param interval: How often the state is written, defaults to 10 seconds.
+func NewFileLimitStateStore(
+	path string,
+	limit StatefulLimit,
+	interval time.Duration,
+	logger Logger,
+) (*FileLimitStateStore, error) {
+	if path == "" {
+		return nil, fmt.Errorf("path must be specified")
+	}
+	if limit == nil {
+		return nil, fmt.Errorf("limit must be specified")
+	}
+	if interval <= 0 {
+		interval = 10 * time.Second
+	}
+	if logger == nil {
+		logger = NoopLimitLogger{}
+	}
+	return &FileLimitStateStore{
+		path:     path,
+		limit:    limit,
+		interval: interval,
+		logger:   logger,
+	}, nil
+}
+
+// Restore seeds the limit from the persisted state.  A missing file is not an error, the limit keeps its initial
+// state.
+func (s *FileLimitStateStore) Restore() error {
+	data, err := ioutil.ReadFile(s.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	return s.limit.RestoreState(data)
+}
+
+// Save persists the current state of the limit.
+func (s *FileLimitStateStore) Save() error {
+	data, err := s.limit.MarshalState()
+	if err != nil {
+		return err
+	}
+	f, err := ioutil.TempFile(filepath.Dir(s.path), filepath.Base(s.path)+".tmp")
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return err
+	}
+	// flush the contents before the rename, otherwise a power loss can leave the renamed file empty
+	if err := f.Sync(); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+	if err := os.Rename(f.Name(), s.path); err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+	return syncDir(filepath.Dir(s.path))
+}
+
+// syncDir flushes the directory entries of dir so a rename into it is durable.
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	if err := d.Sync(); err != nil {
+		d.Close()
+		return err
+	}
+	return d.Close()
+}
+
+// Start seeds the limit from the persisted state and starts persisting the state every interval until Stop is
+// called.  A state that can't be restored is logged and the limit keeps its initial state.
+func (s *FileLimitStateStore) Start() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.stop != nil {
+		return
+	}
+	if err := s.Restore(); err != nil {
+		s.logger.Debugf("failed to restore limit state from %s: %v", s.path, err)
+	}
+	s.stop = make(chan struct{})
+	s.done = make(chan struct{})
+	go s.run(s.stop, s.done)
+}
+
+func (s *FileLimitStateStore) run(stop <-chan struct{}, done chan<- struct{}) {
+	defer close(done)
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			if err := s.Save(); err != nil {
+				s.logger.Debugf("failed to save limit state to %s: %v", s.path, err)
+			}
+		}
+	}
+}
+
+// Stop stops persisting the state periodically and writes the final state.
+func (s *FileLimitStateStore) Stop() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.stop == nil {
+		return nil
+	}
+	close(s.stop)
+	<-s.done
+	s.stop = nil
+	s.done = nil
+	return s.Save()
+}
+
+func (s *FileLimitStateStore) String() string {
+	return fmt.Sprintf("FileLimitStateStore{path=%s, interval=%v, limit=%v}", s.path, s.interval, s.limit)
+}
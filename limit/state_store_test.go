@@ -0,0 +1,151 @@
+package limit
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFileLimitStateStore(t *testing.T) {
+	t.Parallel()
+
+	t.Run("InvalidArguments", func(t2 *testing.T) {
+		t2.Parallel()
+		asrt := assert.New(t2)
+		_, err := NewFileLimitStateStore("", NewSettableLimit("test", 10), time.Second, nil)
+		asrt.Error(err)
+		_, err = NewFileLimitStateStore("state.json", nil, time.Second, nil)
+		asrt.Error(err)
+	})
+
+	t.Run("SaveAndRestore", func(t2 *testing.T) {
+		t2.Parallel()
+		asrt := assert.New(t2)
+		dir, err := ioutil.TempDir("", "limit-state")
+		asrt.NoError(err)
+		defer os.RemoveAll(dir)
+		path := filepath.Join(dir, "state.json")
+
+		l := NewSettableLimit("test", 10)
+		store, err := NewFileLimitStateStore(path, l, time.Second, nil)
+		asrt.NoError(err)
+
+		// nothing persisted yet
+		asrt.NoError(store.Restore())
+		asrt.Equal(10, l.EstimatedLimit())
+
+		l.SetLimit(42)
+		asrt.NoError(store.Save())
+
+		restored := NewSettableLimit("test", 10)
+		store, err = NewFileLimitStateStore(path, restored, time.Second, nil)
+		asrt.NoError(err)
+		asrt.NoError(store.Restore())
+		asrt.Equal(42, restored.EstimatedLimit())
+
+		// no temporary files are left behind
+		files, err := ioutil.ReadDir(dir)
+		asrt.NoError(err)
+		asrt.Len(files, 1)
+
+		asrt.NoError(ioutil.WriteFile(path, []byte("corrupt"), 0600))
+		asrt.Error(store.Restore())
+		asrt.Equal(42, restored.EstimatedLimit())
+	})
+
+	t.Run("StartStop", func(t2 *testing.T) {
+		t2.Parallel()
+		asrt := assert.New(t2)
+		dir, err := ioutil.TempDir("", "limit-state")
+		asrt.NoError(err)
+		defer os.RemoveAll(dir)
+		path := filepath.Join(dir, "state.json")
+		asrt.NoError(ioutil.WriteFile(path, []byte(`{"algorithm":"settable","estimatedLimit":30}`), 0600))
+
+		l := NewSettableLimit("test", 10)
+		store, err := NewFileLimitStateStore(path, l, time.Millisecond, nil)
+		asrt.NoError(err)
+		store.Start()
+		// seeded on start
+		asrt.Equal(30, l.EstimatedLimit())
+		store.Start()
+
+		l.SetLimit(27)
+		saved := false
+		for i := 0; i < 1000 && !saved; i++ {
+			data, err := ioutil.ReadFile(path)
+			saved = err == nil && string(data) == `{"algorithm":"settable","estimatedLimit":27}`
+			time.Sleep(time.Millisecond)
+		}
+		asrt.True(saved)
+
+		l.SetLimit(24)
+		asrt.NoError(store.Stop())
+		asrt.NoError(store.Stop())
+		data, err := ioutil.ReadFile(path)
+		asrt.NoError(err)
+		asrt.Equal(`{"algorithm":"settable","estimatedLimit":24}`, string(data))
+		asrt.Contains(store.String(), "FileLimitStateStore{path="+path)
+	})
+
+	t.Run("WrappedLimit", func(t2 *testing.T) {
+		t2.Parallel()
+		asrt := assert.New(t2)
+		dir, err := ioutil.TempDir("", "limit-state")
+		asrt.NoError(err)
+		defer os.RemoveAll(dir)
+		path := filepath.Join(dir, "state.json")
+
+		gradient, _ := NewGradient2Limit("test", 20, 1000, 4, nil, -1, -1, nil)
+		asrt.NoError(gradient.RestoreState([]byte(`{"algorithm":"gradient2","estimatedLimit":42,"longRtt":10,"shortRtt":12}`)))
+		store, err := NewFileLimitStateStore(path, NewDefaultWindowedLimit("test", gradient), time.Second, nil)
+		asrt.NoError(err)
+		asrt.NoError(store.Save())
+
+		restoredGradient, _ := NewGradient2Limit("test", 20, 1000, 4, nil, -1, -1, nil)
+		restored := NewDefaultWindowedLimit("test", restoredGradient)
+		store, err = NewFileLimitStateStore(path, restored, time.Second, nil)
+		asrt.NoError(err)
+		asrt.NoError(store.Restore())
+		asrt.Equal(42, restored.EstimatedLimit())
+		data, err := restored.MarshalState()
+		asrt.NoError(err)
+		asrt.Equal(`{"algorithm":"gradient2","estimatedLimit":42,"longRtt":10,"shortRtt":12}`, string(data))
+
+		// the restored limit is passed through the other wrappers
+		traced := NewTracedLimit(NewSettableLimit("test", 10), NoopLimitLogger{})
+		asrt.NoError(traced.RestoreState([]byte(`{"algorithm":"settable","estimatedLimit":30}`)))
+		asrt.Equal(30, traced.EstimatedLimit())
+		bounded, _ := NewBoundedLimit(NewSettableLimit("test", 10), 1, 100, 1, 1)
+		asrt.NoError(bounded.RestoreState([]byte(`{"algorithm":"settable","estimatedLimit":30}`)))
+		asrt.Equal(30, bounded.EstimatedLimit())
+
+		// wrapping a limit without state fails
+		_, err = NewDefaultWindowedLimit("test", NewFixedLimit("test", 10)).MarshalState()
+		asrt.Error(err)
+		asrt.Error(NewDefaultWindowedLimit("test", NewFixedLimit("test", 10)).RestoreState(data))
+	})
+
+	t.Run("CompositeLimit", func(t2 *testing.T) {
+		t2.Parallel()
+		asrt := assert.New(t2)
+		settable := NewSettableLimit("test", 10)
+		l, err := NewCompositeLimit("test", MaxLimitReducer, NewFixedLimit("test", 5), settable)
+		asrt.NoError(err)
+		settable.SetLimit(42)
+		data, err := l.MarshalState()
+		asrt.NoError(err)
+		asrt.Equal(`[null,{"algorithm":"settable","estimatedLimit":42}]`, string(data))
+
+		restored, _ := NewCompositeLimit("test", MaxLimitReducer, NewFixedLimit("test", 5), NewSettableLimit("test", 10))
+		asrt.NoError(restored.RestoreState(data))
+		asrt.Equal(42, restored.EstimatedLimit())
+
+		asrt.Error(restored.RestoreState([]byte(`[null]`)))
+		asrt.Error(restored.RestoreState([]byte(`{"algorithm":"settable","estimatedLimit":42}`)))
+	})
+}
@@ -0,0 +1,79 @@
+package limit
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLimitState(t *testing.T) {
+	t.Parallel()
+
+	factories := map[string]func() StatefulLimit{
+		"aimd": func() StatefulLimit { return NewDefaultAIMLimit("test") },
+		"gradient": func() StatefulLimit {
+			return NewGradientLimitWithRegistry("test", 0, 0, 0, -1, nil, -1, 0, NoopLimitLogger{})
+		},
+		"gradient2": func() StatefulLimit { return NewDefaultGradient2Limit("test", nil) },
+		"vegas":     func() StatefulLimit { return NewDefaultVegasLimit("test", nil) },
+		"settable":  func() StatefulLimit { return NewSettableLimit("test", 10) },
+		"bbr":       func() StatefulLimit { return NewDefaultBBRLimit("test", nil) },
+		"cubic":     func() StatefulLimit { return NewDefaultCubicLimit("test", nil) },
+		"pid": func() StatefulLimit {
+			l, _ := NewDefaultPIDLimit("test", 5e6, nil)
+			return l
+		},
+		"latency_slo": func() StatefulLimit {
+			l, _ := NewDefaultLatencySLOLimit("test", 0.9, 20e6, nil)
+			return l
+		},
+		"error_rate": func() StatefulLimit { return NewDefaultErrorRateLimit("test", nil) },
+	}
+
+	for name, factory := range factories {
+		name := name
+		factory := factory
+		t.Run(name, func(t2 *testing.T) {
+			t2.Parallel()
+			asrt := assert.New(t2)
+
+			trained := factory()
+			for i := 0; i < 50; i++ {
+				trained.OnSample(0, int64(10e6+(i%5)*1e6), trained.EstimatedLimit(), i%25 == 24)
+			}
+			data, err := trained.MarshalState()
+			asrt.NoError(err)
+			asrt.Contains(string(data), `"algorithm":"`+name+`"`)
+
+			restored := factory()
+			listener := testNotifyListener{}
+			restored.NotifyOnChange(listener.updater())
+			asrt.NoError(restored.RestoreState(data))
+			asrt.Equal(trained.EstimatedLimit(), restored.EstimatedLimit())
+			asrt.Equal([]int{trained.EstimatedLimit()}, listener.changes)
+
+			restoredData, err := restored.MarshalState()
+			asrt.NoError(err)
+			asrt.JSONEq(string(data), string(restoredData))
+
+			// state from another algorithm or garbage is rejected
+			asrt.Error(restored.RestoreState([]byte(`{"algorithm":"unknown","estimatedLimit":10}`)))
+			asrt.Error(restored.RestoreState([]byte(`{"algorithm":"` + name + `","estimatedLimit":0}`)))
+			asrt.Error(restored.RestoreState([]byte(`not json`)))
+			asrt.Equal(trained.EstimatedLimit(), restored.EstimatedLimit())
+		})
+	}
+
+	t.Run("Gradient2RTT", func(t2 *testing.T) {
+		t2.Parallel()
+		asrt := assert.New(t2)
+		l := NewDefaultGradient2Limit("test", nil)
+		err := l.RestoreState([]byte(`{"algorithm":"gradient2","estimatedLimit":5000,"longRtt":2e7,"shortRtt":1e7}`))
+		asrt.NoError(err)
+		// clamped to maxConcurrency
+		asrt.Equal(200, l.EstimatedLimit())
+		data, err := l.MarshalState()
+		asrt.NoError(err)
+		asrt.JSONEq(`{"algorithm":"gradient2","estimatedLimit":200,"longRtt":20000000,"shortRtt":10000000}`, string(data))
+	})
+}
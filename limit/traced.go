@@ -69,8 +69,8 @@ func (l *TracedLimit) EstimatedLimit() int {
 }
 
 // NotifyOnChange will register a callback to receive notification whenever the limit is updated to a new value.
-func (l *TracedLimit) NotifyOnChange(consumer core.LimitChangeListener) {
-	l.limit.NotifyOnChange(consumer)
+func (l *TracedLimit) NotifyOnChange(consumer core.LimitChangeListener) core.LimitChangeSubscription {
+	return l.limit.NotifyOnChange(consumer)
 }
 
 // OnSample will log and deleate the update of the sample.
@@ -79,6 +79,17 @@ func (l *TracedLimit) OnSample(startTime int64, rtt int64, inFlight int, didDrop
 	l.limit.OnSample(startTime, rtt, inFlight, didDrop)
 }
 
+// MarshalState returns a snapshot of the wrapped limit's learned state, the limit must be a StatefulLimit.
+func (l *TracedLimit) MarshalState() ([]byte, error) {
+	return marshalDelegateState(l.limit)
+}
+
+// RestoreState restores the wrapped limit's learned state from a snapshot returned by MarshalState.
+func (l *TracedLimit) RestoreState(data []byte) error {
+	l.logger.Debugf("restoring state=%s", data)
+	return restoreDelegateState(l.limit, data)
+}
+
 func (l TracedLimit) String() string {
 	return fmt.Sprintf("TracedLimit{limit=%v, logger=%v}", l.limit, l.logger)
 }
@@ -1,6 +1,7 @@
 package limit
 
 import (
+	"encoding/json"
 	"fmt"
 	"math"
 	"math/rand"
@@ -33,7 +34,7 @@ type VegasLimit struct {
 	probeJitter    float64
 	probeCount     int64
 
-	listeners []core.LimitChangeListener
+	listeners listenerRegistry
 	logger    Logger
 	mu        sync.RWMutex
 }
@@ -161,7 +162,6 @@ func NewVegasLimitWithRegistry(
 		probeJitter:    newProbeJitter(),
 		probeCount:     0,
 		rttNoLoad:      rttNoLoad,
-		listeners:      make([]core.LimitChangeListener, 0),
 		logger:         logger,
 	}
 
@@ -183,21 +183,19 @@ func (l *VegasLimit) EstimatedLimit() int {
 }
 
 // NotifyOnChange will register a callback to receive notification whenever the limit is updated to a new value.
-func (l *VegasLimit) NotifyOnChange(consumer core.LimitChangeListener) {
-	l.mu.Lock()
-	l.listeners = append(l.listeners, consumer)
-	l.mu.Unlock()
+func (l *VegasLimit) NotifyOnChange(consumer core.LimitChangeListener) core.LimitChangeSubscription {
+	return l.listeners.add(consumer)
 }
 
-// notifyListeners will call the callbacks on limit changes
-func (l *VegasLimit) notifyListeners(newLimit float64) {
-	for _, listener := range l.listeners {
-		listener(int(newLimit))
+// OnSample the concurrency limit using a new rtt sample.
+func (l *VegasLimit) OnSample(startTime int64, rtt int64, inFlight int, didDrop bool) {
+	if newLimit, ok := l.onSample(startTime, rtt, inFlight, didDrop); ok {
+		l.listeners.notify(newLimit)
 	}
 }
 
-// OnSample the concurrency limit using a new rtt sample.
-func (l *VegasLimit) OnSample(startTime int64, rtt int64, inFlight int, didDrop bool) {
+// onSample updates the estimated limit under the lock, returning the limit to notify listeners of.
+func (l *VegasLimit) onSample(startTime int64, rtt int64, inFlight int, didDrop bool) (int, bool) {
 	l.mu.Lock()
 	defer l.mu.Unlock()
 
@@ -208,23 +206,23 @@ func (l *VegasLimit) OnSample(startTime int64, rtt int64, inFlight int, didDrop
 		l.probeJitter = newProbeJitter()
 		l.probeCount = 0
 		l.rttNoLoad.Add(float64(rtt))
-		return
+		return 0, false
 	}
 
 	if l.rttNoLoad.Get() == 0 || float64(rtt) < l.rttNoLoad.Get() {
 		l.logger.Debugf("Update RTT No Load to %d ms from %d ms", rtt/1e6, int64(l.rttNoLoad.Get())/1e6)
 		l.rttNoLoad.Add(float64(rtt))
-		return
+		return 0, false
 	}
 
-	l.updateEstimatedLimit(startTime, rtt, inFlight, didDrop)
+	return l.updateEstimatedLimit(startTime, rtt, inFlight, didDrop)
 }
 
 func (l *VegasLimit) shouldProbe() bool {
 	return int64(l.probeJitter*float64(l.probeMultipler)*l.estimatedLimit) <= l.probeCount
 }
 
-func (l *VegasLimit) updateEstimatedLimit(startTime int64, rtt int64, inFlight int, didDrop bool) {
+func (l *VegasLimit) updateEstimatedLimit(startTime int64, rtt int64, inFlight int, didDrop bool) (int, bool) {
 	queueSize := int(math.Ceil(l.estimatedLimit * (1 - l.rttNoLoad.Get()/float64(rtt))))
 
 	var newLimit float64
@@ -233,7 +231,7 @@ func (l *VegasLimit) updateEstimatedLimit(startTime int64, rtt int64, inFlight i
 		newLimit = l.decreaseFunc(l.estimatedLimit)
 	} else if float64(inFlight)*2 < l.estimatedLimit {
 		// Prevent upward drift if not close to the limit
-		return
+		return 0, false
 	} else {
 		alpha := l.alphaFunc(int(l.estimatedLimit))
 		beta := l.betaFunc(int(l.estimatedLimit))
@@ -250,7 +248,7 @@ func (l *VegasLimit) updateEstimatedLimit(startTime int64, rtt int64, inFlight i
 			newLimit = l.decreaseFunc(l.estimatedLimit)
 		} else {
 			// otherwise we're within he sweet spot so nothing to do
-			return
+			return 0, false
 		}
 	}
 
@@ -263,7 +261,7 @@ func (l *VegasLimit) updateEstimatedLimit(startTime int64, rtt int64, inFlight i
 	}
 
 	l.estimatedLimit = newLimit
-	l.notifyListeners(l.estimatedLimit)
+	return int(l.estimatedLimit), true
 }
 
 // RTTNoLoad returns the current RTT No Load value.
@@ -273,6 +271,33 @@ func (l *VegasLimit) RTTNoLoad() int64 {
 	return int64(l.rttNoLoad.Get())
 }
 
+// MarshalState returns a snapshot of the learned limit and RTT No Load.
+func (l *VegasLimit) MarshalState() ([]byte, error) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return json.Marshal(LimitState{
+		Algorithm:      "vegas",
+		EstimatedLimit: l.estimatedLimit,
+		RTTNoLoad:      int64(l.rttNoLoad.Get()),
+	})
+}
+
+// RestoreState restores the learned limit and RTT No Load from a snapshot returned by MarshalState.
+func (l *VegasLimit) RestoreState(data []byte) error {
+	state, err := unmarshalLimitState("vegas", data)
+	if err != nil {
+		return err
+	}
+	l.mu.Lock()
+	l.estimatedLimit = clampLimit(state.EstimatedLimit, 1, l.maxLimit)
+	restoreMeasurement(l.rttNoLoad, float64(state.RTTNoLoad))
+	newLimit := int(l.estimatedLimit)
+	l.mu.Unlock()
+
+	l.listeners.notify(newLimit)
+	return nil
+}
+
 func (l *VegasLimit) String() string {
 	return fmt.Sprintf("VegasLimit{limit=%d, rttNoLoad=%d ms}",
 		l.EstimatedLimit(), l.RTTNoLoad())
@@ -0,0 +1,242 @@
+package limit
+
+import (
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/platinummonkey/go-concurrency-limits/core"
+)
+
+// WarmupRamp is the shape of the cap applied by WarmupLimit during warm-up.
+type WarmupRamp int
+
+const (
+	// WarmupRampLinear raises the cap linearly from the start limit to the end limit.
+	WarmupRampLinear WarmupRamp = iota
+	// WarmupRampExponential raises the cap geometrically from the start limit to the end limit, staying low for
+	// longer and catching up towards the end of the warm-up.
+	WarmupRampExponential
+)
+
+func (r WarmupRamp) String() string {
+	switch r {
+	case WarmupRampLinear:
+		return "Linear"
+	case WarmupRampExponential:
+		return "Exponential"
+	}
+	return "Unknown"
+}
+
+// WarmupLimit wraps a delegate Limit and caps its estimate while a freshly started instance warms up its caches and
+// connections.  The cap ramps from startLimit up to the delegate's current limit as the warm-up progresses
+//
+//	linear:      cap = startLimit + (delegateLimit - startLimit) * progress
+//	exponential: cap = startLimit * (delegateLimit / startLimit) ^ progress
+//
+// Progress is the fraction of the warm-up duration elapsed or of the warm-up samples seen, whichever is further along.
+// The elapsed duration is read from the clock whenever the limit is read, so an idle instance still completes its
+// warm-up.  Once the warm-up completes the delegate's limit is passed through as is.
+type WarmupLimit struct {
+	delegate      core.Limit
+	startLimit    int
+	ramp          WarmupRamp
+	duration      int64
+	samples       int
+	startTime     int64
+	sampleCount   int
+	delegateLimit int
+	progress      float64
+	limit         int
+
+	clock     Clock
+	listeners listenerRegistry
+	logger    Logger
+	mu        sync.RWMutex
+}
+
+// NewWarmupLimit will create a new WarmupLimit, the warm-up starts when the limit is created.
+// @param delegate: The limit to cap during warm-up.
+// @param startLimit: The cap at the start of the warm-up, accepts >= 1.
+// @param ramp: The shape of the ramp from startLimit to the delegate's limit.
+// @param duration: Duration of the warm-up, 0 to only warm up by sample count.
+// @param samples: Number of samples in the warm-up, 0 to only warm up by duration.
+// @param clock: Source of the current time, defaults to SystemClock.
+func NewWarmupLimit(
+	name string,
+	delegate core.Limit,
+	startLimit int,
+	ramp WarmupRamp,
+	duration time.Duration,
+	samples int,
+	clock Clock,
+	logger Logger,
+) (*WarmupLimit, error) {
+	if delegate == nil {
+		return nil, fmt.Errorf("delegate must be specified")
+	}
+	if startLimit < 1 {
+		return nil, fmt.Errorf("startLimit must be >= 1")
+	}
+	if ramp != WarmupRampLinear && ramp != WarmupRampExponential {
+		return nil, fmt.Errorf("unknown ramp %d", ramp)
+	}
+	if duration < 0 || samples < 0 {
+		return nil, fmt.Errorf("duration and samples must be >= 0")
+	}
+	if duration == 0 && samples == 0 {
+		return nil, fmt.Errorf("one of duration or samples must be specified")
+	}
+	if clock == nil {
+		clock = SystemClock
+	}
+	if logger == nil {
+		logger = NoopLimitLogger{}
+	}
+
+	l := &WarmupLimit{
+		delegate:      delegate,
+		startLimit:    startLimit,
+		ramp:          ramp,
+		duration:      duration.Nanoseconds(),
+		samples:       samples,
+		startTime:     clock(),
+		delegateLimit: delegate.EstimatedLimit(),
+		clock:         clock,
+		logger:        logger,
+	}
+	l.limit = l.cappedLimit()
+	delegate.NotifyOnChange(l.onDelegateChange)
+	return l, nil
+}
+
+// onDelegateChange reads back the delegate's current limit, notifications may be delivered out of order.
+func (l *WarmupLimit) onDelegateChange(_ int) {
+	limit := l.delegate.EstimatedLimit()
+	now := l.clock()
+	l.mu.Lock()
+	l.delegateLimit = limit
+	newLimit, changed := l.update(now)
+	l.mu.Unlock()
+
+	if changed {
+		l.listeners.notify(newLimit)
+	}
+}
+
+// update advances the warm-up progress to now and recomputes the capped limit, returning the limit and whether it
+// changed.
+// note: not thread safe.
+func (l *WarmupLimit) update(now int64) (int, bool) {
+	if l.progress < 1 {
+		progress := 0.0
+		if l.duration > 0 {
+			progress = float64(now-l.startTime) / float64(l.duration)
+		}
+		if l.samples > 0 {
+			progress = math.Max(progress, float64(l.sampleCount)/float64(l.samples))
+		}
+		l.progress = math.Min(1, progress)
+		if l.progress >= 1 {
+			l.logger.Debugf("warm-up complete")
+		}
+	}
+	newLimit := l.cappedLimit()
+	if newLimit == l.limit {
+		return newLimit, false
+	}
+	l.limit = newLimit
+	return newLimit, true
+}
+
+// cappedLimit returns the delegate's limit capped by the ramp at the current progress.
+// note: not thread safe.
+func (l *WarmupLimit) cappedLimit() int {
+	if l.progress >= 1 || l.delegateLimit <= l.startLimit {
+		return l.delegateLimit
+	}
+	start := float64(l.startLimit)
+	end := float64(l.delegateLimit)
+	var ceiling float64
+	switch l.ramp {
+	case WarmupRampExponential:
+		ceiling = start * math.Pow(end/start, l.progress)
+	default:
+		ceiling = start + (end-start)*l.progress
+	}
+	return int(math.Min(end, math.Floor(ceiling)))
+}
+
+// EstimatedLimit returns the delegate's estimated limit capped by the warm-up ramp at the current time.
+func (l *WarmupLimit) EstimatedLimit() int {
+	now := l.clock()
+	l.mu.Lock()
+	newLimit, changed := l.update(now)
+	l.mu.Unlock()
+
+	if changed {
+		l.listeners.notify(newLimit)
+	}
+	return newLimit
+}
+
+// NotifyOnChange will register a callback to receive notification whenever the limit is updated to a new value.
+func (l *WarmupLimit) NotifyOnChange(consumer core.LimitChangeListener) core.LimitChangeSubscription {
+	return l.listeners.add(consumer)
+}
+
+// OnSample will delegate the sample and advance the warm-up.
+func (l *WarmupLimit) OnSample(startTime int64, rtt int64, inFlight int, didDrop bool) {
+	l.delegate.OnSample(startTime, rtt, inFlight, didDrop)
+
+	now := l.clock()
+	l.mu.Lock()
+	if l.progress < 1 {
+		l.sampleCount++
+	}
+	newLimit, changed := l.update(now)
+	l.mu.Unlock()
+
+	if changed {
+		l.listeners.notify(newLimit)
+	}
+}
+
+// MarshalState returns a snapshot of the delegate's learned state, the delegate must be a StatefulLimit.
+func (l *WarmupLimit) MarshalState() ([]byte, error) {
+	return marshalDelegateState(l.delegate)
+}
+
+// RestoreState restores the delegate's learned state from a snapshot returned by MarshalState, the warm-up then ramps
+// up to the restored limit.
+func (l *WarmupLimit) RestoreState(data []byte) error {
+	return restoreDelegateState(l.delegate, data)
+}
+
+// Progress returns the fraction of the warm-up completed, [0,1].
+func (l *WarmupLimit) Progress() float64 {
+	now := l.clock()
+	l.mu.Lock()
+	newLimit, changed := l.update(now)
+	progress := l.progress
+	l.mu.Unlock()
+
+	if changed {
+		l.listeners.notify(newLimit)
+	}
+	return progress
+}
+
+// Done returns true once the warm-up completed and the delegate's limit is passed through.
+func (l *WarmupLimit) Done() bool {
+	return l.Progress() >= 1
+}
+
+func (l *WarmupLimit) String() string {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return fmt.Sprintf("WarmupLimit{limit=%d, ramp=%s, progress=%0.4f, delegate=%v}",
+		l.limit, l.ramp, l.progress, l.delegate)
+}
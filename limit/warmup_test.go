@@ -0,0 +1,112 @@
+package limit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWarmupLimit(t *testing.T) {
+	t.Parallel()
+
+	t.Run("InvalidArguments", func(t2 *testing.T) {
+		t2.Parallel()
+		asrt := assert.New(t2)
+		_, err := NewWarmupLimit("test", nil, 1, WarmupRampLinear, time.Second, 0, nil, nil)
+		asrt.Error(err)
+		_, err = NewWarmupLimit("test", NewFixedLimit("test", 10), 0, WarmupRampLinear, time.Second, 0, nil, nil)
+		asrt.Error(err)
+		_, err = NewWarmupLimit("test", NewFixedLimit("test", 10), 1, WarmupRamp(5), time.Second, 0, nil, nil)
+		asrt.Error(err)
+		_, err = NewWarmupLimit("test", NewFixedLimit("test", 10), 1, WarmupRampLinear, 0, 0, nil, nil)
+		asrt.Error(err)
+		_, err = NewWarmupLimit("test", NewFixedLimit("test", 10), 1, WarmupRampLinear, -time.Second, 10, nil, nil)
+		asrt.Error(err)
+	})
+
+	t.Run("LinearByDuration", func(t2 *testing.T) {
+		t2.Parallel()
+		asrt := assert.New(t2)
+		clk := &testClock{}
+		delegate := NewSettableLimit("test", 110)
+		l, err := NewWarmupLimit("test", delegate, 10, WarmupRampLinear, 10*time.Second, 0, clk.clock, nil)
+		asrt.NoError(err)
+		asrt.Equal(10, l.EstimatedLimit())
+		listener := testNotifyListener{}
+		l.NotifyOnChange(listener.updater())
+
+		clk.advance(5 * time.Second)
+		l.OnSample(0, 10, 1, false)
+		asrt.Equal(60, l.EstimatedLimit())
+		asrt.InDelta(0.5, l.Progress(), 0.0001)
+		asrt.False(l.Done())
+
+		// the cap ramps towards the delegate's current limit, changes are applied as they are notified
+		delegate.SetLimit(40)
+		asrt.Equal(25, l.EstimatedLimit())
+
+		delegate.SetLimit(110)
+		asrt.Equal(60, l.EstimatedLimit())
+		clk.advance(5 * time.Second)
+		l.OnSample(0, 10, 1, false)
+		asrt.Equal(110, l.EstimatedLimit())
+		asrt.True(l.Done())
+
+		delegate.SetLimit(200)
+		l.OnSample(0, 10, 1, false)
+		asrt.Equal(200, l.EstimatedLimit())
+		asrt.Equal([]int{60, 25, 60, 110, 200}, listener.changes)
+		asrt.Equal("WarmupLimit{limit=200, ramp=Linear, progress=1.0000, delegate=SettableLimit{limit=200}}", l.String())
+	})
+
+	t.Run("ExponentialBySamples", func(t2 *testing.T) {
+		t2.Parallel()
+		asrt := assert.New(t2)
+		clk := &testClock{}
+		l, err := NewWarmupLimit("test", NewFixedLimit("test", 100), 1, WarmupRampExponential, 0, 4, clk.clock, nil)
+		asrt.NoError(err)
+		asrt.Equal(1, l.EstimatedLimit())
+
+		// the duration is ignored when only warming up by samples
+		clk.advance(time.Hour)
+		expected := []int{3, 10, 31, 100}
+		for _, e := range expected {
+			l.OnSample(0, 10, 1, false)
+			asrt.Equal(e, l.EstimatedLimit())
+		}
+		asrt.True(l.Done())
+	})
+
+	t.Run("FirstOfDurationOrSamples", func(t2 *testing.T) {
+		t2.Parallel()
+		asrt := assert.New(t2)
+		clk := &testClock{}
+		l, err := NewWarmupLimit("test", NewFixedLimit("test", 100), 20, WarmupRampLinear, 10*time.Second, 10, clk.clock, nil)
+		asrt.NoError(err)
+		l.OnSample(0, 10, 1, false)
+		asrt.InDelta(0.1, l.Progress(), 0.0001)
+		clk.advance(5 * time.Second)
+		l.OnSample(0, 10, 1, false)
+		asrt.InDelta(0.5, l.Progress(), 0.0001)
+		asrt.Equal(60, l.EstimatedLimit())
+	})
+
+	t.Run("IdleCompletesByDuration", func(t2 *testing.T) {
+		t2.Parallel()
+		asrt := assert.New(t2)
+		clk := &testClock{}
+		l, err := NewWarmupLimit("test", NewFixedLimit("test", 100), 10, WarmupRampLinear, 10*time.Second, 0, clk.clock, nil)
+		asrt.NoError(err)
+		listener := testNotifyListener{}
+		l.NotifyOnChange(listener.updater())
+
+		// no samples are seen, the warm-up still progresses with the clock
+		clk.advance(5 * time.Second)
+		asrt.Equal(55, l.EstimatedLimit())
+		clk.advance(5 * time.Second)
+		asrt.True(l.Done())
+		asrt.Equal(100, l.EstimatedLimit())
+		asrt.Equal([]int{55, 100}, listener.changes)
+	})
+}
@@ -18,9 +18,8 @@ type WindowedLimit struct {
 	windowSize      int32 // Minimum sampling window size for finding a new minimum rtt
 	minRTTThreshold int64
 
-	delegate  core.Limit
-	sample    *measurements.ImmutableSampleWindow
-	listeners []core.LimitChangeListener
+	delegate core.Limit
+	sample   *measurements.ImmutableSampleWindow
 
 	mu sync.RWMutex
 }
@@ -81,7 +80,6 @@ func NewWindowedLimit(
 		minRTTThreshold: minRTTThreshold,
 		delegate:        delegate,
 		sample:          measurements.NewDefaultImmutableSampleWindow(),
-		listeners:       make([]core.LimitChangeListener, 0),
 	}
 	return l, nil
 
@@ -95,42 +93,50 @@ func (l *WindowedLimit) EstimatedLimit() int {
 }
 
 // NotifyOnChange will register a callback to receive notification whenever the limit is updated to a new value.
-func (l *WindowedLimit) NotifyOnChange(consumer core.LimitChangeListener) {
-	l.mu.Lock()
-	l.listeners = append(l.listeners, consumer)
-	l.delegate.NotifyOnChange(consumer)
-	l.mu.Unlock()
+func (l *WindowedLimit) NotifyOnChange(consumer core.LimitChangeListener) core.LimitChangeSubscription {
+	return l.delegate.NotifyOnChange(consumer)
 }
 
-// notifyListeners will call the callbacks on limit changes
-func (l *WindowedLimit) notifyListeners(newLimit int) {
-	for _, listener := range l.listeners {
-		listener(newLimit)
-	}
+// MarshalState returns a snapshot of the delegate's learned state, the delegate must be a StatefulLimit.
+func (l *WindowedLimit) MarshalState() ([]byte, error) {
+	return marshalDelegateState(l.delegate)
+}
+
+// RestoreState restores the delegate's learned state from a snapshot returned by MarshalState.
+func (l *WindowedLimit) RestoreState(data []byte) error {
+	return restoreDelegateState(l.delegate, data)
 }
 
 // OnSample the concurrency limit using a new rtt sample.
 func (l *WindowedLimit) OnSample(startTime int64, rtt int64, inFlight int, didDrop bool) {
-	l.mu.Lock()
-	defer l.mu.Unlock()
-
 	endTime := startTime + rtt
 	if rtt < l.minRTTThreshold {
 		return
 	}
 
+	l.mu.Lock()
+
 	if didDrop {
 		l.sample = l.sample.AddDroppedSample(-1, inFlight)
 	} else {
 		l.sample = l.sample.AddSample(-1, rtt, inFlight)
 	}
 
-	if endTime > l.nextUpdateTime && l.isWindowReady(rtt, inFlight) {
-		current := l.sample
-		l.sample = measurements.NewDefaultImmutableSampleWindow()
-		l.nextUpdateTime = endTime + minInt64(maxInt64(current.CandidateRTTNanoseconds()*2, l.minWindowTime), l.maxWindowTime)
-		l.delegate.OnSample(startTime, current.AverageRTTNanoseconds(), current.MaxInFlight(), didDrop)
+	if endTime <= l.nextUpdateTime || !l.isWindowReady(l.sample) {
+		l.mu.Unlock()
+		return
+	}
+	current := l.sample
+	l.sample = measurements.NewDefaultImmutableSampleWindow()
+	l.nextUpdateTime = endTime + minInt64(maxInt64(current.CandidateRTTNanoseconds()*2, l.minWindowTime), l.maxWindowTime)
+	l.mu.Unlock()
+
+	// the delegate notifies listeners, so it's sampled outside of the lock
+	if windowLimit, ok := l.delegate.(core.SampleWindowLimit); ok {
+		windowLimit.OnSampleWindow(startTime, current)
+		return
 	}
+	l.delegate.OnSample(startTime, current.AverageRTTNanoseconds(), current.MaxInFlight(), current.DidDrop())
 }
 
 func (l *WindowedLimit) String() string {
@@ -140,8 +146,11 @@ func (l *WindowedLimit) String() string {
 		" delegate=%v", l.minWindowTime, l.maxWindowTime, l.minRTTThreshold, l.windowSize, l.delegate)
 }
 
-func (l *WindowedLimit) isWindowReady(rtt int64, inFlight int) bool {
-	return rtt < int64(math.MaxInt64) && int32(inFlight) > l.windowSize
+// isWindowReady returns true once the window holds more than windowSize samples, dropped ones included, at least one
+// of them successful since windows of only dropped samples have no RTT to pass to the delegate.
+func (l *WindowedLimit) isWindowReady(sample *measurements.ImmutableSampleWindow) bool {
+	return sample.CandidateRTTNanoseconds() < int64(math.MaxInt64) &&
+		sample.SampleCount()+sample.DropCount() > int(l.windowSize)
 }
 
 func minInt64(a, b int64) int64 {
@@ -0,0 +1,256 @@
+package limiter
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/platinummonkey/go-concurrency-limits/core"
+	"github.com/platinummonkey/go-concurrency-limits/limit"
+)
+
+// throttleBucket holds the request and accept counts observed during a single slice of the sliding window.
+type throttleBucket struct {
+	start    int64
+	requests float64
+	accepts  float64
+}
+
+// AdaptiveThrottlingListener wraps the delegate Listener to record whether the backend accepted the request.
+type AdaptiveThrottlingListener struct {
+	delegateListener core.Listener
+	limiter          *AdaptiveThrottlingLimiter
+	// bucketStart identifies the bucket the request was counted in
+	bucketStart int64
+}
+
+// OnSuccess is called as a notification that the operation succeeded and internally measured latency should be
+// used as an RTT sample.
+func (l *AdaptiveThrottlingListener) OnSuccess() {
+	l.limiter.recordAccept(l.bucketStart)
+	if l.delegateListener != nil {
+		l.delegateListener.OnSuccess()
+	}
+}
+
+// OnIgnore is called to indicate the operation failed before any meaningful RTT measurement could be made and
+// should be ignored to not introduce an artificially low RTT.
+func (l *AdaptiveThrottlingListener) OnIgnore() {
+	l.limiter.recordIgnore(l.bucketStart)
+	if l.delegateListener != nil {
+		l.delegateListener.OnIgnore()
+	}
+}
+
+// OnDropped is called to indicate the request failed and was dropped due to being rejected by an external limit or
+// hitting a timeout.  Loss based Limit implementations will likely do an aggressive reducing in limit when this
+// happens.
+func (l *AdaptiveThrottlingListener) OnDropped() {
+	// a backend rejection, the request was already counted and is simply not accepted.
+	if l.delegateListener != nil {
+		l.delegateListener.OnDropped()
+	}
+}
+
+// AdaptiveThrottlingLimiter implements the client side adaptive throttling algorithm described in the Google SRE book
+// (chapter "Handling Overload").  Each client tracks, over a sliding window, the number of requests attempted and the
+// number of requests accepted by the backend.  Once the backend starts rejecting requests new requests are rejected
+// locally with probability
+//
+//	max(0, (requests - K * accepts) / (requests + 1))
+//
+// Where K is the multiplier, typically 2.  Lowering K makes throttling more aggressive.  Requests rejected locally
+// are still counted as requests so the rejection probability keeps increasing while the backend remains overloaded.
+//
+// An optional delegate Limiter can be provided which will be consulted once a request has passed the throttle.
+type AdaptiveThrottlingLimiter struct {
+	delegate core.Limiter
+	k        float64
+	window   time.Duration
+	buckets  []throttleBucket
+	clock    limit.Clock
+	random   func() float64
+	logger   limit.Logger
+
+	mu sync.Mutex
+}
+
+// NewAdaptiveThrottlingLimiterWithDefaults will create a new AdaptiveThrottlingLimiter using K=2 over a two minute
+// window.
+func NewAdaptiveThrottlingLimiterWithDefaults(
+	delegate core.Limiter,
+	logger limit.Logger,
+) *AdaptiveThrottlingLimiter {
+	l, _ := NewAdaptiveThrottlingLimiter(delegate, 2, time.Minute*2, 120, nil, logger)
+	return l
+}
+
+// NewAdaptiveThrottlingLimiter will create a new AdaptiveThrottlingLimiter.
+// @param delegate: optional Limiter consulted after the throttle, may be nil.
+// @param k: accepts multiplier, must be >= 1.
+// @param window: duration of the sliding window.
+// @param bucketCount: number of buckets the window is split into.
+// @param clock: source of the current time, defaults to limit.SystemClock.
+func NewAdaptiveThrottlingLimiter(
+	delegate core.Limiter,
+	k float64,
+	window time.Duration,
+	bucketCount int,
+	clock limit.Clock,
+	logger limit.Logger,
+) (*AdaptiveThrottlingLimiter, error) {
+	if k < 1 {
+		return nil, fmt.Errorf("k must be >= 1")
+	}
+	if window <= 0 {
+		return nil, fmt.Errorf("window must be > 0")
+	}
+	if bucketCount <= 0 {
+		bucketCount = 10
+	}
+	if clock == nil {
+		clock = limit.SystemClock
+	}
+	if logger == nil {
+		logger = limit.NoopLimitLogger{}
+	}
+	return &AdaptiveThrottlingLimiter{
+		delegate: delegate,
+		k:        k,
+		window:   window,
+		buckets:  make([]throttleBucket, bucketCount),
+		clock:    clock,
+		random:   rand.Float64,
+		logger:   logger,
+	}, nil
+}
+
+// bucketWidth returns the duration of a single bucket in nanoseconds.
+func (l *AdaptiveThrottlingLimiter) bucketWidth() int64 {
+	width := l.window.Nanoseconds() / int64(len(l.buckets))
+	if width <= 0 {
+		width = 1
+	}
+	return width
+}
+
+// currentBucket returns the bucket for the given time, resetting it if it has expired.
+// note: not thread safe.
+func (l *AdaptiveThrottlingLimiter) currentBucket(now int64) *throttleBucket {
+	width := l.bucketWidth()
+	start := now - now%width
+	bucket := &l.buckets[(now/width)%int64(len(l.buckets))]
+	if bucket.start != start {
+		bucket.start = start
+		bucket.requests = 0
+		bucket.accepts = 0
+	}
+	return bucket
+}
+
+// bucketAt returns the bucket starting at the given time, or nil if it has since been recycled.
+// note: not thread safe.
+func (l *AdaptiveThrottlingLimiter) bucketAt(start int64) *throttleBucket {
+	bucket := &l.buckets[(start/l.bucketWidth())%int64(len(l.buckets))]
+	if bucket.start != start {
+		return nil
+	}
+	return bucket
+}
+
+// totals returns the request and accept counts within the window.
+// note: not thread safe.
+func (l *AdaptiveThrottlingLimiter) totals(now int64) (float64, float64) {
+	requests := 0.0
+	accepts := 0.0
+	oldest := now - l.window.Nanoseconds()
+	for _, b := range l.buckets {
+		if b.start > oldest {
+			requests += b.requests
+			accepts += b.accepts
+		}
+	}
+	return requests, accepts
+}
+
+func (l *AdaptiveThrottlingLimiter) rejectProbability(requests, accepts float64) float64 {
+	return math.Max(0, (requests-l.k*accepts)/(requests+1))
+}
+
+// recordAccept counts an accept in the bucket the request was counted in, if it is still within the window.
+func (l *AdaptiveThrottlingLimiter) recordAccept(bucketStart int64) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if bucket := l.bucketAt(bucketStart); bucket != nil {
+		bucket.accepts++
+	}
+}
+
+// recordIgnore uncounts a request from the bucket it was counted in, if it is still within the window.
+func (l *AdaptiveThrottlingLimiter) recordIgnore(bucketStart int64) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if bucket := l.bucketAt(bucketStart); bucket != nil && bucket.requests > 0 {
+		bucket.requests--
+	}
+}
+
+// Acquire a token from the limiter.  Returns an Optional.empty() if the limit has been exceeded.
+// If acquired the caller must call one of the Listener methods when the operation has been completed to release
+// the count.
+//
+// context Context for the request. The context is used by advanced strategies such as LookupPartitionStrategy.
+func (l *AdaptiveThrottlingLimiter) Acquire(ctx context.Context) (core.Listener, bool) {
+	l.mu.Lock()
+	now := l.clock()
+	requests, accepts := l.totals(now)
+	p := l.rejectProbability(requests, accepts)
+	bucket := l.currentBucket(now)
+	bucket.requests++
+	bucketStart := bucket.start
+	reject := p > 0 && l.random() < p
+	l.mu.Unlock()
+
+	if reject {
+		if l.logger.IsDebugEnabled() {
+			l.logger.Debugf("throttled locally requests=%0.0f accepts=%0.0f probability=%0.4f",
+				requests, accepts, p)
+		}
+		return nil, false
+	}
+
+	var delegateListener core.Listener
+	if l.delegate != nil {
+		listener, ok := l.delegate.Acquire(ctx)
+		if !ok || listener == nil {
+			// never reached the backend, don't count it towards the backend's acceptance rate
+			l.recordIgnore(bucketStart)
+			return nil, false
+		}
+		delegateListener = listener
+	}
+	return &AdaptiveThrottlingListener{
+		delegateListener: delegateListener,
+		limiter:          l,
+		bucketStart:      bucketStart,
+	}, true
+}
+
+// RejectProbability returns the current probability that a new request is rejected locally.
+func (l *AdaptiveThrottlingLimiter) RejectProbability() float64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	requests, accepts := l.totals(l.clock())
+	return l.rejectProbability(requests, accepts)
+}
+
+func (l *AdaptiveThrottlingLimiter) String() string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	requests, accepts := l.totals(l.clock())
+	return fmt.Sprintf("AdaptiveThrottlingLimiter{k=%0.2f, requests=%0.0f, accepts=%0.0f, delegate=%v}",
+		l.k, requests, accepts, l.delegate)
+}
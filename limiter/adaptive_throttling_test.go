@@ -0,0 +1,142 @@
+package limiter
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/platinummonkey/go-concurrency-limits/limit"
+	"github.com/platinummonkey/go-concurrency-limits/strategy"
+)
+
+func TestAdaptiveThrottlingLimiter(t *testing.T) {
+	t.Parallel()
+
+	t.Run("InvalidArguments", func(t2 *testing.T) {
+		t2.Parallel()
+		asrt := assert.New(t2)
+		_, err := NewAdaptiveThrottlingLimiter(nil, 0.5, time.Minute, 10, nil, nil)
+		asrt.Error(err)
+		_, err = NewAdaptiveThrottlingLimiter(nil, 2, 0, 10, nil, nil)
+		asrt.Error(err)
+	})
+
+	t.Run("AcceptsWhileHealthy", func(t2 *testing.T) {
+		t2.Parallel()
+		asrt := assert.New(t2)
+		l := NewAdaptiveThrottlingLimiterWithDefaults(nil, nil)
+		for i := 0; i < 100; i++ {
+			listener, ok := l.Acquire(context.Background())
+			asrt.True(ok)
+			listener.OnSuccess()
+		}
+		asrt.Equal(0.0, l.RejectProbability())
+		asrt.Equal("AdaptiveThrottlingLimiter{k=2.00, requests=100, accepts=100, delegate=<nil>}", l.String())
+	})
+
+	t.Run("ThrottlesOnBackendRejections", func(t2 *testing.T) {
+		t2.Parallel()
+		asrt := assert.New(t2)
+		now := int64(0)
+		l, _ := NewAdaptiveThrottlingLimiter(nil, 2, time.Minute, 10, func() int64 { return now }, nil)
+		l.random = func() float64 { return 0.5 }
+
+		for i := 0; i < 10; i++ {
+			listener, ok := l.Acquire(context.Background())
+			asrt.True(ok)
+			listener.OnSuccess()
+		}
+		// the backend rejects everything, 20 requests are allowed before K * accepts is exceeded
+		for i := 0; i < 10; i++ {
+			listener, ok := l.Acquire(context.Background())
+			asrt.True(ok)
+			listener.OnDropped()
+		}
+		asrt.Equal(0.0, l.RejectProbability())
+		rejected := 0
+		for i := 0; i < 100; i++ {
+			listener, ok := l.Acquire(context.Background())
+			if !ok {
+				rejected++
+				continue
+			}
+			listener.OnDropped()
+		}
+		asrt.True(rejected > 50, "expected most requests to be throttled locally, got %d", rejected)
+		asrt.InDelta((120.0-20.0)/121.0, l.RejectProbability(), 0.0001)
+
+		// the window expires and the throttle resets
+		now += time.Minute.Nanoseconds()
+		asrt.Equal(0.0, l.RejectProbability())
+		_, ok := l.Acquire(context.Background())
+		asrt.True(ok)
+	})
+
+	t.Run("IgnoreIsNeutral", func(t2 *testing.T) {
+		t2.Parallel()
+		asrt := assert.New(t2)
+		now := int64(0)
+		l, _ := NewAdaptiveThrottlingLimiter(nil, 1, time.Minute, 10, func() int64 { return now }, nil)
+		l.random = func() float64 { return 0 }
+		for i := 0; i < 10; i++ {
+			listener, ok := l.Acquire(context.Background())
+			asrt.True(ok)
+			listener.OnIgnore()
+		}
+		asrt.Equal(0.0, l.RejectProbability())
+	})
+
+	t.Run("ReleaseCountedInRequestBucket", func(t2 *testing.T) {
+		t2.Parallel()
+		asrt := assert.New(t2)
+		now := int64(0)
+		l, _ := NewAdaptiveThrottlingLimiter(nil, 1, time.Minute, 10, func() int64 { return now }, nil)
+		l.random = func() float64 { return 1 }
+		ignored, ok := l.Acquire(context.Background())
+		asrt.True(ok)
+		accepted, ok := l.Acquire(context.Background())
+		asrt.True(ok)
+
+		// the requests complete in the next bucket
+		now += (7 * time.Second).Nanoseconds()
+		ignored.OnIgnore()
+		accepted.OnSuccess()
+		asrt.Equal(0.0, l.RejectProbability())
+		asrt.Equal("AdaptiveThrottlingLimiter{k=1.00, requests=1, accepts=1, delegate=<nil>}", l.String())
+
+		// requests completing after their bucket left the window are not counted
+		listener, ok := l.Acquire(context.Background())
+		asrt.True(ok)
+		now += time.Minute.Nanoseconds()
+		listener.OnSuccess()
+		asrt.Equal("AdaptiveThrottlingLimiter{k=1.00, requests=0, accepts=0, delegate=<nil>}", l.String())
+	})
+
+	t.Run("Delegate", func(t2 *testing.T) {
+		t2.Parallel()
+		asrt := assert.New(t2)
+		delegate, err := NewDefaultLimiter(
+			limit.NewFixedLimit("test", 1),
+			defaultMinWindowTime,
+			defaultMaxWindowTime,
+			defaultMinRTTThreshold,
+			defaultWindowSize,
+			strategy.NewSimpleStrategy(1),
+			limit.NoopLimitLogger{},
+		)
+		asrt.NoError(err)
+		l := NewAdaptiveThrottlingLimiterWithDefaults(delegate, limit.NoopLimitLogger{})
+		listener, ok := l.Acquire(context.Background())
+		asrt.True(ok)
+		// the delegate limit is reached
+		_, ok = l.Acquire(context.Background())
+		asrt.False(ok)
+		listener.OnSuccess()
+		listener, ok = l.Acquire(context.Background())
+		asrt.True(ok)
+		listener.OnIgnore()
+		asrt.Equal(0.0, l.RejectProbability())
+	})
+}
@@ -6,7 +6,6 @@ import (
 	"math"
 	"sync"
 	"sync/atomic"
-	"time"
 
 	"github.com/platinummonkey/go-concurrency-limits/core"
 	"github.com/platinummonkey/go-concurrency-limits/limit"
@@ -20,10 +19,14 @@ const (
 	defaultWindowSize      = int(100)   // Minimum observed samples to filter out sample windows with not enough significant samples
 )
 
+// systemClock is the default clock, referenced here as the constructors' limit parameter shadows the limit package.
+var systemClock = limit.SystemClock
+
 // DefaultListener for
 type DefaultListener struct {
 	currentMaxInFlight int64
 	inFlight           *int64
+	weight             int64
 	token              core.StrategyToken
 	startTime          int64
 	minRTTThreshold    int64
@@ -32,16 +35,17 @@ type DefaultListener struct {
 }
 
 // OnSuccess is called as a notification that the operation succeeded and internally measured latency should be
-// used as an RTT sample.
+// used as an RTT sample.  The RTT of a weighted request is normalized by its weight.
 func (l *DefaultListener) OnSuccess() {
-	atomic.AddInt64(l.inFlight, -1)
+	atomic.AddInt64(l.inFlight, -l.units())
 	l.token.Release()
-	endTime := time.Now().UnixNano()
+	endTime := l.limiter.clock()
 	rtt := endTime - l.startTime
 
 	if rtt < l.minRTTThreshold {
 		return
 	}
+	rtt /= l.units()
 	_, current := l.limiter.updateAndGetSample(
 		func(window measurements.ImmutableSampleWindow) measurements.ImmutableSampleWindow {
 			return *(window.AddSample(-1, rtt, int(l.currentMaxInFlight)))
@@ -71,12 +75,16 @@ func (l *DefaultListener) OnSuccess() {
 					minVal = minWindowTime
 				}
 				l.limiter.nextUpdateTime = endTime + minVal
-				l.limiter.limit.OnSample(
-					0,
-					current.CandidateRTTNanoseconds(),
-					current.MaxInFlight(),
-					current.DidDrop(),
-				)
+				if windowLimit, ok := l.limiter.limit.(core.SampleWindowLimit); ok {
+					windowLimit.OnSampleWindow(0, &current)
+				} else {
+					l.limiter.limit.OnSample(
+						0,
+						current.CandidateRTTNanoseconds(),
+						current.MaxInFlight(),
+						current.DidDrop(),
+					)
+				}
 				l.limiter.strategy.SetLimit(l.limiter.limit.EstimatedLimit())
 			}
 		}
@@ -87,7 +95,7 @@ func (l *DefaultListener) OnSuccess() {
 // OnIgnore is called to indicate the operation failed before any meaningful RTT measurement could be made and
 // should be ignored to not introduce an artificially low RTT.
 func (l *DefaultListener) OnIgnore() {
-	atomic.AddInt64(l.inFlight, -1)
+	atomic.AddInt64(l.inFlight, -l.units())
 	l.token.Release()
 }
 
@@ -95,13 +103,21 @@ func (l *DefaultListener) OnIgnore() {
 // hitting a timeout.  Loss based Limit implementations will likely do an aggressive reducing in limit when this
 // happens.
 func (l *DefaultListener) OnDropped() {
-	atomic.AddInt64(l.inFlight, -1)
+	atomic.AddInt64(l.inFlight, -l.units())
 	l.token.Release()
 	l.limiter.updateAndGetSample(func(window measurements.ImmutableSampleWindow) measurements.ImmutableSampleWindow {
 		return *(window.AddDroppedSample(-1, int(l.currentMaxInFlight)))
 	})
 }
 
+// units returns the number of limit units held by the listener.
+func (l *DefaultListener) units() int64 {
+	if l.weight < 1 {
+		return 1
+	}
+	return l.weight
+}
+
 // DefaultLimiter is a Limiter that combines a plugable limit algorithm and enforcement strategy to enforce concurrency
 // limits to a fixed resource.
 type DefaultLimiter struct {
@@ -111,6 +127,7 @@ type DefaultLimiter struct {
 	maxWindowTime   int64
 	windowSize      int
 	minRTTThreshold int64
+	clock           limit.Clock
 	logger          limit.Logger
 
 	sample         *measurements.ImmutableSampleWindow
@@ -145,6 +162,22 @@ func NewDefaultLimiter(
 	windowSize int,
 	strategy core.Strategy,
 	logger limit.Logger,
+) (*DefaultLimiter, error) {
+	return NewDefaultLimiterWithClock(limit, minWindowTime, maxWindowTime, minRTTThreshold, windowSize, strategy, nil,
+		logger)
+}
+
+// NewDefaultLimiterWithClock creates a new DefaultLimiter measuring the RTT with the given clock, defaults to the
+// system clock.  A virtual clock allows the limiter to be driven deterministically, i.e. by a simulation.
+func NewDefaultLimiterWithClock(
+	limit core.Limit,
+	minWindowTime int64,
+	maxWindowTime int64,
+	minRTTThreshold int64,
+	windowSize int,
+	strategy core.Strategy,
+	clock limit.Clock,
+	logger limit.Logger,
 ) (*DefaultLimiter, error) {
 	if limit == nil {
 		return nil, fmt.Errorf("limit must be provided")
@@ -165,6 +198,10 @@ func NewDefaultLimiter(
 		return nil, fmt.Errorf("windowSize must be >= 10")
 	}
 
+	if clock == nil {
+		clock = systemClock
+	}
+
 	inFlight := int64(0)
 
 	strategy.SetLimit(limit.EstimatedLimit())
@@ -175,6 +212,7 @@ func NewDefaultLimiter(
 		maxWindowTime:   maxWindowTime,
 		minRTTThreshold: minRTTThreshold,
 		windowSize:      windowSize,
+		clock:           clock,
 		inFlight:        &inFlight,
 		sample:          measurements.NewDefaultImmutableSampleWindow(),
 		logger:          logger,
@@ -183,24 +221,45 @@ func NewDefaultLimiter(
 
 // Acquire a token from the limiter.  Returns an Optional.empty() if the limit has been exceeded.
 // If acquired the caller must call one of the Listener methods when the operation has been completed to release
-// the count.
+// the count.  The request is weighted by core.WeightFromContext.
 //
 // context Context for the request. The context is used by advanced strategies such as LookupPartitionStrategy.
 func (l *DefaultLimiter) Acquire(ctx context.Context) (core.Listener, bool) {
+	return l.AcquireWeighted(ctx, core.WeightFromContext(ctx))
+}
+
+// AcquireWeighted acquires weight units of the limit for an expensive request, i.e. a batch RPC.  The RTT sample
+// recorded on success is divided by the weight.  Strategies that don't implement core.WeightedStrategy always acquire
+// a single unit, in which case the weight is ignored.
+//
+// context Context for the request. The context is used by advanced strategies such as LookupPartitionStrategy.
+// weight The number of units to acquire, values less than 1 are treated as 1.
+func (l *DefaultLimiter) AcquireWeighted(ctx context.Context, weight int) (core.Listener, bool) {
+	if weight < 1 {
+		weight = 1
+	}
 	l.mu.Lock()
 	defer l.mu.Unlock()
 
 	// Did we exceed the limit?
-	token, ok := l.strategy.TryAcquire(ctx)
+	var token core.StrategyToken
+	var ok bool
+	if weighted, isWeighted := l.strategy.(core.WeightedStrategy); isWeighted {
+		token, ok = weighted.TryAcquireWeighted(ctx, weight)
+	} else {
+		weight = 1
+		token, ok = l.strategy.TryAcquire(ctx)
+	}
 	if !ok || token == nil {
 		return nil, false
 	}
 
-	startTime := time.Now().UnixNano()
-	currentMaxInFlight := atomic.AddInt64(l.inFlight, 1)
+	startTime := l.clock()
+	currentMaxInFlight := atomic.AddInt64(l.inFlight, int64(weight))
 	return &DefaultListener{
 		currentMaxInFlight: currentMaxInFlight,
 		inFlight:           l.inFlight,
+		weight:             int64(weight),
 		token:              token,
 		startTime:          startTime,
 		minRTTThreshold:    l.minRTTThreshold,
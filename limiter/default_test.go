@@ -125,4 +125,126 @@ func TestDefaultLimiter(t *testing.T) {
 		asrt.NotNil(listener)
 		listener.OnSuccess()
 	})
+	t.Run("NewDefaultLimiterWithClock", func(t2 *testing.T) {
+		t2.Parallel()
+		asrt := assert.New(t2)
+		now := int64(1e9)
+		history, err := limit.NewHistoryLimit("test", limit.NewFixedLimit("test", 100), 10, nil)
+		asrt.NoError(err)
+		l, err := NewDefaultLimiterWithClock(
+			history,
+			1,
+			1,
+			0,
+			10,
+			strategy.NewSimpleStrategy(100),
+			func() int64 { return now },
+			limit.NoopLimitLogger{},
+		)
+		asrt.NoError(err)
+
+		// the rtt is measured with the given clock
+		for i := 0; i < 11; i++ {
+			listener, ok := l.Acquire(context.Background())
+			asrt.True(ok)
+			now += (5 * time.Millisecond).Nanoseconds()
+			listener.OnSuccess()
+		}
+		samples := history.History()
+		asrt.Len(samples, 1)
+		asrt.Equal((5 * time.Millisecond).Nanoseconds(), samples[0].RTT)
+	})
+
+	t.Run("AcquireWeighted", func(t2 *testing.T) {
+		t2.Parallel()
+		asrt := assert.New(t2)
+		now := int64(1e9)
+		history, err := limit.NewHistoryLimit("test", limit.NewFixedLimit("test", 10), 10, nil)
+		asrt.NoError(err)
+		strtgy := strategy.NewSimpleStrategy(10)
+		l, err := NewDefaultLimiterWithClock(
+			history,
+			1,
+			1,
+			0,
+			10,
+			strtgy,
+			func() int64 { return now },
+			limit.NoopLimitLogger{},
+		)
+		asrt.NoError(err)
+
+		listener, ok := l.AcquireWeighted(context.Background(), 6)
+		asrt.True(ok)
+		asrt.Equal(6, strtgy.GetBusyCount())
+		_, ok = l.AcquireWeighted(context.Background(), 5)
+		asrt.False(ok)
+		// the weight may also be carried by the context
+		ignored, ok := l.Acquire(core.WithWeight(context.Background(), 4))
+		asrt.True(ok)
+		asrt.Equal(10, strtgy.GetBusyCount())
+		ignored.OnIgnore()
+		listener.OnDropped()
+		asrt.Equal(0, strtgy.GetBusyCount())
+		asrt.Equal(int64(0), *l.inFlight)
+
+		// the rtt is normalized by the weight
+		for i := 0; i < 11; i++ {
+			listener, ok := l.AcquireWeighted(context.Background(), 4)
+			asrt.True(ok)
+			now += (20 * time.Millisecond).Nanoseconds()
+			listener.OnSuccess()
+		}
+		samples := history.History()
+		asrt.Len(samples, 1)
+		asrt.Equal((5 * time.Millisecond).Nanoseconds(), samples[0].RTT)
+		asrt.Equal(int64(0), *l.inFlight)
+	})
+
+	t.Run("SampleWindowLimit", func(t2 *testing.T) {
+		t2.Parallel()
+		asrt := assert.New(t2)
+
+		// run batches of up to 20 concurrent requests dropping every dropEvery'th request
+		run := func(dropEvery int) int {
+			now := int64(1e9)
+			errorRate, err := limit.NewErrorRateLimit("test", 20, 1, 1000, 20, 0.05, nil, nil, nil)
+			asrt.NoError(err)
+			l, err := NewDefaultLimiterWithClock(
+				errorRate,
+				1,
+				1,
+				0,
+				10,
+				strategy.NewSimpleStrategy(20),
+				func() int64 { return now },
+				limit.NoopLimitLogger{},
+			)
+			asrt.NoError(err)
+			request := 0
+			for batch := 0; batch < 30; batch++ {
+				listeners := make([]core.Listener, 0, 20)
+				for i := 0; i < 20; i++ {
+					if listener, ok := l.Acquire(context.Background()); ok {
+						listeners = append(listeners, listener)
+					}
+				}
+				for _, listener := range listeners {
+					now += time.Millisecond.Nanoseconds()
+					request++
+					if request%dropEvery == 0 {
+						listener.OnDropped()
+					} else {
+						listener.OnSuccess()
+					}
+				}
+			}
+			return l.EstimatedLimit()
+		}
+
+		// a steady 2% error rate is within the threshold, even though most windows contain a drop
+		asrt.True(run(50) > 20, "expected the limit to grow under a tolerable error rate")
+		// a 20% error rate is not
+		asrt.True(run(5) < 20, "expected the limit to shrink under a high error rate")
+	})
 }
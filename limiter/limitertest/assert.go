@@ -0,0 +1,81 @@
+package limitertest
+
+import (
+	"fmt"
+	"testing"
+)
+
+// Counts are the number of tokens acquired and rejected and how the tokens were released.
+type Counts struct {
+	Acquired  int
+	Rejected  int
+	Successes int
+	Ignores   int
+	Drops     int
+}
+
+// Released returns the number of releases, a token released more than once is counted for each release.
+func (c Counts) Released() int {
+	return c.Successes + c.Ignores + c.Drops
+}
+
+// InFlight returns the number of tokens acquired but not released.
+func (c Counts) InFlight() int {
+	return c.Acquired - c.Released()
+}
+
+func (c Counts) String() string {
+	return fmt.Sprintf("acquired=%d, rejected=%d, successes=%d, ignores=%d, drops=%d",
+		c.Acquired, c.Rejected, c.Successes, c.Ignores, c.Drops)
+}
+
+// Recorder is implemented by FakeLimiter and RecordingListener.
+type Recorder interface {
+	Counts() Counts
+}
+
+// AssertCounts fails the test unless the recorder's counts match the expected counts.
+func AssertCounts(t testing.TB, recorder Recorder, expected Counts) bool {
+	t.Helper()
+	if actual := recorder.Counts(); actual != expected {
+		t.Errorf("counts mismatch\nexpected: %v\nactual:   %v", expected, actual)
+		return false
+	}
+	return true
+}
+
+// AssertAllReleased fails the test unless every token acquired from the limiter was released exactly once.
+func AssertAllReleased(t testing.TB, l *FakeLimiter) bool {
+	t.Helper()
+	ok := true
+	for i, listener := range l.Listeners() {
+		if released := listener.Counts().Released(); released != 1 {
+			t.Errorf("token %d released %d times, expected once", i, released)
+			ok = false
+		}
+	}
+	return ok
+}
+
+// AssertReleases fails the test unless the tokens acquired from the limiter, in order, were released exactly once as
+// expected.  ReleaseNone expects a token not released yet.
+func AssertReleases(t testing.TB, l *FakeLimiter, expected ...Release) bool {
+	t.Helper()
+	listeners := l.Listeners()
+	if len(listeners) != len(expected) {
+		t.Errorf("%d tokens acquired, expected %d", len(listeners), len(expected))
+		return false
+	}
+	ok := true
+	for i, listener := range listeners {
+		release, released := listener.Release(), listener.Counts().Released()
+		if release != expected[i] {
+			t.Errorf("token %d released as %v, expected %v", i, release, expected[i])
+			ok = false
+		} else if released > 1 {
+			t.Errorf("token %d released %d times, expected once", i, released)
+			ok = false
+		}
+	}
+	return ok
+}
@@ -0,0 +1,155 @@
+// Package limitertest provides utilities for testing code integrating a core.Limiter, i.e. handlers, middlewares and
+// the grpc interceptors.  FakeLimiter accepts or rejects requests as scripted by an AcquirePolicy and records how
+// every acquired token was released, which the assertion helpers then verify.
+//
+// Usage:
+//
+//	l := limitertest.NewFakeLimiter(limitertest.AcceptN(1))
+//	interceptor := grpc.UnaryServerInterceptor(grpc.WithLimiter(l))
+//	... call the interceptor twice, the handler failing ...
+//	limitertest.AssertCounts(t, l, limitertest.Counts{Acquired: 1, Rejected: 1, Drops: 1})
+//	limitertest.AssertReleases(t, l, limitertest.ReleaseDropped)
+package limitertest
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/platinummonkey/go-concurrency-limits/core"
+)
+
+// AcquirePolicy decides whether a call to Acquire is accepted, attempt counts the calls starting at 1.
+type AcquirePolicy func(ctx context.Context, attempt int) bool
+
+// AcceptAll accepts every call.
+func AcceptAll() AcquirePolicy {
+	return func(ctx context.Context, attempt int) bool {
+		return true
+	}
+}
+
+// RejectAll rejects every call.
+func RejectAll() AcquirePolicy {
+	return func(ctx context.Context, attempt int) bool {
+		return false
+	}
+}
+
+// AcceptN accepts the first n calls and rejects the following.
+func AcceptN(n int) AcquirePolicy {
+	return func(ctx context.Context, attempt int) bool {
+		return attempt <= n
+	}
+}
+
+// RejectIf rejects the calls matching the predicate, i.e. based on a value of the request's context.
+func RejectIf(predicate func(ctx context.Context) bool) AcquirePolicy {
+	return func(ctx context.Context, attempt int) bool {
+		return !predicate(ctx)
+	}
+}
+
+// Script accepts or rejects the calls following the decisions in order, rejecting once the decisions are exhausted.
+func Script(decisions ...bool) AcquirePolicy {
+	return func(ctx context.Context, attempt int) bool {
+		return attempt <= len(decisions) && decisions[attempt-1]
+	}
+}
+
+// FakeLimiter implements core.Limiter, accepting calls as decided by its AcquirePolicy.  Accepted calls return a
+// RecordingListener so tests can assert how the token was released.  When a delegate is set, calls accepted by the
+// policy are passed on to the delegate, allowing rejections to be forced on a real limiter.
+type FakeLimiter struct {
+	policy   AcquirePolicy
+	delegate core.Limiter
+
+	attempts  int
+	rejected  int
+	listeners []*RecordingListener
+	mu        sync.Mutex
+}
+
+// NewFakeLimiter will create a new FakeLimiter.  The policy defaults to AcceptAll when nil.
+func NewFakeLimiter(policy AcquirePolicy) *FakeLimiter {
+	return NewFakeLimiterWithDelegate(nil, policy)
+}
+
+// NewFakeLimiterWithDelegate will create a new FakeLimiter recording the calls to the delegate.
+// @param delegate: The limiter acquiring the calls accepted by the policy, nil to accept them.
+// @param policy: The policy deciding whether calls are accepted, defaults to AcceptAll when nil.
+func NewFakeLimiterWithDelegate(delegate core.Limiter, policy AcquirePolicy) *FakeLimiter {
+	if policy == nil {
+		policy = AcceptAll()
+	}
+	return &FakeLimiter{
+		policy:   policy,
+		delegate: delegate,
+	}
+}
+
+// SetPolicy replaces the policy for the following calls.
+func (l *FakeLimiter) SetPolicy(policy AcquirePolicy) {
+	if policy == nil {
+		policy = AcceptAll()
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.policy = policy
+}
+
+// Acquire a token if accepted by the policy and the delegate, if any.
+func (l *FakeLimiter) Acquire(ctx context.Context) (core.Listener, bool) {
+	l.mu.Lock()
+	l.attempts++
+	attempt := l.attempts
+	policy := l.policy
+	l.mu.Unlock()
+
+	// the policy and delegate are called outside of the lock, they may inspect the limiter
+	ok := policy(ctx, attempt)
+	var delegate core.Listener
+	if ok && l.delegate != nil {
+		delegate, ok = l.delegate.Acquire(ctx)
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if !ok {
+		l.rejected++
+		return nil, false
+	}
+	listener := NewRecordingListener(delegate)
+	l.listeners = append(l.listeners, listener)
+	return listener, true
+}
+
+// Listeners returns the listeners of the accepted calls, in the order they were acquired.
+func (l *FakeLimiter) Listeners() []*RecordingListener {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	listeners := make([]*RecordingListener, len(l.listeners))
+	copy(listeners, l.listeners)
+	return listeners
+}
+
+// Counts returns the number of accepted and rejected calls and how the tokens were released.
+func (l *FakeLimiter) Counts() Counts {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	counts := Counts{
+		Acquired: len(l.listeners),
+		Rejected: l.rejected,
+	}
+	for _, listener := range l.listeners {
+		c := listener.Counts()
+		counts.Successes += c.Successes
+		counts.Ignores += c.Ignores
+		counts.Drops += c.Drops
+	}
+	return counts
+}
+
+func (l *FakeLimiter) String() string {
+	return fmt.Sprintf("FakeLimiter{%v}", l.Counts())
+}
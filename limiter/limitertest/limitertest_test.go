@@ -0,0 +1,213 @@
+package limitertest
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/platinummonkey/go-concurrency-limits/core"
+	"github.com/platinummonkey/go-concurrency-limits/limit"
+	"github.com/platinummonkey/go-concurrency-limits/limiter"
+	"github.com/platinummonkey/go-concurrency-limits/strategy"
+)
+
+type testContextKey struct{}
+
+// testingT captures the failures of the assertion helpers.
+type testingT struct {
+	testing.TB
+	errors []string
+}
+
+func (t *testingT) Helper() {}
+
+func (t *testingT) Errorf(format string, args ...interface{}) {
+	t.errors = append(t.errors, fmt.Sprintf(format, args...))
+}
+
+func acquire(l core.Limiter, n int) []bool {
+	results := make([]bool, n)
+	for i := range results {
+		_, results[i] = l.Acquire(context.Background())
+	}
+	return results
+}
+
+func TestAcquirePolicies(t *testing.T) {
+	t.Parallel()
+	asrt := assert.New(t)
+
+	asrt.Equal([]bool{true, true, true}, acquire(NewFakeLimiter(nil), 3))
+	asrt.Equal([]bool{true, true, true}, acquire(NewFakeLimiter(AcceptAll()), 3))
+	asrt.Equal([]bool{false, false}, acquire(NewFakeLimiter(RejectAll()), 2))
+	asrt.Equal([]bool{true, true, false, false}, acquire(NewFakeLimiter(AcceptN(2)), 4))
+	asrt.Equal([]bool{true, false, true, false}, acquire(NewFakeLimiter(Script(true, false, true)), 4))
+
+	l := NewFakeLimiter(RejectIf(func(ctx context.Context) bool {
+		return ctx.Value(testContextKey{}) == "batch"
+	}))
+	_, ok := l.Acquire(context.WithValue(context.Background(), testContextKey{}, "batch"))
+	asrt.False(ok)
+	_, ok = l.Acquire(context.WithValue(context.Background(), testContextKey{}, "live"))
+	asrt.True(ok)
+
+	l.SetPolicy(RejectAll())
+	_, ok = l.Acquire(context.Background())
+	asrt.False(ok)
+	l.SetPolicy(nil)
+	_, ok = l.Acquire(context.Background())
+	asrt.True(ok)
+	asrt.Equal(Counts{Acquired: 2, Rejected: 2}, l.Counts())
+}
+
+func TestFakeLimiter(t *testing.T) {
+	t.Parallel()
+
+	t.Run("RecordsReleases", func(t2 *testing.T) {
+		t2.Parallel()
+		asrt := assert.New(t2)
+		l := NewFakeLimiter(AcceptN(4))
+		listeners := make([]core.Listener, 0)
+		for i := 0; i < 5; i++ {
+			if listener, ok := l.Acquire(context.Background()); ok {
+				listeners = append(listeners, listener)
+			}
+		}
+		listeners[0].OnSuccess()
+		listeners[1].OnIgnore()
+		listeners[2].OnDropped()
+
+		asrt.Equal(Counts{Acquired: 4, Rejected: 1, Successes: 1, Ignores: 1, Drops: 1}, l.Counts())
+		asrt.Equal(1, l.Counts().InFlight())
+		asrt.Len(l.Listeners(), 4)
+		asrt.Equal(ReleaseNone, l.Listeners()[3].Release())
+		asrt.Equal("FakeLimiter{acquired=4, rejected=1, successes=1, ignores=1, drops=1}", l.String())
+	})
+
+	t.Run("Delegate", func(t2 *testing.T) {
+		t2.Parallel()
+		asrt := assert.New(t2)
+		delegate, err := limiter.NewDefaultLimiter(limit.NewFixedLimit("test", 2), 1e8, 1e9, 0, 10,
+			strategy.NewSimpleStrategy(2), nil)
+		asrt.NoError(err)
+		l := NewFakeLimiterWithDelegate(delegate, Script(true, false, true, true))
+
+		results := make([]bool, 0)
+		listeners := make([]core.Listener, 0)
+		for i := 0; i < 4; i++ {
+			listener, ok := l.Acquire(context.Background())
+			results = append(results, ok)
+			if ok {
+				listeners = append(listeners, listener)
+			}
+		}
+		// the second call is rejected by the policy, the fourth by the delegate's limit
+		asrt.Equal([]bool{true, false, true, false}, results)
+		asrt.Equal(Counts{Acquired: 2, Rejected: 2}, l.Counts())
+
+		listeners[0].OnSuccess()
+		_, ok := delegate.Acquire(context.Background())
+		asrt.True(ok, "release passed on to the delegate")
+	})
+
+	t.Run("Concurrent", func(t2 *testing.T) {
+		t2.Parallel()
+		asrt := assert.New(t2)
+		l := NewFakeLimiter(nil)
+		var wg sync.WaitGroup
+		for i := 0; i < 10; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for j := 0; j < 100; j++ {
+					listener, _ := l.Acquire(context.Background())
+					listener.OnSuccess()
+				}
+			}()
+		}
+		wg.Wait()
+		asrt.Equal(Counts{Acquired: 1000, Successes: 1000}, l.Counts())
+	})
+}
+
+func TestRecordingListener(t *testing.T) {
+	t.Parallel()
+	asrt := assert.New(t)
+
+	delegate := NewRecordingListener(nil)
+	listener := NewRecordingListener(delegate)
+	asrt.Equal(ReleaseNone, listener.Release())
+	listener.OnIgnore()
+	listener.OnSuccess()
+	asrt.Equal(ReleaseIgnore, listener.Release())
+	asrt.Equal(Counts{Acquired: 1, Successes: 1, Ignores: 1}, listener.Counts())
+	asrt.Equal(listener.Counts(), delegate.Counts())
+	asrt.Equal("RecordingListener{release=ignore, releases=2}", listener.String())
+	asrt.Equal("Release(7)", Release(7).String())
+}
+
+func TestAssertions(t *testing.T) {
+	t.Parallel()
+
+	newLimiter := func() *FakeLimiter {
+		l := NewFakeLimiter(AcceptN(3))
+		acquire(l, 4)
+		listeners := l.Listeners()
+		listeners[0].OnSuccess()
+		listeners[1].OnDropped()
+		listeners[1].OnDropped()
+		return l
+	}
+
+	t.Run("Pass", func(t2 *testing.T) {
+		t2.Parallel()
+		asrt := assert.New(t2)
+		l := NewFakeLimiter(AcceptN(2))
+		acquire(l, 3)
+		l.Listeners()[0].OnSuccess()
+		l.Listeners()[1].OnIgnore()
+
+		tt := &testingT{TB: t2}
+		asrt.True(AssertCounts(tt, l, Counts{Acquired: 2, Rejected: 1, Successes: 1, Ignores: 1}))
+		asrt.True(AssertAllReleased(tt, l))
+		asrt.True(AssertReleases(tt, l, ReleaseSuccess, ReleaseIgnore))
+		asrt.Empty(tt.errors)
+	})
+
+	t.Run("AssertCounts", func(t2 *testing.T) {
+		t2.Parallel()
+		asrt := assert.New(t2)
+		tt := &testingT{TB: t2}
+		asrt.False(AssertCounts(tt, newLimiter(), Counts{Acquired: 3, Rejected: 1, Successes: 1, Drops: 1}))
+		asrt.Equal([]string{"counts mismatch\n" +
+			"expected: acquired=3, rejected=1, successes=1, ignores=0, drops=1\n" +
+			"actual:   acquired=3, rejected=1, successes=1, ignores=0, drops=2"}, tt.errors)
+	})
+
+	t.Run("AssertAllReleased", func(t2 *testing.T) {
+		t2.Parallel()
+		asrt := assert.New(t2)
+		tt := &testingT{TB: t2}
+		asrt.False(AssertAllReleased(tt, newLimiter()))
+		asrt.Equal([]string{
+			"token 1 released 2 times, expected once",
+			"token 2 released 0 times, expected once",
+		}, tt.errors)
+	})
+
+	t.Run("AssertReleases", func(t2 *testing.T) {
+		t2.Parallel()
+		asrt := assert.New(t2)
+		tt := &testingT{TB: t2}
+		asrt.False(AssertReleases(tt, newLimiter(), ReleaseSuccess))
+		asrt.False(AssertReleases(tt, newLimiter(), ReleaseIgnore, ReleaseDropped, ReleaseNone))
+		asrt.Equal([]string{
+			"3 tokens acquired, expected 1",
+			"token 0 released as success, expected ignore",
+			"token 1 released 2 times, expected once",
+		}, tt.errors)
+	})
+}
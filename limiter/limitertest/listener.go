@@ -0,0 +1,111 @@
+package limitertest
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/platinummonkey/go-concurrency-limits/core"
+)
+
+// Release is how a token was released.
+type Release int
+
+const (
+	// ReleaseNone means the token was not released.
+	ReleaseNone Release = iota
+	// ReleaseSuccess means the token was released with OnSuccess.
+	ReleaseSuccess
+	// ReleaseIgnore means the token was released with OnIgnore.
+	ReleaseIgnore
+	// ReleaseDropped means the token was released with OnDropped.
+	ReleaseDropped
+)
+
+func (r Release) String() string {
+	switch r {
+	case ReleaseNone:
+		return "none"
+	case ReleaseSuccess:
+		return "success"
+	case ReleaseIgnore:
+		return "ignore"
+	case ReleaseDropped:
+		return "dropped"
+	}
+	return fmt.Sprintf("Release(%d)", int(r))
+}
+
+// RecordingListener implements core.Listener counting the calls to OnSuccess, OnIgnore and OnDropped before passing
+// them on to the delegate, if any.
+type RecordingListener struct {
+	delegate core.Listener
+	first    Release
+	counts   Counts
+	mu       sync.Mutex
+}
+
+// NewRecordingListener will create a new RecordingListener, the delegate may be nil.
+func NewRecordingListener(delegate core.Listener) *RecordingListener {
+	return &RecordingListener{
+		delegate: delegate,
+		counts:   Counts{Acquired: 1},
+	}
+}
+
+// OnSuccess records the release and calls the delegate.
+func (l *RecordingListener) OnSuccess() {
+	l.record(ReleaseSuccess)
+	if l.delegate != nil {
+		l.delegate.OnSuccess()
+	}
+}
+
+// OnIgnore records the release and calls the delegate.
+func (l *RecordingListener) OnIgnore() {
+	l.record(ReleaseIgnore)
+	if l.delegate != nil {
+		l.delegate.OnIgnore()
+	}
+}
+
+// OnDropped records the release and calls the delegate.
+func (l *RecordingListener) OnDropped() {
+	l.record(ReleaseDropped)
+	if l.delegate != nil {
+		l.delegate.OnDropped()
+	}
+}
+
+func (l *RecordingListener) record(release Release) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.first == ReleaseNone {
+		l.first = release
+	}
+	switch release {
+	case ReleaseSuccess:
+		l.counts.Successes++
+	case ReleaseIgnore:
+		l.counts.Ignores++
+	case ReleaseDropped:
+		l.counts.Drops++
+	}
+}
+
+// Release returns how the token was first released, ReleaseNone if not released yet.
+func (l *RecordingListener) Release() Release {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.first
+}
+
+// Counts returns the number of calls to OnSuccess, OnIgnore and OnDropped.
+func (l *RecordingListener) Counts() Counts {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.counts
+}
+
+func (l *RecordingListener) String() string {
+	return fmt.Sprintf("RecordingListener{release=%v, releases=%d}", l.Release(), l.Counts().Released())
+}
@@ -0,0 +1,118 @@
+package limiter
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+
+	"github.com/platinummonkey/go-concurrency-limits/core"
+	"github.com/platinummonkey/go-concurrency-limits/limit"
+)
+
+// RecordingLimiter wraps a delegate Limiter and records the outcome of every request, whether it was rejected or
+// released through OnSuccess, OnIgnore or OnDropped, as limit.SampleRecord lines with the Outcome set.  Sharing the
+// writer of a limit.RecordingLimit interleaves the outcomes with the limit's samples in a single capture.
+type RecordingLimiter struct {
+	delegate core.Limiter
+	writer   *limit.SampleRecordWriter
+	clock    limit.Clock
+	inFlight int64
+}
+
+// NewRecordingLimiter will create a new RecordingLimiter.
+// @param delegate: The limiter to record.
+// @param writer: Destination of the records.
+// @param clock: Source of the record timestamps, defaults to limit.SystemClock.
+func NewRecordingLimiter(
+	delegate core.Limiter,
+	writer *limit.SampleRecordWriter,
+	clock limit.Clock,
+) (*RecordingLimiter, error) {
+	if delegate == nil {
+		return nil, fmt.Errorf("delegate must be specified")
+	}
+	if writer == nil {
+		return nil, fmt.Errorf("writer must be specified")
+	}
+	if clock == nil {
+		clock = limit.SystemClock
+	}
+	return &RecordingLimiter{
+		delegate: delegate,
+		writer:   writer,
+		clock:    clock,
+	}, nil
+}
+
+// Acquire a token from the delegate, recording the request as rejected if it was not acquired.
+func (l *RecordingLimiter) Acquire(ctx context.Context) (core.Listener, bool) {
+	startTime := l.clock()
+	listener, ok := l.delegate.Acquire(ctx)
+	if !ok {
+		l.record(startTime, int(atomic.LoadInt64(&l.inFlight)), limit.OutcomeRejected)
+		return listener, false
+	}
+	return &recordingListener{
+		delegate:  listener,
+		limiter:   l,
+		startTime: startTime,
+		inFlight:  int(atomic.AddInt64(&l.inFlight, 1)),
+	}, true
+}
+
+// record writes the outcome of a request that started at startTime.
+func (l *RecordingLimiter) record(startTime int64, inFlight int, outcome string) {
+	now := l.clock()
+	record := limit.SampleRecord{
+		Timestamp: now,
+		StartTime: startTime,
+		RTT:       now - startTime,
+		InFlight:  inFlight,
+		DidDrop:   outcome == limit.OutcomeDropped,
+		Outcome:   outcome,
+	}
+	if estimator, ok := l.delegate.(interface{ EstimatedLimit() int }); ok {
+		record.Limit = estimator.EstimatedLimit()
+	}
+	l.writer.WriteRecord(record)
+}
+
+// Err returns the first error writing the records, if any.
+func (l *RecordingLimiter) Err() error {
+	return l.writer.Err()
+}
+
+func (l *RecordingLimiter) String() string {
+	return fmt.Sprintf("RecordingLimiter{delegate=%v}", l.delegate)
+}
+
+// recordingListener releases the delegate's listener and records the outcome.
+type recordingListener struct {
+	delegate  core.Listener
+	limiter   *RecordingLimiter
+	startTime int64
+	inFlight  int
+}
+
+func (l *recordingListener) release(outcome string) {
+	atomic.AddInt64(&l.limiter.inFlight, -1)
+	l.limiter.record(l.startTime, l.inFlight, outcome)
+}
+
+// OnSuccess releases the delegate's listener and records the success.
+func (l *recordingListener) OnSuccess() {
+	l.delegate.OnSuccess()
+	l.release(limit.OutcomeSuccess)
+}
+
+// OnIgnore releases the delegate's listener and records the ignored request.
+func (l *recordingListener) OnIgnore() {
+	l.delegate.OnIgnore()
+	l.release(limit.OutcomeIgnore)
+}
+
+// OnDropped releases the delegate's listener and records the drop.
+func (l *recordingListener) OnDropped() {
+	l.delegate.OnDropped()
+	l.release(limit.OutcomeDropped)
+}
@@ -0,0 +1,84 @@
+package limiter
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/platinummonkey/go-concurrency-limits/limit"
+	"github.com/platinummonkey/go-concurrency-limits/strategy"
+)
+
+func TestRecordingLimiter(t *testing.T) {
+	t.Parallel()
+
+	t.Run("InvalidArguments", func(t2 *testing.T) {
+		t2.Parallel()
+		asrt := assert.New(t2)
+		_, err := NewRecordingLimiter(nil, limit.NewSampleRecordWriter(&bytes.Buffer{}), nil)
+		asrt.Error(err)
+		l, _ := NewDefaultLimiterWithDefaults("test", strategy.NewSimpleStrategy(10), limit.NoopLimitLogger{})
+		_, err = NewRecordingLimiter(l, nil, nil)
+		asrt.Error(err)
+	})
+
+	t.Run("RecordsOutcomes", func(t2 *testing.T) {
+		t2.Parallel()
+		asrt := assert.New(t2)
+		now := int64(0)
+		clock := func() int64 { return now }
+		var buf bytes.Buffer
+		recordingLimit, err := limit.NewRecordingLimit("test", limit.NewFixedLimit("test", 2), &buf, clock)
+		asrt.NoError(err)
+		delegate, err := NewDefaultLimiterWithClock(recordingLimit, 1, 1, 0, 10, strategy.NewSimpleStrategy(2), clock,
+			limit.NoopLimitLogger{})
+		asrt.NoError(err)
+		l, err := NewRecordingLimiter(delegate, recordingLimit.Writer(), clock)
+		asrt.NoError(err)
+		recordingLimit.OnSample(0, 10, 1, false)
+
+		first, ok := l.Acquire(context.Background())
+		asrt.True(ok)
+		second, ok := l.Acquire(context.Background())
+		asrt.True(ok)
+		_, ok = l.Acquire(context.Background())
+		asrt.False(ok)
+		now += time.Millisecond.Nanoseconds()
+		first.OnSuccess()
+		second.OnDropped()
+		third, ok := l.Acquire(context.Background())
+		asrt.True(ok)
+		third.OnIgnore()
+		asrt.NoError(l.Err())
+
+		var outcomes []limit.SampleRecord
+		samples := 0
+		r := limit.NewSampleRecordReader(&buf)
+		for {
+			record, err := r.Next()
+			if err == io.EOF {
+				break
+			}
+			asrt.NoError(err)
+			if record.Outcome == "" {
+				samples++
+				continue
+			}
+			outcomes = append(outcomes, record)
+		}
+		// the limit's samples are interleaved with the outcomes
+		asrt.Equal(1, samples)
+		asrt.Equal([]limit.SampleRecord{
+			{Timestamp: 0, StartTime: 0, RTT: 0, InFlight: 2, Limit: 2, Outcome: limit.OutcomeRejected},
+			{Timestamp: 1000000, StartTime: 0, RTT: 1000000, InFlight: 1, Limit: 2, Outcome: limit.OutcomeSuccess},
+			{Timestamp: 1000000, StartTime: 0, RTT: 1000000, InFlight: 2, DidDrop: true, Limit: 2,
+				Outcome: limit.OutcomeDropped},
+			{Timestamp: 1000000, StartTime: 1000000, RTT: 0, InFlight: 1, Limit: 2, Outcome: limit.OutcomeIgnore},
+		}, outcomes)
+		asrt.Contains(l.String(), "RecordingLimiter{delegate=DefaultLimiter{")
+	})
+}
@@ -14,6 +14,7 @@ type ImmutableSampleWindow struct {
 	sampleCount int
 	sum         int64
 	didDrop     bool
+	dropCount   int
 }
 
 // NewDefaultImmutableSampleWindow will create a new ImmutableSampleWindow with defaults
@@ -40,6 +41,10 @@ func NewImmutableSampleWindow(
 	if minRTT == 0 {
 		minRTT = math.MaxInt64
 	}
+	dropCount := 0
+	if didDrop {
+		dropCount = 1
+	}
 	return &ImmutableSampleWindow{
 		startTime:   startTime,
 		minRTT:      minRTT,
@@ -47,6 +52,7 @@ func NewImmutableSampleWindow(
 		maxInFlight: maxInFlight,
 		sampleCount: sampleCount,
 		didDrop:     didDrop,
+		dropCount:   dropCount,
 	}
 }
 
@@ -62,7 +68,15 @@ func (s *ImmutableSampleWindow) AddSample(startTime int64, rtt int64, maxInFligh
 	if startTime < 0 {
 		startTime = time.Now().UnixNano()
 	}
-	return NewImmutableSampleWindow(startTime, minRTT, s.sum+rtt, maxInFlight, s.sampleCount+1, s.didDrop)
+	return &ImmutableSampleWindow{
+		startTime:   startTime,
+		minRTT:      minRTT,
+		sum:         s.sum + rtt,
+		maxInFlight: maxInFlight,
+		sampleCount: s.sampleCount + 1,
+		didDrop:     s.didDrop,
+		dropCount:   s.dropCount,
+	}
 }
 
 // AddDroppedSample will create a new immutable sample that was dropped.
@@ -73,7 +87,15 @@ func (s *ImmutableSampleWindow) AddDroppedSample(startTime int64, maxInFlight in
 	if startTime < 0 {
 		startTime = time.Now().UnixNano()
 	}
-	return NewImmutableSampleWindow(startTime, s.minRTT, s.sum, maxInFlight, s.sampleCount, true)
+	return &ImmutableSampleWindow{
+		startTime:   startTime,
+		minRTT:      s.minRTT,
+		sum:         s.sum,
+		maxInFlight: maxInFlight,
+		sampleCount: s.sampleCount,
+		didDrop:     true,
+		dropCount:   s.dropCount + 1,
+	}
 }
 
 // StartTimeNanoseconds returns the epoch start time in nanoseconds.
@@ -109,6 +131,11 @@ func (s *ImmutableSampleWindow) DidDrop() bool {
 	return s.didDrop
 }
 
+// DropCount is the number of dropped samples in the sample window, these are not included in SampleCount.
+func (s *ImmutableSampleWindow) DropCount() int {
+	return s.dropCount
+}
+
 func (s *ImmutableSampleWindow) String() string {
 	return fmt.Sprintf(
 		"ImmutableSampleWindow{minRTT=%d, averageRTT=%d, maxInFlight=%d, sampleCount=%d, didDrop=%t}",
@@ -30,4 +30,11 @@ func TestImmutableSampleWindow(t *testing.T) {
 	// Adding a successful sample should not void the dropped marker on the window
 	w4 := w3.AddSample(10, 10, 5)
 	asrt.True(w4.DidDrop())
+
+	// Drops are counted separately from the samples
+	w5 := w4.AddDroppedSample(-10, 5)
+	asrt.Equal(0, w2.DropCount())
+	asrt.Equal(2, w5.DropCount())
+	asrt.Equal(2, w5.SampleCount())
+	asrt.Equal(1, NewImmutableSampleWindow(0, 0, 0, 0, 0, true).DropCount())
 }
@@ -0,0 +1,105 @@
+package measurements
+
+import (
+	"fmt"
+	"sync"
+)
+
+// WindowedFilterMeasurement tracks the best (minimum or maximum) value observed over the last `window` samples.
+// This is the windowed min/max filter used by BBR style algorithms to track the bottleneck bandwidth and the
+// propagation round trip time where older samples must eventually expire.
+type WindowedFilterMeasurement struct {
+	samples []float64
+	next    int
+	count   int
+	value   float64
+	better  func(a, b float64) bool
+	kind    string
+
+	mu sync.RWMutex
+}
+
+// NewWindowedMinimumMeasurement will create a new WindowedFilterMeasurement tracking the minimum of the last `window`
+// samples.
+func NewWindowedMinimumMeasurement(window int) *WindowedFilterMeasurement {
+	return newWindowedFilterMeasurement(window, "min", func(a, b float64) bool { return a < b })
+}
+
+// NewWindowedMaximumMeasurement will create a new WindowedFilterMeasurement tracking the maximum of the last `window`
+// samples.
+func NewWindowedMaximumMeasurement(window int) *WindowedFilterMeasurement {
+	return newWindowedFilterMeasurement(window, "max", func(a, b float64) bool { return a > b })
+}
+
+func newWindowedFilterMeasurement(window int, kind string, better func(a, b float64) bool) *WindowedFilterMeasurement {
+	if window < 1 {
+		window = 1
+	}
+	return &WindowedFilterMeasurement{
+		samples: make([]float64, window),
+		better:  better,
+		kind:    kind,
+	}
+}
+
+// Add a single sample and update the internal state.
+// returns true if the filtered value changed, also return the current value.
+func (m *WindowedFilterMeasurement) Add(value float64) (float64, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	oldValue := m.value
+	m.samples[m.next] = value
+	m.next = (m.next + 1) % len(m.samples)
+	if m.count < len(m.samples) {
+		m.count++
+	}
+	m.recompute()
+	return m.value, oldValue != m.value
+}
+
+func (m *WindowedFilterMeasurement) recompute() {
+	if m.count == 0 {
+		m.value = 0
+		return
+	}
+	best := m.samples[0]
+	for i := 1; i < m.count; i++ {
+		if m.better(m.samples[i], best) {
+			best = m.samples[i]
+		}
+	}
+	m.value = best
+}
+
+// Get the current value.
+func (m *WindowedFilterMeasurement) Get() float64 {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.value
+}
+
+// Reset the internal state as if no samples were ever added.
+func (m *WindowedFilterMeasurement) Reset() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.next = 0
+	m.count = 0
+	m.value = 0
+}
+
+// Update will update the value given an operation function, the operation is applied to every sample in the window.
+func (m *WindowedFilterMeasurement) Update(operation func(value float64) float64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i := 0; i < m.count; i++ {
+		m.samples[i] = operation(m.samples[i])
+	}
+	m.recompute()
+}
+
+func (m *WindowedFilterMeasurement) String() string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return fmt.Sprintf("WindowedFilterMeasurement{kind=%s, value=%0.5f, count=%d, window=%d}",
+		m.kind, m.value, m.count, len(m.samples))
+}
@@ -0,0 +1,58 @@
+package measurements
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWindowedFilterMeasurement(t *testing.T) {
+	t.Parallel()
+
+	t.Run("Minimum", func(t2 *testing.T) {
+		t2.Parallel()
+		asrt := assert.New(t2)
+		m := NewWindowedMinimumMeasurement(3)
+		asrt.Equal(0.0, m.Get())
+		v, changed := m.Add(5)
+		asrt.Equal(5.0, v)
+		asrt.True(changed)
+		m.Add(3)
+		m.Add(4)
+		asrt.Equal(3.0, m.Get())
+		// 5 expires, 3 remains
+		m.Add(6)
+		asrt.Equal(3.0, m.Get())
+		// 3 expires
+		v, changed = m.Add(7)
+		asrt.Equal(4.0, v)
+		asrt.True(changed)
+		asrt.Equal("WindowedFilterMeasurement{kind=min, value=4.00000, count=3, window=3}", m.String())
+	})
+
+	t.Run("Maximum", func(t2 *testing.T) {
+		t2.Parallel()
+		asrt := assert.New(t2)
+		m := NewWindowedMaximumMeasurement(2)
+		m.Add(5)
+		m.Add(3)
+		asrt.Equal(5.0, m.Get())
+		m.Add(1)
+		asrt.Equal(3.0, m.Get())
+	})
+
+	t.Run("ResetAndUpdate", func(t2 *testing.T) {
+		t2.Parallel()
+		asrt := assert.New(t2)
+		m := NewWindowedMaximumMeasurement(0)
+		m.Add(5)
+		m.Update(func(value float64) float64 {
+			return value * 2
+		})
+		asrt.Equal(10.0, m.Get())
+		m.Reset()
+		asrt.Equal(0.0, m.Get())
+		m.Add(2)
+		asrt.Equal(2.0, m.Get())
+	})
+}
@@ -0,0 +1,46 @@
+package simulation
+
+import (
+	"time"
+)
+
+// ArrivalProcess returns the client request rate, in requests per second, at the given time since the start of the
+// simulation.  Requests arrive as a Poisson process with that rate.
+type ArrivalProcess func(elapsed time.Duration) float64
+
+// ConstantArrivals returns a steady rate of requests per second.
+func ConstantArrivals(rps float64) ArrivalProcess {
+	return func(elapsed time.Duration) float64 {
+		return rps
+	}
+}
+
+// BurstArrivals returns baseRPS, raised to burstRPS for burstDuration at the start of every period.
+func BurstArrivals(baseRPS float64, burstRPS float64, period time.Duration, burstDuration time.Duration) ArrivalProcess {
+	return func(elapsed time.Duration) float64 {
+		if period > 0 && elapsed%period < burstDuration {
+			return burstRPS
+		}
+		return baseRPS
+	}
+}
+
+// RampArrivals returns a rate increasing linearly from startRPS to endRPS over duration, then held at endRPS.
+func RampArrivals(startRPS float64, endRPS float64, duration time.Duration) ArrivalProcess {
+	return func(elapsed time.Duration) float64 {
+		if duration <= 0 || elapsed >= duration {
+			return endRPS
+		}
+		return startRPS + (endRPS-startRPS)*float64(elapsed)/float64(duration)
+	}
+}
+
+// StepArrivals returns beforeRPS until at, and afterRPS from then on.
+func StepArrivals(beforeRPS float64, afterRPS float64, at time.Duration) ArrivalProcess {
+	return func(elapsed time.Duration) float64 {
+		if elapsed < at {
+			return beforeRPS
+		}
+		return afterRPS
+	}
+}
@@ -0,0 +1,35 @@
+package simulation
+
+import (
+	"time"
+)
+
+// VirtualClock is a manually advanced clock.  Its Now method can be passed wherever a limit.Clock is accepted so
+// limits and limiters measure time in the simulation rather than the wall clock.
+type VirtualClock struct {
+	now int64
+}
+
+// NewVirtualClock will create a new VirtualClock starting at the given epoch time in nanoseconds.
+func NewVirtualClock(start int64) *VirtualClock {
+	return &VirtualClock{now: start}
+}
+
+// Now returns the current virtual time in epoch nanoseconds.
+func (c *VirtualClock) Now() int64 {
+	return c.now
+}
+
+// Advance moves the clock forward by d, negative durations are ignored.
+func (c *VirtualClock) Advance(d time.Duration) {
+	if d > 0 {
+		c.now += d.Nanoseconds()
+	}
+}
+
+// set moves the clock to the given time, the clock never goes backwards.
+func (c *VirtualClock) set(now int64) {
+	if now > c.now {
+		c.now = now
+	}
+}
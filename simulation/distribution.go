@@ -0,0 +1,42 @@
+package simulation
+
+import (
+	"math"
+	"math/rand"
+	"time"
+)
+
+// Distribution samples a duration, for example the service time of a request.
+type Distribution func(r *rand.Rand) time.Duration
+
+// ConstantDistribution always returns d.
+func ConstantDistribution(d time.Duration) Distribution {
+	return func(r *rand.Rand) time.Duration {
+		return d
+	}
+}
+
+// UniformDistribution returns durations uniformly distributed in [min, max).
+func UniformDistribution(min time.Duration, max time.Duration) Distribution {
+	return func(r *rand.Rand) time.Duration {
+		if max <= min {
+			return min
+		}
+		return min + time.Duration(r.Int63n(int64(max-min)))
+	}
+}
+
+// ExponentialDistribution returns exponentially distributed durations with the given mean.
+func ExponentialDistribution(mean time.Duration) Distribution {
+	return func(r *rand.Rand) time.Duration {
+		return time.Duration(r.ExpFloat64() * float64(mean))
+	}
+}
+
+// LogNormalDistribution returns log-normally distributed durations with the given median, sigma controls the length
+// of the tail.  Service times often follow a log-normal distribution.
+func LogNormalDistribution(median time.Duration, sigma float64) Distribution {
+	return func(r *rand.Rand) time.Duration {
+		return time.Duration(float64(median) * math.Exp(r.NormFloat64()*sigma))
+	}
+}
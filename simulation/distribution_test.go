@@ -0,0 +1,68 @@
+package simulation
+
+import (
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDistribution(t *testing.T) {
+	t.Parallel()
+	asrt := assert.New(t)
+	r := rand.New(rand.NewSource(1))
+
+	asrt.Equal(time.Second, ConstantDistribution(time.Second)(r))
+	asrt.Equal(time.Second, UniformDistribution(time.Second, time.Second)(r))
+
+	mean := func(d Distribution) time.Duration {
+		var sum time.Duration
+		for i := 0; i < 10000; i++ {
+			v := d(r)
+			asrt.True(v >= 0)
+			sum += v
+		}
+		return sum / 10000
+	}
+	asrt.InDelta(float64(15*time.Millisecond), float64(mean(UniformDistribution(10*time.Millisecond, 20*time.Millisecond))),
+		float64(time.Millisecond))
+	asrt.InDelta(float64(10*time.Millisecond), float64(mean(ExponentialDistribution(10*time.Millisecond))),
+		float64(time.Millisecond))
+	// the mean of a log-normal distribution is median * e^(sigma^2 / 2)
+	asrt.InDelta(float64(11331*time.Microsecond), float64(mean(LogNormalDistribution(10*time.Millisecond, 0.5))),
+		float64(time.Millisecond))
+}
+
+func TestArrivalProcess(t *testing.T) {
+	t.Parallel()
+	asrt := assert.New(t)
+
+	asrt.Equal(100.0, ConstantArrivals(100)(time.Hour))
+
+	burst := BurstArrivals(100, 1000, 10*time.Second, time.Second)
+	asrt.Equal(1000.0, burst(0))
+	asrt.Equal(100.0, burst(time.Second))
+	asrt.Equal(1000.0, burst(20500*time.Millisecond))
+
+	ramp := RampArrivals(100, 200, 10*time.Second)
+	asrt.Equal(100.0, ramp(0))
+	asrt.Equal(150.0, ramp(5*time.Second))
+	asrt.Equal(200.0, ramp(time.Minute))
+
+	step := StepArrivals(100, 200, time.Second)
+	asrt.Equal(100.0, step(0))
+	asrt.Equal(200.0, step(time.Second))
+}
+
+func TestVirtualClock(t *testing.T) {
+	t.Parallel()
+	asrt := assert.New(t)
+	c := NewVirtualClock(100)
+	asrt.Equal(int64(100), c.Now())
+	c.Advance(time.Microsecond)
+	asrt.Equal(int64(1100), c.Now())
+	c.Advance(-time.Second)
+	c.set(50)
+	asrt.Equal(int64(1100), c.Now())
+}
@@ -0,0 +1,347 @@
+package simulation
+
+import (
+	"container/heap"
+	"context"
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+	"time"
+
+	"github.com/platinummonkey/go-concurrency-limits/core"
+	"github.com/platinummonkey/go-concurrency-limits/limit"
+)
+
+// idleArrivalCheck is how often the arrival rate is checked again while it's zero.
+const idleArrivalCheck = 10 * time.Millisecond
+
+// Server models the protected resource, a fixed pool of workers in front of a bounded FIFO queue.
+type Server struct {
+	// Workers is the number of requests served concurrently.
+	Workers int
+	// QueueSize is the number of requests waiting for a worker, requests arriving to a full queue are rejected by the
+	// server and reported to the limiter as dropped.
+	QueueSize int
+	// ServiceTime is the distribution of the time a worker spends on a request.
+	ServiceTime Distribution
+	// Timeout is how long clients wait for a response, requests taking longer are reported to the limiter as dropped
+	// while the server keeps working on them.  0 to wait forever.
+	Timeout time.Duration
+}
+
+// Config configures a Simulation.
+type Config struct {
+	// Duration is the simulated time.
+	Duration time.Duration
+	// Arrivals is the client request rate.
+	Arrivals ArrivalProcess
+	// Server is the protected resource.
+	Server Server
+	// Seed seeds the random sources, simulations with the same seed and config are identical.  Arrivals are drawn
+	// from their own source so every limiter sees the same requests.
+	Seed int64
+	// Start is the epoch time in nanoseconds the virtual clock starts at.
+	Start int64
+}
+
+// LimitPoint is a change of the limit during the simulation.
+type LimitPoint struct {
+	// Elapsed is the time since the start of the simulation.
+	Elapsed time.Duration
+	// Limit is the new limit.
+	Limit int
+}
+
+// Result reports the outcome of a simulation.
+type Result struct {
+	// Duration is the simulated time.
+	Duration time.Duration
+	// Requests is the number of requests issued by the clients.
+	Requests int
+	// Rejected is the number of requests rejected by the limiter.
+	Rejected int
+	// Succeeded is the number of requests served within the timeout.
+	Succeeded int
+	// ServerRejected is the number of requests admitted by the limiter but rejected by the server's full queue.
+	ServerRejected int
+	// TimedOut is the number of requests admitted by the limiter that exceeded the client timeout.
+	TimedOut int
+	// Timeline is the limit over time, only recorded when a limit is given to Run.
+	Timeline []LimitPoint
+
+	latencies []time.Duration
+}
+
+// Goodput returns the number of requests served successfully per second.
+func (r *Result) Goodput() float64 {
+	if r.Duration <= 0 {
+		return 0
+	}
+	return float64(r.Succeeded) / r.Duration.Seconds()
+}
+
+// RejectionRate returns the fraction of requests rejected by the limiter.
+func (r *Result) RejectionRate() float64 {
+	if r.Requests == 0 {
+		return 0
+	}
+	return float64(r.Rejected) / float64(r.Requests)
+}
+
+// FailureRate returns the fraction of requests admitted by the limiter that failed, either rejected by the server or
+// timed out.
+func (r *Result) FailureRate() float64 {
+	admitted := r.Requests - r.Rejected
+	if admitted == 0 {
+		return 0
+	}
+	return float64(r.ServerRejected+r.TimedOut) / float64(admitted)
+}
+
+// Latency returns the given percentile, accepts [0,1], of the latency of the successful requests.
+func (r *Result) Latency(percentile float64) time.Duration {
+	if len(r.latencies) == 0 {
+		return 0
+	}
+	idx := int(math.Ceil(percentile*float64(len(r.latencies)))) - 1
+	if idx < 0 {
+		idx = 0
+	}
+	if idx >= len(r.latencies) {
+		idx = len(r.latencies) - 1
+	}
+	return r.latencies[idx]
+}
+
+func (r *Result) String() string {
+	return fmt.Sprintf("Result{requests=%d, goodput=%0.2f/s, rejectionRate=%0.4f, failureRate=%0.4f, "+
+		"p50=%v, p99=%v}", r.Requests, r.Goodput(), r.RejectionRate(), r.FailureRate(), r.Latency(0.5), r.Latency(0.99))
+}
+
+// Simulation is a discrete-event simulation of clients sending requests through a limiter to a server.  Time only
+// advances in the virtual clock so a simulation of minutes of traffic runs in milliseconds and, given the same seed,
+// is fully deterministic.  Limiters must not block, i.e. a BlockingLimiter would block the simulation itself.
+type Simulation struct {
+	config Config
+	clock  *VirtualClock
+}
+
+// NewSimulation will create a new Simulation.
+func NewSimulation(config Config) (*Simulation, error) {
+	if config.Duration <= 0 {
+		return nil, fmt.Errorf("duration must be > 0")
+	}
+	if config.Arrivals == nil {
+		return nil, fmt.Errorf("arrivals must be specified")
+	}
+	if config.Server.Workers <= 0 {
+		return nil, fmt.Errorf("server workers must be > 0")
+	}
+	if config.Server.QueueSize < 0 {
+		return nil, fmt.Errorf("server queue size must be >= 0")
+	}
+	if config.Server.ServiceTime == nil {
+		return nil, fmt.Errorf("server service time must be specified")
+	}
+	return &Simulation{
+		config: config,
+		clock:  NewVirtualClock(config.Start),
+	}, nil
+}
+
+// Clock returns the simulation's virtual clock, the limiter and any time based limit must use it.
+func (s *Simulation) Clock() limit.Clock {
+	return s.clock.Now
+}
+
+// Run runs the simulation once, sending the requests through the limiter.  If l is not nil the limit timeline is
+// recorded from its changes.
+func (s *Simulation) Run(limiter core.Limiter, l core.Limit) (*Result, error) {
+	if limiter == nil {
+		return nil, fmt.Errorf("limiter must be specified")
+	}
+	run := &simulationRun{
+		config:   s.config,
+		clock:    s.clock,
+		start:    s.clock.Now(),
+		arrivals: rand.New(rand.NewSource(s.config.Seed)),
+		service:  rand.New(rand.NewSource(s.config.Seed + 1)),
+		limiter:  limiter,
+		result:   &Result{Duration: s.config.Duration},
+		inFlight: make(map[*request]struct{}),
+	}
+	if l != nil {
+		run.recordLimit(l.EstimatedLimit())
+		subscription := l.NotifyOnChange(run.recordLimit)
+		defer subscription.Cancel()
+	}
+	run.run()
+	return run.result, nil
+}
+
+type request struct {
+	arrival  int64
+	listener core.Listener
+	resolved bool
+}
+
+type simulationRun struct {
+	config   Config
+	clock    *VirtualClock
+	start    int64
+	arrivals *rand.Rand
+	service  *rand.Rand
+	limiter  core.Limiter
+	result   *Result
+
+	events   eventQueue
+	seq      int64
+	busy     int
+	queue    []*request
+	inFlight map[*request]struct{}
+}
+
+func (r *simulationRun) run() {
+	end := r.start + r.config.Duration.Nanoseconds()
+	r.scheduleArrival(r.start)
+	for r.events.Len() > 0 {
+		e := heap.Pop(&r.events).(*event)
+		if e.time > end {
+			break
+		}
+		r.clock.set(e.time)
+		e.fn()
+	}
+	r.clock.set(end)
+	// requests still in flight at the end were neither successful nor failed
+	for req := range r.inFlight {
+		req.listener.OnIgnore()
+	}
+	sort.Slice(r.result.latencies, func(i, j int) bool {
+		return r.result.latencies[i] < r.result.latencies[j]
+	})
+}
+
+func (r *simulationRun) schedule(at int64, fn func()) {
+	r.seq++
+	heap.Push(&r.events, &event{time: at, seq: r.seq, fn: fn})
+}
+
+func (r *simulationRun) elapsed() time.Duration {
+	return time.Duration(r.clock.Now() - r.start)
+}
+
+func (r *simulationRun) scheduleArrival(now int64) {
+	rate := r.config.Arrivals(time.Duration(now - r.start))
+	if rate <= 0 {
+		r.schedule(now+idleArrivalCheck.Nanoseconds(), func() {
+			r.scheduleArrival(r.clock.Now())
+		})
+		return
+	}
+	next := now + int64(r.arrivals.ExpFloat64()/rate*1e9)
+	r.schedule(next, func() {
+		r.arrive()
+		r.scheduleArrival(r.clock.Now())
+	})
+}
+
+func (r *simulationRun) arrive() {
+	r.result.Requests++
+	listener, ok := r.limiter.Acquire(context.Background())
+	if !ok {
+		r.result.Rejected++
+		return
+	}
+	req := &request{arrival: r.clock.Now(), listener: listener}
+	r.inFlight[req] = struct{}{}
+
+	if r.busy < r.config.Server.Workers {
+		r.serve(req)
+	} else if len(r.queue) < r.config.Server.QueueSize {
+		r.queue = append(r.queue, req)
+	} else {
+		r.result.ServerRejected++
+		r.resolve(req, false)
+		return
+	}
+	if r.config.Server.Timeout > 0 {
+		r.schedule(req.arrival+r.config.Server.Timeout.Nanoseconds(), func() {
+			if !req.resolved {
+				r.result.TimedOut++
+				r.resolve(req, false)
+			}
+		})
+	}
+}
+
+func (r *simulationRun) serve(req *request) {
+	r.busy++
+	serviceTime := r.config.Server.ServiceTime(r.service)
+	if serviceTime < 0 {
+		serviceTime = 0
+	}
+	r.schedule(r.clock.Now()+serviceTime.Nanoseconds(), func() {
+		r.busy--
+		if !req.resolved {
+			r.result.Succeeded++
+			r.result.latencies = append(r.result.latencies, time.Duration(r.clock.Now()-req.arrival))
+			r.resolve(req, true)
+		}
+		if len(r.queue) > 0 {
+			next := r.queue[0]
+			r.queue = r.queue[1:]
+			r.serve(next)
+		}
+	})
+}
+
+func (r *simulationRun) resolve(req *request, success bool) {
+	req.resolved = true
+	delete(r.inFlight, req)
+	if success {
+		req.listener.OnSuccess()
+	} else {
+		req.listener.OnDropped()
+	}
+}
+
+func (r *simulationRun) recordLimit(newLimit int) {
+	timeline := r.result.Timeline
+	if len(timeline) > 0 && timeline[len(timeline)-1].Limit == newLimit {
+		return
+	}
+	r.result.Timeline = append(timeline, LimitPoint{Elapsed: r.elapsed(), Limit: newLimit})
+}
+
+type event struct {
+	time int64
+	seq  int64
+	fn   func()
+}
+
+// eventQueue orders the events by time, events at the same time in the order they were scheduled.
+type eventQueue []*event
+
+func (q eventQueue) Len() int { return len(q) }
+
+func (q eventQueue) Less(i, j int) bool {
+	if q[i].time == q[j].time {
+		return q[i].seq < q[j].seq
+	}
+	return q[i].time < q[j].time
+}
+
+func (q eventQueue) Swap(i, j int) { q[i], q[j] = q[j], q[i] }
+
+func (q *eventQueue) Push(x interface{}) { *q = append(*q, x.(*event)) }
+
+func (q *eventQueue) Pop() interface{} {
+	old := *q
+	n := len(old)
+	e := old[n-1]
+	old[n-1] = nil
+	*q = old[:n-1]
+	return e
+}
@@ -0,0 +1,172 @@
+package simulation
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/platinummonkey/go-concurrency-limits/core"
+	"github.com/platinummonkey/go-concurrency-limits/limit"
+	"github.com/platinummonkey/go-concurrency-limits/limiter"
+	"github.com/platinummonkey/go-concurrency-limits/strategy"
+)
+
+// overloadConfig doubles the load past the server's capacity of ~1000 requests per second after 2 seconds.
+func overloadConfig() Config {
+	return Config{
+		Duration: 12 * time.Second,
+		Arrivals: StepArrivals(500, 2000, 2*time.Second),
+		Server: Server{
+			Workers:     10,
+			QueueSize:   1000,
+			ServiceTime: ExponentialDistribution(10 * time.Millisecond),
+			Timeout:     200 * time.Millisecond,
+		},
+		Seed: 1,
+	}
+}
+
+func runLimit(t *testing.T, config Config, l core.Limit) *Result {
+	sim, err := NewSimulation(config)
+	assert.NoError(t, err)
+	lim, err := limiter.NewDefaultLimiterWithClock(l, 1e8, 1e9, 1e5, 10, strategy.NewSimpleStrategy(10),
+		sim.Clock(), nil)
+	assert.NoError(t, err)
+	result, err := sim.Run(lim, l)
+	assert.NoError(t, err)
+	return result
+}
+
+type unlimitedLimiter struct{}
+
+func (unlimitedLimiter) Acquire(ctx context.Context) (core.Listener, bool) {
+	return unlimitedListener{}, true
+}
+
+type unlimitedListener struct{}
+
+func (unlimitedListener) OnSuccess() {}
+func (unlimitedListener) OnIgnore()  {}
+func (unlimitedListener) OnDropped() {}
+
+func TestSimulation(t *testing.T) {
+	t.Parallel()
+
+	t.Run("InvalidConfig", func(t2 *testing.T) {
+		t2.Parallel()
+		asrt := assert.New(t2)
+		valid := overloadConfig()
+		configs := []func(c *Config){
+			func(c *Config) { c.Duration = 0 },
+			func(c *Config) { c.Arrivals = nil },
+			func(c *Config) { c.Server.Workers = 0 },
+			func(c *Config) { c.Server.QueueSize = -1 },
+			func(c *Config) { c.Server.ServiceTime = nil },
+		}
+		for _, mutate := range configs {
+			c := valid
+			mutate(&c)
+			_, err := NewSimulation(c)
+			asrt.Error(err)
+		}
+		sim, err := NewSimulation(valid)
+		asrt.NoError(err)
+		_, err = sim.Run(nil, nil)
+		asrt.Error(err)
+	})
+
+	t.Run("UnderCapacity", func(t2 *testing.T) {
+		t2.Parallel()
+		asrt := assert.New(t2)
+		sim, err := NewSimulation(Config{
+			Duration: 10 * time.Second,
+			Arrivals: ConstantArrivals(100),
+			Server:   Server{Workers: 100, ServiceTime: ConstantDistribution(5 * time.Millisecond)},
+			Seed:     1,
+			Start:    1e9,
+		})
+		asrt.NoError(err)
+		result, err := sim.Run(unlimitedLimiter{}, nil)
+		asrt.NoError(err)
+		asrt.InDelta(1000, result.Requests, 100)
+		asrt.Equal(0, result.Rejected)
+		asrt.InDelta(result.Requests, result.Succeeded, 1)
+		asrt.InDelta(100, result.Goodput(), 10)
+		asrt.Equal(0.0, result.RejectionRate())
+		asrt.Equal(5*time.Millisecond, result.Latency(0.5))
+		asrt.Equal(5*time.Millisecond, result.Latency(0.99))
+		asrt.Len(result.Timeline, 0)
+		asrt.Equal(int64(11e9), sim.Clock()())
+	})
+
+	t.Run("ServerQueue", func(t2 *testing.T) {
+		t2.Parallel()
+		asrt := assert.New(t2)
+		sim, err := NewSimulation(Config{
+			Duration: 5 * time.Second,
+			Arrivals: ConstantArrivals(200),
+			Server:   Server{Workers: 1, QueueSize: 10, ServiceTime: ConstantDistribution(10 * time.Millisecond)},
+			Seed:     1,
+		})
+		asrt.NoError(err)
+		result, err := sim.Run(unlimitedLimiter{}, nil)
+		asrt.NoError(err)
+		// the server only serves 100 requests per second, the rest overflow the queue
+		asrt.InDelta(100, result.Goodput(), 5)
+		asrt.InDelta(0.5, result.FailureRate(), 0.05)
+		asrt.True(result.ServerRejected > 0)
+		asrt.Equal(0, result.TimedOut)
+		asrt.True(result.Latency(0.99) <= 110*time.Millisecond)
+	})
+
+	t.Run("IdleArrivals", func(t2 *testing.T) {
+		t2.Parallel()
+		asrt := assert.New(t2)
+		sim, err := NewSimulation(Config{
+			Duration: 10 * time.Second,
+			Arrivals: StepArrivals(0, 100, 5*time.Second),
+			Server:   Server{Workers: 10, ServiceTime: ConstantDistribution(time.Millisecond)},
+		})
+		asrt.NoError(err)
+		result, err := sim.Run(unlimitedLimiter{}, nil)
+		asrt.NoError(err)
+		asrt.InDelta(500, result.Requests, 75)
+	})
+
+	t.Run("Deterministic", func(t2 *testing.T) {
+		t2.Parallel()
+		asrt := assert.New(t2)
+		first := runLimit(t2, overloadConfig(), limit.NewDefaultGradient2Limit("test", nil))
+		second := runLimit(t2, overloadConfig(), limit.NewDefaultGradient2Limit("test", nil))
+		asrt.Equal(first, second)
+	})
+
+	t.Run("CompareLimits", func(t2 *testing.T) {
+		t2.Parallel()
+		asrt := assert.New(t2)
+
+		// without an adaptive limit the queue grows until most requests time out
+		unlimited := runLimit(t2, overloadConfig(), limit.NewFixedLimit("test", 100000))
+		asrt.True(unlimited.FailureRate() > 0.5, "%v", unlimited)
+		asrt.True(unlimited.TimedOut > 0)
+		asrt.Len(unlimited.Timeline, 1)
+
+		limits := map[string]core.Limit{
+			"vegas":     limit.NewDefaultVegasLimit("test", nil),
+			"gradient2": limit.NewDefaultGradient2Limit("test", nil),
+			"aimd":      limit.NewDefaultAIMLimit("test"),
+			"gradient":  limit.NewGradientLimitWithRegistry("test", 0, 0, 0, -1, nil, -1, 0, nil),
+		}
+		for name, l := range limits {
+			result := runLimit(t2, overloadConfig(), l)
+			asrt.Equal(unlimited.Requests, result.Requests, name)
+			asrt.True(result.Goodput() > 5*unlimited.Goodput(), "%s %v", name, result)
+			asrt.True(result.FailureRate() < 0.01, "%s %v", name, result)
+			asrt.True(result.RejectionRate() > 0.3, "%s %v", name, result)
+			asrt.True(len(result.Timeline) > 1, name)
+			asrt.Equal(l.EstimatedLimit(), result.Timeline[len(result.Timeline)-1].Limit, name)
+		}
+	})
+}
@@ -0,0 +1,237 @@
+package strategy
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/platinummonkey/go-concurrency-limits/core"
+)
+
+type byteReservationContextKey struct{}
+
+// ByteReservation declares the payload size of a request for the ByteBudgetStrategy.  Once acquired the bytes may be
+// released incrementally as a streaming body is consumed, the remainder is released with the token.
+// A reservation is bound to its first acquisition, later acquisitions with the same reservation are rejected.
+type ByteReservation struct {
+	size      int64
+	remaining int64
+	strategy  *ByteBudgetStrategy
+	done      bool
+	mu        sync.Mutex
+}
+
+// NewByteReservation will create a new ByteReservation for a payload of size bytes.
+func NewByteReservation(size int64) *ByteReservation {
+	if size < 0 {
+		size = 0
+	}
+	return &ByteReservation{
+		size: size,
+	}
+}
+
+// WithByteReservation returns a copy of the context carrying the reservation of the request.
+func WithByteReservation(ctx context.Context, reservation *ByteReservation) context.Context {
+	return context.WithValue(ctx, byteReservationContextKey{}, reservation)
+}
+
+// WithPayloadBytes returns a copy of the context declaring the payload size of the request, along with the
+// reservation used to release the bytes incrementally.
+func WithPayloadBytes(ctx context.Context, size int64) (context.Context, *ByteReservation) {
+	reservation := NewByteReservation(size)
+	return WithByteReservation(ctx, reservation), reservation
+}
+
+// ByteReservationFromContext returns the reservation of the request, if any.
+func ByteReservationFromContext(ctx context.Context) (*ByteReservation, bool) {
+	if ctx == nil {
+		return nil, false
+	}
+	reservation, ok := ctx.Value(byteReservationContextKey{}).(*ByteReservation)
+	return reservation, ok && reservation != nil
+}
+
+// Size returns the declared payload size in bytes.
+func (r *ByteReservation) Size() int64 {
+	return r.size
+}
+
+// Outstanding returns the number of acquired bytes not yet released.
+func (r *ByteReservation) Outstanding() int64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.remaining
+}
+
+// Release returns n of the acquired bytes to the budget, i.e. as a streaming body is consumed.  Releasing more than
+// is outstanding releases the remainder, releasing before the reservation is acquired is a noop.
+func (r *ByteReservation) Release(n int64) {
+	r.mu.Lock()
+	if r.strategy == nil || r.done || n <= 0 {
+		r.mu.Unlock()
+		return
+	}
+	if n > r.remaining {
+		n = r.remaining
+	}
+	r.remaining -= n
+	strategy := r.strategy
+	r.mu.Unlock()
+
+	strategy.release(n, false)
+}
+
+// bind binds the reservation to the strategy once its bytes are acquired, returning false if the reservation was
+// already used for an acquisition.
+func (r *ByteReservation) bind(strategy *ByteBudgetStrategy) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.strategy != nil {
+		return false
+	}
+	r.strategy = strategy
+	r.remaining = r.size
+	return true
+}
+
+// releaseAll releases the outstanding bytes along with the request, only the first call has an effect.
+func (r *ByteReservation) releaseAll() {
+	r.mu.Lock()
+	if r.strategy == nil || r.done {
+		r.mu.Unlock()
+		return
+	}
+	r.done = true
+	n := r.remaining
+	r.remaining = 0
+	strategy := r.strategy
+	r.mu.Unlock()
+
+	strategy.release(n, true)
+}
+
+// ByteBudgetStrategy admits requests based on their declared payload size rather than their count, for ingestion
+// endpoints where the scarce resource is buffered bytes.  A request is admitted while the bytes in flight plus its size
+// fit in the budget, a request larger than the budget is only admitted while nothing else is in flight so it can't be
+// starved forever.
+//
+// The budget is derived from the current limit as limit * averageBytes, or is fixed when budgetBytes is given in which
+// case the limit is ignored.  Requests declare their size with WithPayloadBytes or WithByteReservation, undeclared
+// requests are assumed to be averageBytes.
+type ByteBudgetStrategy struct {
+	averageBytes int64
+	budgetBytes  int64
+
+	limit         int
+	inFlight      int
+	inFlightBytes int64
+	mu            sync.RWMutex
+}
+
+// NewByteBudgetStrategy will create a new ByteBudgetStrategy.
+// @param limit: The initial limit.
+// @param averageBytes: The average payload size, used to derive the budget and as the size of undeclared requests.
+// @param budgetBytes: A fixed budget independent of the limit, 0 to derive the budget from the limit.
+func NewByteBudgetStrategy(limit int, averageBytes int64, budgetBytes int64) (*ByteBudgetStrategy, error) {
+	if averageBytes <= 0 {
+		return nil, fmt.Errorf("averageBytes must be > 0")
+	}
+	if budgetBytes < 0 {
+		return nil, fmt.Errorf("budgetBytes must be >= 0")
+	}
+	if limit < 1 {
+		limit = 1
+	}
+	return &ByteBudgetStrategy{
+		averageBytes: averageBytes,
+		budgetBytes:  budgetBytes,
+		limit:        limit,
+	}, nil
+}
+
+// TryAcquire will try to acquire the declared payload size of the request from the byte budget.
+// context Context of the request carrying the ByteReservation.
+// returns not ok if the budget is exceeded, or a StrategyToken that must be released when the operation completes.
+func (s *ByteBudgetStrategy) TryAcquire(ctx context.Context) (token core.StrategyToken, ok bool) {
+	reservation, ok := ByteReservationFromContext(ctx)
+	if !ok {
+		reservation = NewByteReservation(s.averageBytes)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.inFlight > 0 && s.inFlightBytes+reservation.size > s.budget() {
+		return core.NewNotAcquiredStrategyToken(s.inFlight), false
+	}
+	if !reservation.bind(s) {
+		return core.NewNotAcquiredStrategyToken(s.inFlight), false
+	}
+	s.inFlight++
+	s.inFlightBytes += reservation.size
+	return core.NewAcquiredStrategyToken(s.inFlight, reservation.releaseAll), true
+}
+
+// release returns the bytes to the budget, and the request if done.
+func (s *ByteBudgetStrategy) release(n int64, done bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.inFlightBytes -= n
+	if done {
+		s.inFlight--
+	}
+}
+
+// budget returns the current byte budget.
+// note: not thread safe.
+func (s *ByteBudgetStrategy) budget() int64 {
+	if s.budgetBytes > 0 {
+		return s.budgetBytes
+	}
+	return int64(s.limit) * s.averageBytes
+}
+
+// SetLimit will update the limit the byte budget is derived from.
+func (s *ByteBudgetStrategy) SetLimit(limit int) {
+	if limit < 1 {
+		limit = 1
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.limit = limit
+}
+
+// GetLimit will get the current limit.
+func (s *ByteBudgetStrategy) GetLimit() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.limit
+}
+
+// GetBusyCount will get the number of requests in flight.
+func (s *ByteBudgetStrategy) GetBusyCount() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.inFlight
+}
+
+// BudgetBytes returns the current byte budget.
+func (s *ByteBudgetStrategy) BudgetBytes() int64 {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.budget()
+}
+
+// InFlightBytes returns the number of acquired bytes not yet released.
+func (s *ByteBudgetStrategy) InFlightBytes() int64 {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.inFlightBytes
+}
+
+func (s *ByteBudgetStrategy) String() string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return fmt.Sprintf("ByteBudgetStrategy{inFlight=%d, inFlightBytes=%d, budgetBytes=%d, limit=%d}",
+		s.inFlight, s.inFlightBytes, s.budget(), s.limit)
+}
@@ -0,0 +1,150 @@
+package strategy
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestByteBudgetStrategy(t *testing.T) {
+	t.Parallel()
+
+	t.Run("NewByteBudgetStrategy", func(t2 *testing.T) {
+		t2.Parallel()
+		asrt := assert.New(t2)
+		_, err := NewByteBudgetStrategy(10, 0, 0)
+		asrt.Error(err)
+		_, err = NewByteBudgetStrategy(10, 100, -1)
+		asrt.Error(err)
+
+		strategy, err := NewByteBudgetStrategy(-1, 100, 0)
+		asrt.NoError(err)
+		asrt.Equal(1, strategy.GetLimit())
+		asrt.Equal(int64(100), strategy.BudgetBytes())
+		asrt.Equal("ByteBudgetStrategy{inFlight=0, inFlightBytes=0, budgetBytes=100, limit=1}", strategy.String())
+	})
+
+	t.Run("BudgetDerivedFromLimit", func(t2 *testing.T) {
+		t2.Parallel()
+		asrt := assert.New(t2)
+		strategy, err := NewByteBudgetStrategy(10, 100, 0)
+		asrt.NoError(err)
+		asrt.Equal(int64(1000), strategy.BudgetBytes())
+		strategy.SetLimit(20)
+		asrt.Equal(int64(2000), strategy.BudgetBytes())
+
+		// an independent budget ignores the limit
+		strategy, err = NewByteBudgetStrategy(10, 100, 5000)
+		asrt.NoError(err)
+		strategy.SetLimit(20)
+		asrt.Equal(int64(5000), strategy.BudgetBytes())
+	})
+
+	t.Run("AdmitsByDeclaredSize", func(t2 *testing.T) {
+		t2.Parallel()
+		asrt := assert.New(t2)
+		strategy, err := NewByteBudgetStrategy(10, 100, 0)
+		asrt.NoError(err)
+
+		ctx, _ := WithPayloadBytes(context.Background(), 800)
+		large, ok := strategy.TryAcquire(ctx)
+		asrt.True(ok && large.IsAcquired())
+		asrt.Equal(int64(800), strategy.InFlightBytes())
+
+		// undeclared requests are assumed to be average sized
+		small, ok := strategy.TryAcquire(context.Background())
+		asrt.True(ok)
+		asrt.Equal(int64(900), strategy.InFlightBytes())
+		asrt.Equal(2, strategy.GetBusyCount())
+
+		ctx, _ = WithPayloadBytes(context.Background(), 101)
+		token, ok := strategy.TryAcquire(ctx)
+		asrt.False(ok)
+		asrt.False(token.IsAcquired())
+
+		large.Release()
+		small.Release()
+		// releasing twice has no effect
+		large.Release()
+		asrt.Equal(int64(0), strategy.InFlightBytes())
+		asrt.Equal(0, strategy.GetBusyCount())
+	})
+
+	t.Run("OversizedAdmittedWhileIdle", func(t2 *testing.T) {
+		t2.Parallel()
+		asrt := assert.New(t2)
+		strategy, err := NewByteBudgetStrategy(1, 100, 0)
+		asrt.NoError(err)
+		ctx, _ := WithPayloadBytes(context.Background(), 1000)
+		token, ok := strategy.TryAcquire(ctx)
+		asrt.True(ok)
+		_, ok = strategy.TryAcquire(ctx)
+		asrt.False(ok)
+		token.Release()
+		asrt.Equal(int64(0), strategy.InFlightBytes())
+	})
+
+	t.Run("PartialRelease", func(t2 *testing.T) {
+		t2.Parallel()
+		asrt := assert.New(t2)
+		strategy, err := NewByteBudgetStrategy(10, 100, 0)
+		asrt.NoError(err)
+
+		ctx, reservation := WithPayloadBytes(context.Background(), 1000)
+		// releasing before acquiring is a noop
+		reservation.Release(100)
+		token, ok := strategy.TryAcquire(ctx)
+		asrt.True(ok)
+		asrt.Equal(int64(1000), reservation.Outstanding())
+		_, ok = strategy.TryAcquire(context.Background())
+		asrt.False(ok)
+
+		// consume the body
+		reservation.Release(300)
+		asrt.Equal(int64(700), reservation.Outstanding())
+		asrt.Equal(int64(700), strategy.InFlightBytes())
+		other, ok := strategy.TryAcquire(context.Background())
+		asrt.True(ok)
+
+		reservation.Release(5000)
+		asrt.Equal(int64(0), reservation.Outstanding())
+		asrt.Equal(int64(100), strategy.InFlightBytes())
+		asrt.Equal(2, strategy.GetBusyCount())
+
+		token.Release()
+		other.Release()
+		asrt.Equal(int64(0), strategy.InFlightBytes())
+		asrt.Equal(0, strategy.GetBusyCount())
+		asrt.Equal(int64(1000), reservation.Size())
+	})
+
+	t.Run("ReservationReuseRejected", func(t2 *testing.T) {
+		t2.Parallel()
+		asrt := assert.New(t2)
+		strategy, err := NewByteBudgetStrategy(10, 100, 0)
+		asrt.NoError(err)
+
+		ctx, _ := WithPayloadBytes(context.Background(), 100)
+		token, ok := strategy.TryAcquire(ctx)
+		asrt.True(ok)
+		token.Release()
+		asrt.Equal(int64(0), strategy.InFlightBytes())
+
+		// the reservation was already used, acquiring with it again would leak its bytes
+		_, ok = strategy.TryAcquire(ctx)
+		asrt.False(ok)
+		asrt.Equal(int64(0), strategy.InFlightBytes())
+		asrt.Equal(0, strategy.GetBusyCount())
+
+		// also while the first acquisition is still in flight
+		ctx, _ = WithPayloadBytes(context.Background(), 100)
+		token, ok = strategy.TryAcquire(ctx)
+		asrt.True(ok)
+		_, ok = strategy.TryAcquire(ctx)
+		asrt.False(ok)
+		token.Release()
+		asrt.Equal(int64(0), strategy.InFlightBytes())
+		asrt.Equal(0, strategy.GetBusyCount())
+	})
+}
@@ -0,0 +1,317 @@
+package strategy
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strings"
+	"sync"
+
+	"github.com/platinummonkey/go-concurrency-limits/core"
+	"github.com/platinummonkey/go-concurrency-limits/strategy/matchers"
+)
+
+// unknownPartitionName is the name of the partition requests not matching any child are assigned to.
+const unknownPartitionName = "<unknown>"
+
+// HierarchicalPartition defines a node of the HierarchicalPartitionStrategy's partition tree, i.e. a tenant divided
+// further among its RPC methods.  A node's limit is its percent of its parent's limit.
+// Note: generally speaking you shouldn't use this directly, instead use the higher level HierarchicalPartitionStrategy
+type HierarchicalPartition struct {
+	name       string
+	percent    float64
+	lookupFunc func(ctx context.Context) string
+	children   []*HierarchicalPartition
+	byName     map[string]*HierarchicalPartition
+	unknown    *HierarchicalPartition
+	parent     *HierarchicalPartition
+
+	limit int32
+	busy  int32
+	mu    sync.RWMutex
+}
+
+// NewHierarchicalPartition will create a new HierarchicalPartition.  Requests are assigned to a child by name with
+// the lookup function, requests not matching any child are assigned to an unknown child with a 0 percent share.
+// @param name: The name of the partition, unique among its siblings.
+// @param percent: The percent, accepts [0,1], of the parent's limit guaranteed to the partition.
+// @param lookupFunc: Selects the child of the request, defaults to matchers.DefaultStringLookupFunc.
+// @param children: The child partitions, their percentages must sum to <= 1.0.
+func NewHierarchicalPartition(
+	name string,
+	percent float64,
+	lookupFunc func(ctx context.Context) string,
+	children ...*HierarchicalPartition,
+) (*HierarchicalPartition, error) {
+	if percent < 0 || percent > 1 {
+		return nil, fmt.Errorf("percent must be between [0,1]")
+	}
+	p := &HierarchicalPartition{
+		name:     name,
+		percent:  percent,
+		children: children,
+		limit:    1,
+	}
+	if len(children) == 0 {
+		return p, nil
+	}
+
+	if lookupFunc == nil {
+		lookupFunc = matchers.DefaultStringLookupFunc
+	}
+	sum := float64(0)
+	byName := make(map[string]*HierarchicalPartition, len(children))
+	for _, child := range children {
+		if child == nil {
+			return nil, fmt.Errorf("partition %s has a nil child", name)
+		}
+		if _, ok := byName[child.name]; ok || child.name == unknownPartitionName {
+			return nil, fmt.Errorf("partition %s has a duplicate child %s", name, child.name)
+		}
+		if child.parent != nil {
+			return nil, fmt.Errorf("partition %s already belongs to %s", child.name, child.parent.name)
+		}
+		sum += child.percent
+		byName[child.name] = child
+	}
+	if sum > 1.0 {
+		return nil, fmt.Errorf("sum of percentages of partition %s must be <= 1.0", name)
+	}
+	for _, child := range children {
+		child.parent = p
+	}
+	p.lookupFunc = lookupFunc
+	p.byName = byName
+	p.unknown = &HierarchicalPartition{
+		name:    unknownPartitionName,
+		percent: 0,
+		limit:   1,
+		parent:  p,
+	}
+	return p, nil
+}
+
+// Name will return the partition name, these are immutable.
+func (p *HierarchicalPartition) Name() string {
+	return p.name
+}
+
+// Percent returns the partition percent, these are immutable.
+func (p *HierarchicalPartition) Percent() float64 {
+	return p.percent
+}
+
+// Children returns the child partitions, these are immutable.
+func (p *HierarchicalPartition) Children() []*HierarchicalPartition {
+	return p.children
+}
+
+// Child returns the child partition with the given name, including the unknown partition.
+func (p *HierarchicalPartition) Child(name string) (*HierarchicalPartition, bool) {
+	if p.unknown != nil && name == unknownPartitionName {
+		return p.unknown, true
+	}
+	child, ok := p.byName[name]
+	return child, ok
+}
+
+// BusyCount will return the current busy count, including the busy count of the children.
+func (p *HierarchicalPartition) BusyCount() int {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return int(p.busy)
+}
+
+// Limit will return the current limit.
+func (p *HierarchicalPartition) Limit() int {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return int(p.limit)
+}
+
+// updateLimit will update the limit of the partition and its children.  The limit is rounded up and at least 1, so
+// the sum of the children's limits may end up being higher than the partition's limit.
+func (p *HierarchicalPartition) updateLimit(limit int32) {
+	p.mu.Lock()
+	p.limit = limit
+	p.mu.Unlock()
+	for _, child := range p.children {
+		child.updateLimit(int32(math.Max(1, math.Ceil(float64(limit)*child.percent))))
+	}
+	if p.unknown != nil {
+		p.unknown.updateLimit(1)
+	}
+}
+
+// lookup returns the path of partitions from this partition down to the leaf the request is assigned to.
+func (p *HierarchicalPartition) lookup(ctx context.Context) []*HierarchicalPartition {
+	path := []*HierarchicalPartition{p}
+	for node := p; node.lookupFunc != nil; {
+		child, ok := node.byName[node.lookupFunc(ctx)]
+		if !ok {
+			child = node.unknown
+		}
+		path = append(path, child)
+		node = child
+	}
+	return path
+}
+
+// canAcquire returns true if the partition is within its share or may borrow the weight from its parent, recursively.
+func (p *HierarchicalPartition) canAcquire(weight int32) bool {
+	p.mu.RLock()
+	busy, limit := p.busy, p.limit
+	p.mu.RUnlock()
+	if p.parent == nil {
+		// a request heavier than the limit is only admitted while nothing else is in flight
+		return busy == 0 || busy+weight <= limit
+	}
+	return busy+weight <= limit || p.parent.canAcquire(weight)
+}
+
+func (p *HierarchicalPartition) add(delta int32) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.busy += delta
+}
+
+func (p *HierarchicalPartition) String() string {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if len(p.children) == 0 {
+		return fmt.Sprintf("HierarchicalPartition{name=%s, percent=%f, limit=%d, busy=%d}",
+			p.name, p.percent, p.limit, p.busy)
+	}
+	return fmt.Sprintf("HierarchicalPartition{name=%s, percent=%f, limit=%d, busy=%d, children=%v}",
+		p.name, p.percent, p.limit, p.busy, p.children)
+}
+
+// HierarchicalPartitionStrategy is a concurrency limiter with nested partitions, i.e. a percentage of the limit
+// guaranteed per tenant which is further divided among the tenant's RPC methods.
+//
+// The borrowing rules of LookupPartitionStrategy apply at each level: a partition within its share of its parent's
+// limit is always admitted, otherwise it may borrow excess capacity from its parent as long as the parent is within
+// its own share or may borrow in turn, up to the total limit.
+type HierarchicalPartitionStrategy struct {
+	root *HierarchicalPartition
+	mu   sync.Mutex
+}
+
+// NewHierarchicalPartitionStrategy will create a new HierarchicalPartitionStrategy.
+// @param partitions: The top level partitions.
+// @param lookupFunc: Selects the top level partition of the request, defaults to matchers.DefaultStringLookupFunc.
+// @param limit: The initial limit.
+func NewHierarchicalPartitionStrategy(
+	partitions []*HierarchicalPartition,
+	lookupFunc func(ctx context.Context) string,
+	limit int32,
+) (*HierarchicalPartitionStrategy, error) {
+	if len(partitions) == 0 {
+		return nil, fmt.Errorf("no partitions specified")
+	}
+	root, err := NewHierarchicalPartition("", 1.0, lookupFunc, partitions...)
+	if err != nil {
+		return nil, err
+	}
+	if limit < 1 {
+		limit = 1
+	}
+	root.updateLimit(limit)
+	return &HierarchicalPartitionStrategy{
+		root: root,
+	}, nil
+}
+
+// TryAcquire a token from the partition of the request.
+func (s *HierarchicalPartitionStrategy) TryAcquire(ctx context.Context) (core.StrategyToken, bool) {
+	return s.TryAcquireWeighted(ctx, 1)
+}
+
+// TryAcquireWeighted will try to acquire weight units from the partition of the request with a single token.
+func (s *HierarchicalPartitionStrategy) TryAcquireWeighted(ctx context.Context, weight int) (core.StrategyToken, bool) {
+	if weight < 1 {
+		weight = 1
+	}
+	w := int32(weight)
+	path := s.root.lookup(ctx)
+	leaf := path[len(path)-1]
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !leaf.canAcquire(w) {
+		return core.NewNotAcquiredStrategyToken(s.root.BusyCount()), false
+	}
+	for _, p := range path {
+		p.add(w)
+	}
+	return core.NewAcquiredStrategyToken(s.root.BusyCount(), s.releasePartitions(path, w)), true
+}
+
+func (s *HierarchicalPartitionStrategy) releasePartitions(path []*HierarchicalPartition, weight int32) func() {
+	return func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		for _, p := range path {
+			p.add(-weight)
+		}
+	}
+}
+
+// SetLimit will set a new limit for the HierarchicalPartitionStrategy and its partitions.
+func (s *HierarchicalPartitionStrategy) SetLimit(limit int) {
+	if limit < 1 {
+		limit = 1
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.root.Limit() != limit {
+		// only do it if they don't match, otherwise it's just extra churn by O(N)
+		s.root.updateLimit(int32(limit))
+	}
+}
+
+// BusyCount will return the current busy count.
+func (s *HierarchicalPartitionStrategy) BusyCount() int {
+	return s.root.BusyCount()
+}
+
+// Limit will return the current limit.
+func (s *HierarchicalPartitionStrategy) Limit() int {
+	return s.root.Limit()
+}
+
+// Partition returns the partition at the given path of names, i.e. tenant then method.
+func (s *HierarchicalPartitionStrategy) Partition(path ...string) (*HierarchicalPartition, error) {
+	node := s.root
+	for _, name := range path {
+		child, ok := node.Child(name)
+		if !ok {
+			return nil, fmt.Errorf("invalid group %s", strings.Join(path, "/"))
+		}
+		node = child
+	}
+	return node, nil
+}
+
+// BinBusyCount will return the busy count of the partition at the given path
+func (s *HierarchicalPartitionStrategy) BinBusyCount(path ...string) (int, error) {
+	partition, err := s.Partition(path...)
+	if err != nil {
+		return 0, err
+	}
+	return partition.BusyCount(), nil
+}
+
+// BinLimit will return the limit of the partition at the given path
+func (s *HierarchicalPartitionStrategy) BinLimit(path ...string) (int, error) {
+	partition, err := s.Partition(path...)
+	if err != nil {
+		return 0, err
+	}
+	return partition.Limit(), nil
+}
+
+func (s *HierarchicalPartitionStrategy) String() string {
+	return fmt.Sprintf("HierarchicalPartitionStrategy{partitions=%v, limit=%d, busy=%d}",
+		s.root.children, s.root.Limit(), s.root.BusyCount())
+}
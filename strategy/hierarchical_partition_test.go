@@ -0,0 +1,183 @@
+package strategy
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/platinummonkey/go-concurrency-limits/strategy/matchers"
+)
+
+type testMethodContextKey struct{}
+
+func testMethodLookupFunc(ctx context.Context) string {
+	method, _ := ctx.Value(testMethodContextKey{}).(string)
+	return method
+}
+
+func testHierarchicalContext(tenant string, method string) context.Context {
+	ctx := context.WithValue(context.Background(), matchers.LookupPartitionContextKey, tenant)
+	return context.WithValue(ctx, testMethodContextKey{}, method)
+}
+
+func makeTestHierarchicalPartitions(t *testing.T) []*HierarchicalPartition {
+	read, _ := NewHierarchicalPartition("read", 0.5, nil)
+	write, _ := NewHierarchicalPartition("write", 0.5, nil)
+	a, err := NewHierarchicalPartition("a", 0.6, testMethodLookupFunc, read, write)
+	assert.NoError(t, err)
+	b, _ := NewHierarchicalPartition("b", 0.4, nil)
+	return []*HierarchicalPartition{a, b}
+}
+
+func TestHierarchicalPartitionStrategy(t *testing.T) {
+	t.Parallel()
+
+	t.Run("NewHierarchicalPartition", func(t2 *testing.T) {
+		t2.Parallel()
+		asrt := assert.New(t2)
+		_, err := NewHierarchicalPartition("a", 1.5, nil)
+		asrt.Error(err)
+
+		x, _ := NewHierarchicalPartition("x", 0.6, nil)
+		y, _ := NewHierarchicalPartition("y", 0.6, nil)
+		_, err = NewHierarchicalPartition("a", 1.0, nil, x, y)
+		asrt.EqualError(err, "sum of percentages of partition a must be <= 1.0")
+
+		x2, _ := NewHierarchicalPartition("x", 0.1, nil)
+		_, err = NewHierarchicalPartition("a", 1.0, nil, x, x2)
+		asrt.EqualError(err, "partition a has a duplicate child x")
+
+		_, err = NewHierarchicalPartition("a", 1.0, nil, x)
+		asrt.NoError(err)
+		_, err = NewHierarchicalPartition("b", 1.0, nil, x)
+		asrt.EqualError(err, "partition x already belongs to a")
+
+		_, err = NewHierarchicalPartitionStrategy(nil, nil, 10)
+		asrt.Error(err)
+	})
+
+	t.Run("LimitAllocatedToNodes", func(t2 *testing.T) {
+		t2.Parallel()
+		asrt := assert.New(t2)
+		strategy, err := NewHierarchicalPartitionStrategy(makeTestHierarchicalPartitions(t2), nil, 10)
+		asrt.NoError(err)
+		asrt.Equal(10, strategy.Limit())
+		asrt.Equal(0, strategy.BusyCount())
+
+		for _, tc := range []struct {
+			path  []string
+			limit int
+		}{
+			{[]string{"a"}, 6},
+			{[]string{"a", "read"}, 3},
+			{[]string{"a", "write"}, 3},
+			{[]string{"a", "<unknown>"}, 1},
+			{[]string{"b"}, 4},
+			{[]string{"<unknown>"}, 1},
+		} {
+			lmt, err := strategy.BinLimit(tc.path...)
+			asrt.NoError(err)
+			asrt.Equal(tc.limit, lmt, "%v", tc.path)
+		}
+		_, err = strategy.BinLimit("a", "delete")
+		asrt.EqualError(err, "invalid group a/delete")
+		_, err = strategy.BinBusyCount("b", "read")
+		asrt.Error(err)
+
+		strategy.SetLimit(20)
+		lmt, _ := strategy.BinLimit("a", "read")
+		asrt.Equal(6, lmt)
+		lmt, _ = strategy.BinLimit("b")
+		asrt.Equal(8, lmt)
+		strategy.SetLimit(-1)
+		lmt, _ = strategy.BinLimit("a", "write")
+		asrt.Equal(1, lmt)
+
+		partition, err := strategy.Partition("a")
+		asrt.NoError(err)
+		asrt.Equal("a", partition.Name())
+		asrt.Equal(0.6, partition.Percent())
+		asrt.Len(partition.Children(), 2)
+		asrt.Equal(
+			"HierarchicalPartitionStrategy{partitions=[HierarchicalPartition{name=a, percent=0.600000, limit=1, "+
+				"busy=0, children=[HierarchicalPartition{name=read, percent=0.500000, limit=1, busy=0} "+
+				"HierarchicalPartition{name=write, percent=0.500000, limit=1, busy=0}]} "+
+				"HierarchicalPartition{name=b, percent=0.400000, limit=1, busy=0}], limit=1, busy=0}",
+			strategy.String(),
+		)
+	})
+
+	t.Run("BorrowAtEachLevel", func(t2 *testing.T) {
+		t2.Parallel()
+		asrt := assert.New(t2)
+		strategy, err := NewHierarchicalPartitionStrategy(makeTestHierarchicalPartitions(t2), nil, 10)
+		asrt.NoError(err)
+
+		tokens := make([]func(), 0)
+		acquire := func(ctx context.Context) bool {
+			token, ok := strategy.TryAcquire(ctx)
+			if ok {
+				tokens = append(tokens, token.Release)
+			}
+			return ok
+		}
+
+		// a/read uses its share, then borrows from a and then from the total limit
+		for i := 0; i < 10; i++ {
+			asrt.True(acquire(testHierarchicalContext("a", "read")), "acquire %d", i)
+		}
+		asrt.False(acquire(testHierarchicalContext("a", "read")))
+		busy, _ := strategy.BinBusyCount("a", "read")
+		asrt.Equal(10, busy)
+
+		// a/write is still guaranteed its share of a
+		for i := 0; i < 3; i++ {
+			asrt.True(acquire(testHierarchicalContext("a", "write")), "acquire %d", i)
+		}
+		asrt.False(acquire(testHierarchicalContext("a", "write")))
+		// as is b its share of the total
+		for i := 0; i < 4; i++ {
+			asrt.True(acquire(testHierarchicalContext("b", "")), "acquire %d", i)
+		}
+		asrt.False(acquire(testHierarchicalContext("b", "")))
+		// unknown tenants and methods may only use excess capacity beyond their minimum of 1
+		asrt.True(acquire(testHierarchicalContext("c", "")))
+		asrt.False(acquire(testHierarchicalContext("c", "")))
+		asrt.True(acquire(testHierarchicalContext("a", "delete")))
+		asrt.False(acquire(testHierarchicalContext("a", "delete")))
+
+		busy, _ = strategy.BinBusyCount("a")
+		asrt.Equal(14, busy)
+		asrt.Equal(19, strategy.BusyCount())
+
+		for _, release := range tokens {
+			release()
+		}
+		asrt.Equal(0, strategy.BusyCount())
+		busy, _ = strategy.BinBusyCount("a", "read")
+		asrt.Equal(0, busy)
+	})
+
+	t.Run("AcquireWeighted", func(t2 *testing.T) {
+		t2.Parallel()
+		asrt := assert.New(t2)
+		strategy, err := NewHierarchicalPartitionStrategy(makeTestHierarchicalPartitions(t2), nil, 10)
+		asrt.NoError(err)
+
+		batch, ok := strategy.TryAcquireWeighted(testHierarchicalContext("a", "write"), 9)
+		asrt.True(ok)
+		busy, _ := strategy.BinBusyCount("a", "write")
+		asrt.Equal(9, busy)
+		_, ok = strategy.TryAcquireWeighted(testHierarchicalContext("a", "write"), 2)
+		asrt.False(ok)
+		// b is within its share
+		token, ok := strategy.TryAcquireWeighted(testHierarchicalContext("b", ""), 4)
+		asrt.True(ok)
+		asrt.Equal(13, token.InFlightCount())
+
+		batch.Release()
+		token.Release()
+		asrt.Equal(0, strategy.BusyCount())
+	})
+}
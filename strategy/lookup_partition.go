@@ -75,20 +75,35 @@ func (p *LookupPartition) IsLimitExceeded() bool {
 	return p.busy >= p.limit
 }
 
+// isLimitExceededBy will return true if acquiring weight more units would exceed the limit.
+func (p *LookupPartition) isLimitExceededBy(weight int32) bool {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.busy+weight > p.limit
+}
+
 // Acquire from the worker pool
 // note: not to be used directly, not thread safe.
 func (p *LookupPartition) Acquire() {
+	p.acquire(1)
+}
+
+func (p *LookupPartition) acquire(weight int32) {
 	p.mu.Lock()
 	defer p.mu.Unlock()
-	p.busy++
+	p.busy += weight
 }
 
 // Release from the worker pool
 // note: not to be used directly, not thread safe.
 func (p *LookupPartition) Release() {
+	p.release(1)
+}
+
+func (p *LookupPartition) release(weight int32) {
 	p.mu.Lock()
 	defer p.mu.Unlock()
-	p.busy--
+	p.busy -= weight
 }
 
 // Name will return the partition name, these are immutable.
@@ -184,6 +199,19 @@ func (s *LookupPartitionStrategy) RemovePartition(name string) (int, bool) {
 
 // TryAcquire a token from a partition
 func (s *LookupPartitionStrategy) TryAcquire(ctx context.Context) (token core.StrategyToken, ok bool) {
+	return s.TryAcquireWeighted(ctx, 1)
+}
+
+// TryAcquireWeighted will try to acquire weight units from a partition with a single token.  A request heavier than
+// the limit is only admitted while nothing else is in flight.
+func (s *LookupPartitionStrategy) TryAcquireWeighted(
+	ctx context.Context,
+	weight int,
+) (token core.StrategyToken, ok bool) {
+	if weight < 1 {
+		weight = 1
+	}
+	w := int32(weight)
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	partitionName := s.lookupFunc(ctx)
@@ -191,21 +219,21 @@ func (s *LookupPartitionStrategy) TryAcquire(ctx context.Context) (token core.St
 	if !ok {
 		partition = s.unknownPartition
 	}
-	if s.busy >= s.limit && partition.IsLimitExceeded() {
+	if s.busy > 0 && s.busy+w > s.limit && partition.isLimitExceededBy(w) {
 		return core.NewNotAcquiredStrategyToken(int(s.busy)), false
 	}
 	// otherwise we can acquire
-	s.busy++
-	partition.Acquire()
-	return core.NewAcquiredStrategyToken(int(s.busy), s.releasePartition(partition)), true
+	s.busy += w
+	partition.acquire(w)
+	return core.NewAcquiredStrategyToken(int(s.busy), s.releasePartition(partition, w)), true
 }
 
-func (s *LookupPartitionStrategy) releasePartition(partition *LookupPartition) func() {
+func (s *LookupPartitionStrategy) releasePartition(partition *LookupPartition, weight int32) func() {
 	return func() {
 		s.mu.Lock()
 		defer s.mu.Unlock()
-		s.busy--
-		partition.Release()
+		s.busy -= weight
+		partition.release(weight)
 	}
 }
 
@@ -0,0 +1,228 @@
+package strategy
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/platinummonkey/go-concurrency-limits/core"
+	"github.com/platinummonkey/go-concurrency-limits/limit"
+)
+
+const (
+	// MemoryPressureReasonNone means the limit is not overridden.
+	MemoryPressureReasonNone = ""
+	// MemoryPressureReasonHeap means the limit is reduced because the live heap is nearing the memory limit.
+	MemoryPressureReasonHeap = "heap"
+	// MemoryPressureReasonGC means the limit is reduced because of excessive garbage collection CPU usage.
+	MemoryPressureReasonGC = "gc"
+	// MemoryPressureReasonGoroutines means the limit is reduced because of the number of live goroutines.
+	MemoryPressureReasonGoroutines = "goroutines"
+)
+
+// MemoryPressureStats reports the state of the MemoryPressureStrategy and why the limit was overridden.
+type MemoryPressureStats struct {
+	// Limit is the limit requested via SetLimit.
+	Limit int
+	// EffectiveLimit is the limit applied to the delegate strategy.
+	EffectiveLimit int
+	// Scale is the scale applied to the requested limit.
+	Scale float64
+	// Reason is the dominant signal reducing the limit, MemoryPressureReasonNone if not reduced.
+	Reason string
+	// Memory is the last memory stats read.
+	Memory MemoryStats
+}
+
+// MemoryPressureStrategy wraps a delegate Strategy and reduces the limit it enforces when the process nears its
+// memory limit, complementing the latency driven limits.  Each signal that exceeds its threshold is converted into an
+// excess in [0,1] and the limit set on the delegate is scaled by max(minScale, 1 - maxExcess)
+//
+//	heap:       (heapLive/memoryLimit - heapThreshold) / (1 - heapThreshold)
+//	gc:         (gcCPUFraction - gcCPUThreshold) / (1 - gcCPUThreshold)
+//	goroutines: (goroutines - maxGoroutines) / maxGoroutines
+//
+// The signals are refreshed on SetLimit and on TryAcquire once the refresh interval has elapsed.
+type MemoryPressureStrategy struct {
+	delegate       core.Strategy
+	reader         MemoryStatsReader
+	heapThreshold  float64
+	gcCPUThreshold float64
+	maxGoroutines  int
+	minScale       float64
+	interval       int64
+	clock          limit.Clock
+
+	limit       int
+	nextRefresh int64
+	stats       MemoryPressureStats
+	mu          sync.RWMutex
+}
+
+// NewMemoryPressureStrategyWithDefaults will create a new MemoryPressureStrategy reading the runtime metrics every
+// second, reducing the limit once the live heap exceeds 80% of the memory limit or the GC uses more than 25% of CPU.
+func NewMemoryPressureStrategyWithDefaults(delegate core.Strategy, initialLimit int) (*MemoryPressureStrategy, error) {
+	return NewMemoryPressureStrategy(
+		delegate,
+		NewRuntimeMemoryStatsReader(),
+		0.8,
+		0.25,
+		0,
+		0.1,
+		time.Second,
+		nil,
+		initialLimit,
+	)
+}
+
+// NewMemoryPressureStrategy will create a new MemoryPressureStrategy.
+// @param delegate: The strategy enforcing the effective limit.
+// @param reader: Source of the memory stats.
+// @param heapThreshold: Fraction of the memory limit, accepts (0,1), above which the limit is reduced.
+// @param gcCPUThreshold: GC CPU fraction, accepts (0,1), above which the limit is reduced.
+// @param maxGoroutines: Number of goroutines above which the limit is reduced, 0 to disable.
+// @param minScale: The minimum scale applied to the limit, accepts (0,1].
+// @param interval: How often the memory stats are refreshed.
+// @param clock: Source of the current time, defaults to limit.SystemClock.
+// @param initialLimit: The initial limit.
+func NewMemoryPressureStrategy(
+	delegate core.Strategy,
+	reader MemoryStatsReader,
+	heapThreshold float64,
+	gcCPUThreshold float64,
+	maxGoroutines int,
+	minScale float64,
+	interval time.Duration,
+	clock limit.Clock,
+	initialLimit int,
+) (*MemoryPressureStrategy, error) {
+	if delegate == nil {
+		return nil, fmt.Errorf("delegate must be specified")
+	}
+	if reader == nil {
+		return nil, fmt.Errorf("reader must be specified")
+	}
+	if heapThreshold <= 0 || heapThreshold >= 1 {
+		return nil, fmt.Errorf("heapThreshold must be between (0,1)")
+	}
+	if gcCPUThreshold <= 0 || gcCPUThreshold >= 1 {
+		return nil, fmt.Errorf("gcCPUThreshold must be between (0,1)")
+	}
+	if maxGoroutines < 0 {
+		maxGoroutines = 0
+	}
+	if minScale <= 0 || minScale > 1 {
+		minScale = 0.1
+	}
+	if interval <= 0 {
+		interval = time.Second
+	}
+	if clock == nil {
+		clock = limit.SystemClock
+	}
+
+	s := &MemoryPressureStrategy{
+		delegate:       delegate,
+		reader:         reader,
+		heapThreshold:  heapThreshold,
+		gcCPUThreshold: gcCPUThreshold,
+		maxGoroutines:  maxGoroutines,
+		minScale:       minScale,
+		interval:       interval.Nanoseconds(),
+		clock:          clock,
+	}
+	s.SetLimit(initialLimit)
+	return s, nil
+}
+
+// TryAcquire will try to acquire a token from the delegate strategy.
+func (s *MemoryPressureStrategy) TryAcquire(ctx context.Context) (token core.StrategyToken, ok bool) {
+	s.refreshIfStale()
+	return s.delegate.TryAcquire(ctx)
+}
+
+// TryAcquireWeighted will try to acquire weight units from the delegate strategy, a delegate that doesn't implement
+// core.WeightedStrategy acquires a single unit.
+func (s *MemoryPressureStrategy) TryAcquireWeighted(ctx context.Context, weight int) (core.StrategyToken, bool) {
+	s.refreshIfStale()
+	if weighted, ok := s.delegate.(core.WeightedStrategy); ok {
+		return weighted.TryAcquireWeighted(ctx, weight)
+	}
+	return s.delegate.TryAcquire(ctx)
+}
+
+// refreshIfStale refreshes the memory stats once the refresh interval has elapsed.
+func (s *MemoryPressureStrategy) refreshIfStale() {
+	s.mu.RLock()
+	stale := s.clock() >= s.nextRefresh
+	s.mu.RUnlock()
+	if stale {
+		s.mu.Lock()
+		if s.clock() >= s.nextRefresh {
+			s.refresh()
+		}
+		s.mu.Unlock()
+	}
+}
+
+// SetLimit will update the requested limit and apply the memory pressure scaled limit to the delegate strategy.
+func (s *MemoryPressureStrategy) SetLimit(limit int) {
+	if limit < 1 {
+		limit = 1
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.limit = limit
+	s.refresh()
+}
+
+// refresh reads the memory stats and applies the effective limit to the delegate.
+// note: not thread safe.
+func (s *MemoryPressureStrategy) refresh() {
+	s.nextRefresh = s.clock() + s.interval
+	memory := s.reader.Read()
+
+	excess := 0.0
+	reason := MemoryPressureReasonNone
+	consider := func(value float64, signal string) {
+		if value > excess {
+			excess = value
+			reason = signal
+		}
+	}
+	if memory.MemoryLimitBytes > 0 {
+		ratio := float64(memory.HeapLiveBytes) / float64(memory.MemoryLimitBytes)
+		consider((ratio-s.heapThreshold)/(1-s.heapThreshold), MemoryPressureReasonHeap)
+	}
+	consider((memory.GCCPUFraction-s.gcCPUThreshold)/(1-s.gcCPUThreshold), MemoryPressureReasonGC)
+	if s.maxGoroutines > 0 {
+		consider(float64(memory.Goroutines-s.maxGoroutines)/float64(s.maxGoroutines), MemoryPressureReasonGoroutines)
+	}
+
+	scale := math.Max(s.minScale, 1-excess)
+	effectiveLimit := int(math.Max(1, math.Floor(float64(s.limit)*scale)))
+	s.stats = MemoryPressureStats{
+		Limit:          s.limit,
+		EffectiveLimit: effectiveLimit,
+		Scale:          scale,
+		Reason:         reason,
+		Memory:         memory,
+	}
+	s.delegate.SetLimit(effectiveLimit)
+}
+
+// Stats returns the current state of the strategy including the reason the limit was overridden.
+func (s *MemoryPressureStrategy) Stats() MemoryPressureStats {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.stats
+}
+
+func (s *MemoryPressureStrategy) String() string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return fmt.Sprintf("MemoryPressureStrategy{limit=%d, effectiveLimit=%d, reason=%q, delegate=%v}",
+		s.stats.Limit, s.stats.EffectiveLimit, s.stats.Reason, s.delegate)
+}
@@ -0,0 +1,100 @@
+package strategy
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type testMemoryStatsReader struct {
+	stats MemoryStats
+	reads int
+}
+
+func (r *testMemoryStatsReader) Read() MemoryStats {
+	r.reads++
+	return r.stats
+}
+
+func TestMemoryPressureStrategy(t *testing.T) {
+	t.Parallel()
+
+	t.Run("InvalidArguments", func(t2 *testing.T) {
+		t2.Parallel()
+		asrt := assert.New(t2)
+		reader := &testMemoryStatsReader{}
+		_, err := NewMemoryPressureStrategy(nil, reader, 0.8, 0.25, 0, 0.1, time.Second, nil, 10)
+		asrt.Error(err)
+		_, err = NewMemoryPressureStrategy(NewSimpleStrategy(10), nil, 0.8, 0.25, 0, 0.1, time.Second, nil, 10)
+		asrt.Error(err)
+		_, err = NewMemoryPressureStrategy(NewSimpleStrategy(10), reader, 1, 0.25, 0, 0.1, time.Second, nil, 10)
+		asrt.Error(err)
+		_, err = NewMemoryPressureStrategy(NewSimpleStrategy(10), reader, 0.8, 0, 0, 0.1, time.Second, nil, 10)
+		asrt.Error(err)
+	})
+
+	t.Run("RuntimeDefaults", func(t2 *testing.T) {
+		t2.Parallel()
+		asrt := assert.New(t2)
+		delegate := NewSimpleStrategy(1)
+		s, err := NewMemoryPressureStrategyWithDefaults(delegate, 10)
+		asrt.NoError(err)
+		stats := s.Stats()
+		asrt.Equal(10, stats.Limit)
+		asrt.True(stats.Memory.Goroutines > 0)
+		token, ok := s.TryAcquire(context.Background())
+		asrt.True(ok)
+		token.Release()
+	})
+
+	t.Run("Override", func(t2 *testing.T) {
+		t2.Parallel()
+		asrt := assert.New(t2)
+		now := int64(0)
+		reader := &testMemoryStatsReader{stats: MemoryStats{HeapLiveBytes: 100, MemoryLimitBytes: 1000}}
+		delegate := NewSimpleStrategy(1)
+		s, err := NewMemoryPressureStrategy(delegate, reader, 0.8, 0.25, 100, 0.1, time.Second,
+			func() int64 { return now }, 100)
+		asrt.NoError(err)
+		asrt.Equal(100, delegate.GetLimit())
+		asrt.Equal(MemoryPressureReasonNone, s.Stats().Reason)
+		asrt.Equal(1.0, s.Stats().Scale)
+
+		// half way between the heap threshold and the memory limit
+		reader.stats = MemoryStats{HeapLiveBytes: 900, MemoryLimitBytes: 1000}
+		s.SetLimit(100)
+		asrt.Equal(50, delegate.GetLimit())
+		stats := s.Stats()
+		asrt.Equal(MemoryPressureReasonHeap, stats.Reason)
+		asrt.Equal(100, stats.Limit)
+		asrt.Equal(50, stats.EffectiveLimit)
+		asrt.Contains(s.String(), `MemoryPressureStrategy{limit=100, effectiveLimit=50, reason="heap", delegate=`)
+
+		// the gc dominates, refreshed by TryAcquire once the interval elapses
+		reader.stats = MemoryStats{GCCPUFraction: 0.625}
+		token, ok := s.TryAcquire(context.Background())
+		asrt.True(ok)
+		token.Release()
+		asrt.Equal(MemoryPressureReasonHeap, s.Stats().Reason)
+		now += time.Second.Nanoseconds()
+		token, ok = s.TryAcquire(context.Background())
+		asrt.True(ok)
+		token.Release()
+		asrt.Equal(MemoryPressureReasonGC, s.Stats().Reason)
+		asrt.Equal(50, delegate.GetLimit())
+
+		// goroutines, bounded by the min scale
+		reader.stats = MemoryStats{Goroutines: 1000}
+		s.SetLimit(100)
+		asrt.Equal(MemoryPressureReasonGoroutines, s.Stats().Reason)
+		asrt.Equal(10, delegate.GetLimit())
+
+		// recovered
+		reader.stats = MemoryStats{}
+		s.SetLimit(200)
+		asrt.Equal(MemoryPressureReasonNone, s.Stats().Reason)
+		asrt.Equal(200, delegate.GetLimit())
+	})
+}
@@ -0,0 +1,19 @@
+package strategy
+
+// MemoryStats is a snapshot of the process memory and scheduler signals.
+type MemoryStats struct {
+	// HeapLiveBytes is the heap memory occupied by live objects.
+	HeapLiveBytes uint64
+	// MemoryLimitBytes is the runtime soft memory limit (GOMEMLIMIT), 0 if no limit is set.
+	MemoryLimitBytes uint64
+	// GCCPUFraction is the fraction of CPU time used by the garbage collector, [0,1].
+	GCCPUFraction float64
+	// Goroutines is the number of live goroutines.
+	Goroutines int
+}
+
+// MemoryStatsReader reads the current process memory signals.
+type MemoryStatsReader interface {
+	// Read returns the current memory stats.
+	Read() MemoryStats
+}
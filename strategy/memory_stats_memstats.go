@@ -0,0 +1,29 @@
+//go:build !go1.21
+// +build !go1.21
+
+package strategy
+
+import (
+	"runtime"
+)
+
+// RuntimeMemoryStatsReader implements MemoryStatsReader using runtime.ReadMemStats for toolchains without the
+// runtime/metrics memory limit metrics.  The memory limit is never reported and the GC CPU fraction is the fraction
+// since the process started.
+type RuntimeMemoryStatsReader struct{}
+
+// NewRuntimeMemoryStatsReader will create a new RuntimeMemoryStatsReader.
+func NewRuntimeMemoryStatsReader() *RuntimeMemoryStatsReader {
+	return &RuntimeMemoryStatsReader{}
+}
+
+// Read returns the current memory stats.
+func (r *RuntimeMemoryStatsReader) Read() MemoryStats {
+	var m runtime.MemStats
+	runtime.ReadMemStats(&m)
+	return MemoryStats{
+		HeapLiveBytes: m.HeapAlloc,
+		GCCPUFraction: m.GCCPUFraction,
+		Goroutines:    runtime.NumGoroutine(),
+	}
+}
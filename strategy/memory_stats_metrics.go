@@ -0,0 +1,88 @@
+//go:build go1.21
+// +build go1.21
+
+package strategy
+
+import (
+	"math"
+	"runtime/metrics"
+	"sync"
+)
+
+const (
+	metricHeapLive     = "/gc/heap/live:bytes"
+	metricMemoryLimit  = "/gc/gomemlimit:bytes"
+	metricGCCPUSeconds = "/cpu/classes/gc/total:cpu-seconds"
+	metricCPUSeconds   = "/cpu/classes/total:cpu-seconds"
+	metricGoroutines   = "/sched/goroutines:goroutines"
+)
+
+// RuntimeMemoryStatsReader implements MemoryStatsReader using runtime/metrics.  The GC CPU fraction is computed from
+// the change between consecutive reads, the first read reports the fraction since the process started.
+type RuntimeMemoryStatsReader struct {
+	samples      []metrics.Sample
+	lastGCCPU    float64
+	lastTotalCPU float64
+	mu           sync.Mutex
+}
+
+// NewRuntimeMemoryStatsReader will create a new RuntimeMemoryStatsReader.
+func NewRuntimeMemoryStatsReader() *RuntimeMemoryStatsReader {
+	names := []string{metricHeapLive, metricMemoryLimit, metricGCCPUSeconds, metricCPUSeconds, metricGoroutines}
+	samples := make([]metrics.Sample, len(names))
+	for i, name := range names {
+		samples[i].Name = name
+	}
+	return &RuntimeMemoryStatsReader{
+		samples: samples,
+	}
+}
+
+// Read returns the current memory stats.
+func (r *RuntimeMemoryStatsReader) Read() MemoryStats {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	metrics.Read(r.samples)
+
+	stats := MemoryStats{}
+	var gcCPU, totalCPU float64
+	for _, sample := range r.samples {
+		switch sample.Name {
+		case metricHeapLive:
+			stats.HeapLiveBytes = sampleUint64(sample)
+		case metricMemoryLimit:
+			limit := sampleUint64(sample)
+			// the limit is math.MaxInt64 when unset
+			if limit < math.MaxInt64 {
+				stats.MemoryLimitBytes = limit
+			}
+		case metricGCCPUSeconds:
+			gcCPU = sampleFloat64(sample)
+		case metricCPUSeconds:
+			totalCPU = sampleFloat64(sample)
+		case metricGoroutines:
+			stats.Goroutines = int(sampleUint64(sample))
+		}
+	}
+
+	if deltaTotal := totalCPU - r.lastTotalCPU; deltaTotal > 0 {
+		stats.GCCPUFraction = math.Max(0, math.Min(1, (gcCPU-r.lastGCCPU)/deltaTotal))
+	}
+	r.lastGCCPU = gcCPU
+	r.lastTotalCPU = totalCPU
+	return stats
+}
+
+func sampleUint64(sample metrics.Sample) uint64 {
+	if sample.Value.Kind() != metrics.KindUint64 {
+		return 0
+	}
+	return sample.Value.Uint64()
+}
+
+func sampleFloat64(sample metrics.Sample) float64 {
+	if sample.Value.Kind() != metrics.KindFloat64 {
+		return 0
+	}
+	return sample.Value.Float64()
+}
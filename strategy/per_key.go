@@ -0,0 +1,238 @@
+package strategy
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"sync"
+	"time"
+
+	"github.com/platinummonkey/go-concurrency-limits/core"
+	"github.com/platinummonkey/go-concurrency-limits/limit"
+	"github.com/platinummonkey/go-concurrency-limits/strategy/matchers"
+)
+
+// perKeyShardCount is the number of shards the per key counters are split into to reduce lock contention.
+const perKeyShardCount = 32
+
+type perKeyEntry struct {
+	busy     int
+	lastUsed int64
+}
+
+type perKeyShard struct {
+	entries   map[string]*perKeyEntry
+	nextSweep int64
+	mu        sync.Mutex
+}
+
+// evictIdle removes the keys with nothing in flight that have been idle for at least idleTimeout.
+// note: not thread safe.
+func (sh *perKeyShard) evictIdle(now int64, idleTimeout int64) {
+	for key, entry := range sh.entries {
+		if entry.busy == 0 && now-entry.lastUsed >= idleTimeout {
+			delete(sh.entries, key)
+		}
+	}
+	sh.nextSweep = now + idleTimeout
+}
+
+// PerKeyStrategy caps the number of concurrent requests per key, i.e. per user or tenant, in addition to the global
+// limit enforced by a delegate Strategy.  A request is rejected when either its key is at maxPerKey or the delegate
+// rejects it.
+//
+// The counters are kept in a map split into 32 shards, each holding up to ceil(maxKeys/32) keys.  Keys with nothing in
+// flight are evicted once they have been idle for idleTimeout, either by the periodic sweep of their shard or when the
+// shard is full.  New keys are rejected while their shard is full of keys that aren't idle.
+type PerKeyStrategy struct {
+	delegate     core.Strategy
+	keyFunc      func(ctx context.Context) string
+	maxKeysShard int
+	idleTimeout  int64
+	clock        limit.Clock
+
+	maxPerKey int
+	shards    [perKeyShardCount]*perKeyShard
+	mu        sync.RWMutex
+}
+
+// NewPerKeyStrategyWithDefaults will create a new PerKeyStrategy keyed by matchers.DefaultStringLookupFunc, tracking
+// up to 100000 keys evicted after a minute of idleness.
+func NewPerKeyStrategyWithDefaults(delegate core.Strategy, maxPerKey int) (*PerKeyStrategy, error) {
+	return NewPerKeyStrategy(delegate, matchers.DefaultStringLookupFunc, maxPerKey, 100000, time.Minute, nil)
+}
+
+// NewPerKeyStrategy will create a new PerKeyStrategy.
+// @param delegate: The strategy enforcing the global limit.
+// @param keyFunc: Extracts the key of the request from the context.
+// @param maxPerKey: The maximum number of concurrent requests per key.
+// @param maxKeys: The maximum number of keys tracked, enforced per shard as ceil(maxKeys/32) keys.
+// @param idleTimeout: How long a key with nothing in flight is kept before being evicted.
+// @param clock: Source of the current time, defaults to limit.SystemClock.
+func NewPerKeyStrategy(
+	delegate core.Strategy,
+	keyFunc func(ctx context.Context) string,
+	maxPerKey int,
+	maxKeys int,
+	idleTimeout time.Duration,
+	clock limit.Clock,
+) (*PerKeyStrategy, error) {
+	if delegate == nil {
+		return nil, fmt.Errorf("delegate must be specified")
+	}
+	if keyFunc == nil {
+		return nil, fmt.Errorf("keyFunc must be specified")
+	}
+	if maxPerKey < 1 {
+		return nil, fmt.Errorf("maxPerKey must be >= 1")
+	}
+	if maxKeys < 1 {
+		return nil, fmt.Errorf("maxKeys must be >= 1")
+	}
+	if idleTimeout <= 0 {
+		idleTimeout = time.Minute
+	}
+	if clock == nil {
+		clock = limit.SystemClock
+	}
+
+	maxKeysShard := (maxKeys + perKeyShardCount - 1) / perKeyShardCount
+	s := &PerKeyStrategy{
+		delegate:     delegate,
+		keyFunc:      keyFunc,
+		maxKeysShard: maxKeysShard,
+		idleTimeout:  idleTimeout.Nanoseconds(),
+		clock:        clock,
+		maxPerKey:    maxPerKey,
+	}
+	for i := range s.shards {
+		s.shards[i] = &perKeyShard{
+			entries: make(map[string]*perKeyEntry),
+		}
+	}
+	return s, nil
+}
+
+func (s *PerKeyStrategy) shard(key string) *perKeyShard {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(key))
+	return s.shards[h.Sum32()%perKeyShardCount]
+}
+
+// TryAcquire will try to acquire a token for the key of the request and from the delegate strategy.
+func (s *PerKeyStrategy) TryAcquire(ctx context.Context) (token core.StrategyToken, ok bool) {
+	return s.TryAcquireWeighted(ctx, 1)
+}
+
+// TryAcquireWeighted will try to acquire weight units for the key of the request and from the delegate strategy, a
+// delegate that doesn't implement core.WeightedStrategy acquires a single unit.
+func (s *PerKeyStrategy) TryAcquireWeighted(ctx context.Context, weight int) (core.StrategyToken, bool) {
+	if weight < 1 {
+		weight = 1
+	}
+	key := s.keyFunc(ctx)
+	sh := s.shard(key)
+	maxPerKey := s.MaxPerKey()
+	now := s.clock()
+
+	sh.mu.Lock()
+	if now >= sh.nextSweep {
+		sh.evictIdle(now, s.idleTimeout)
+	}
+	entry, ok := sh.entries[key]
+	if !ok {
+		if len(sh.entries) >= s.maxKeysShard {
+			// make room by evicting the idle keys, bounded by the size of the shard
+			sh.evictIdle(now, s.idleTimeout)
+		}
+		if len(sh.entries) >= s.maxKeysShard {
+			sh.mu.Unlock()
+			return core.NewNotAcquiredStrategyToken(0), false
+		}
+		entry = &perKeyEntry{}
+		sh.entries[key] = entry
+	}
+	if entry.busy > 0 && entry.busy+weight > maxPerKey {
+		busy := entry.busy
+		sh.mu.Unlock()
+		return core.NewNotAcquiredStrategyToken(busy), false
+	}
+	// reserve the key before acquiring from the delegate so the lock isn't held across the delegate
+	entry.busy += weight
+	entry.lastUsed = now
+	sh.mu.Unlock()
+
+	var delegateToken core.StrategyToken
+	if weighted, isWeighted := s.delegate.(core.WeightedStrategy); isWeighted {
+		delegateToken, ok = weighted.TryAcquireWeighted(ctx, weight)
+	} else {
+		delegateToken, ok = s.delegate.TryAcquire(ctx)
+	}
+	if !ok || delegateToken == nil {
+		s.releaseKey(sh, entry, weight)
+		if delegateToken == nil {
+			delegateToken = core.NewNotAcquiredStrategyToken(0)
+		}
+		return delegateToken, false
+	}
+
+	return core.NewAcquiredStrategyToken(delegateToken.InFlightCount(), func() {
+		delegateToken.Release()
+		s.releaseKey(sh, entry, weight)
+	}), true
+}
+
+func (s *PerKeyStrategy) releaseKey(sh *perKeyShard, entry *perKeyEntry, weight int) {
+	sh.mu.Lock()
+	defer sh.mu.Unlock()
+	entry.busy -= weight
+	entry.lastUsed = s.clock()
+}
+
+// SetLimit will update the global limit of the delegate strategy.
+func (s *PerKeyStrategy) SetLimit(limit int) {
+	s.delegate.SetLimit(limit)
+}
+
+// SetMaxPerKey will update the maximum number of concurrent requests per key.
+func (s *PerKeyStrategy) SetMaxPerKey(maxPerKey int) {
+	if maxPerKey < 1 {
+		maxPerKey = 1
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.maxPerKey = maxPerKey
+}
+
+// MaxPerKey returns the maximum number of concurrent requests per key.
+func (s *PerKeyStrategy) MaxPerKey() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.maxPerKey
+}
+
+// KeyBusyCount returns the number of requests in flight for the key.
+func (s *PerKeyStrategy) KeyBusyCount(key string) int {
+	sh := s.shard(key)
+	sh.mu.Lock()
+	defer sh.mu.Unlock()
+	if entry, ok := sh.entries[key]; ok {
+		return entry.busy
+	}
+	return 0
+}
+
+// KeyCount returns the number of keys tracked, including idle keys not yet evicted.
+func (s *PerKeyStrategy) KeyCount() int {
+	count := 0
+	for _, sh := range s.shards {
+		sh.mu.Lock()
+		count += len(sh.entries)
+		sh.mu.Unlock()
+	}
+	return count
+}
+
+func (s *PerKeyStrategy) String() string {
+	return fmt.Sprintf("PerKeyStrategy{maxPerKey=%d, keys=%d, delegate=%v}", s.MaxPerKey(), s.KeyCount(), s.delegate)
+}
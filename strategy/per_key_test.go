@@ -0,0 +1,224 @@
+package strategy
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/platinummonkey/go-concurrency-limits/strategy/matchers"
+)
+
+func TestPerKeyStrategy(t *testing.T) {
+	t.Parallel()
+
+	keyCtx := func(key string) context.Context {
+		return context.WithValue(context.Background(), matchers.LookupPartitionContextKey, key)
+	}
+
+	t.Run("NewPerKeyStrategy", func(t2 *testing.T) {
+		t2.Parallel()
+		asrt := assert.New(t2)
+		_, err := NewPerKeyStrategy(nil, matchers.DefaultStringLookupFunc, 1, 10, 0, nil)
+		asrt.Error(err)
+		_, err = NewPerKeyStrategy(NewSimpleStrategy(10), nil, 1, 10, 0, nil)
+		asrt.Error(err)
+		_, err = NewPerKeyStrategy(NewSimpleStrategy(10), matchers.DefaultStringLookupFunc, 0, 10, 0, nil)
+		asrt.Error(err)
+		_, err = NewPerKeyStrategy(NewSimpleStrategy(10), matchers.DefaultStringLookupFunc, 1, 0, 0, nil)
+		asrt.Error(err)
+
+		strategy, err := NewPerKeyStrategyWithDefaults(NewSimpleStrategy(10), 2)
+		asrt.NoError(err)
+		asrt.Equal(2, strategy.MaxPerKey())
+		asrt.Equal("PerKeyStrategy{maxPerKey=2, keys=0, delegate=SimpleStrategy{inFlight=0, limit=10}}",
+			strategy.String())
+	})
+
+	t.Run("RejectsWhenKeyIsAtCap", func(t2 *testing.T) {
+		t2.Parallel()
+		asrt := assert.New(t2)
+		strategy, err := NewPerKeyStrategyWithDefaults(NewSimpleStrategy(10), 2)
+		asrt.NoError(err)
+
+		first, ok := strategy.TryAcquire(keyCtx("alice"))
+		asrt.True(ok && first.IsAcquired())
+		second, ok := strategy.TryAcquire(keyCtx("alice"))
+		asrt.True(ok)
+		token, ok := strategy.TryAcquire(keyCtx("alice"))
+		asrt.False(ok)
+		asrt.Equal(2, token.InFlightCount())
+
+		// other keys are unaffected
+		other, ok := strategy.TryAcquire(keyCtx("bob"))
+		asrt.True(ok)
+		asrt.Equal(2, strategy.KeyBusyCount("alice"))
+		asrt.Equal(1, strategy.KeyBusyCount("bob"))
+		asrt.Equal(0, strategy.KeyBusyCount("carol"))
+
+		first.Release()
+		_, ok = strategy.TryAcquire(keyCtx("alice"))
+		asrt.True(ok)
+
+		strategy.SetMaxPerKey(0)
+		asrt.Equal(1, strategy.MaxPerKey())
+		second.Release()
+		other.Release()
+		asrt.Equal(0, strategy.KeyBusyCount("bob"))
+	})
+
+	t.Run("RejectsWhenGlobalLimitIsExceeded", func(t2 *testing.T) {
+		t2.Parallel()
+		asrt := assert.New(t2)
+		delegate := NewSimpleStrategy(10)
+		strategy, err := NewPerKeyStrategyWithDefaults(delegate, 5)
+		asrt.NoError(err)
+		strategy.SetLimit(2)
+		asrt.Equal(2, delegate.GetLimit())
+
+		first, ok := strategy.TryAcquire(keyCtx("alice"))
+		asrt.True(ok)
+		_, ok = strategy.TryAcquire(keyCtx("bob"))
+		asrt.True(ok)
+		_, ok = strategy.TryAcquire(keyCtx("carol"))
+		asrt.False(ok)
+		// the rejected request doesn't hold its key
+		asrt.Equal(0, strategy.KeyBusyCount("carol"))
+
+		first.Release()
+		asrt.Equal(1, delegate.GetBusyCount())
+		asrt.Equal(0, strategy.KeyBusyCount("alice"))
+	})
+
+	t.Run("AcquireWeighted", func(t2 *testing.T) {
+		t2.Parallel()
+		asrt := assert.New(t2)
+		delegate := NewSimpleStrategy(10)
+		strategy, err := NewPerKeyStrategyWithDefaults(delegate, 4)
+		asrt.NoError(err)
+
+		token, ok := strategy.TryAcquireWeighted(keyCtx("alice"), 3)
+		asrt.True(ok)
+		asrt.Equal(3, token.InFlightCount())
+		asrt.Equal(3, strategy.KeyBusyCount("alice"))
+		_, ok = strategy.TryAcquireWeighted(keyCtx("alice"), 2)
+		asrt.False(ok)
+		token.Release()
+		asrt.Equal(0, delegate.GetBusyCount())
+	})
+
+	t.Run("EvictsIdleKeys", func(t2 *testing.T) {
+		t2.Parallel()
+		asrt := assert.New(t2)
+		now := int64(0)
+		strategy, err := NewPerKeyStrategy(
+			NewSimpleStrategy(100),
+			matchers.DefaultStringLookupFunc,
+			1,
+			100,
+			time.Second,
+			func() int64 { return now },
+		)
+		asrt.NoError(err)
+
+		busy, ok := strategy.TryAcquire(keyCtx("busy"))
+		asrt.True(ok)
+		idle, ok := strategy.TryAcquire(keyCtx("idle"))
+		asrt.True(ok)
+		idle.Release()
+		asrt.Equal(2, strategy.KeyCount())
+
+		// shards are swept as they are used, only keys with nothing in flight are evicted
+		now += time.Second.Nanoseconds()
+		_, ok = strategy.TryAcquire(keyCtx("busy"))
+		asrt.False(ok)
+		token, ok := strategy.TryAcquire(keyCtx(collidingKey(strategy, "idle")))
+		asrt.True(ok)
+		asrt.Equal(2, strategy.KeyCount())
+		asrt.Equal(1, strategy.KeyBusyCount("busy"))
+		token.Release()
+		busy.Release()
+	})
+
+	t.Run("BoundsTheNumberOfKeys", func(t2 *testing.T) {
+		t2.Parallel()
+		asrt := assert.New(t2)
+		now := int64(0)
+		strategy, err := NewPerKeyStrategy(
+			NewSimpleStrategy(1000),
+			matchers.DefaultStringLookupFunc,
+			1,
+			1,
+			time.Second,
+			func() int64 { return now },
+		)
+		asrt.NoError(err)
+
+		// a shard holds a single key, so a second key of the same shard is rejected while the first is active
+		first, ok := strategy.TryAcquire(keyCtx("a"))
+		asrt.True(ok)
+		colliding := collidingKey(strategy, "a")
+		_, ok = strategy.TryAcquire(keyCtx(colliding))
+		asrt.False(ok)
+
+		// once the active key is released and idle it is evicted to make room
+		first.Release()
+		now += time.Second.Nanoseconds()
+		token, ok := strategy.TryAcquire(keyCtx(colliding))
+		asrt.True(ok)
+		asrt.Equal(1, strategy.KeyCount())
+		token.Release()
+	})
+
+	t.Run("FullShardEvictsIdleKeys", func(t2 *testing.T) {
+		t2.Parallel()
+		asrt := assert.New(t2)
+		now := int64(0)
+		strategy, err := NewPerKeyStrategy(
+			NewSimpleStrategy(1000),
+			matchers.DefaultStringLookupFunc,
+			1,
+			1,
+			time.Second,
+			func() int64 { return now },
+		)
+		asrt.NoError(err)
+		colliding := collidingKey(strategy, "a")
+
+		// the shard is swept at 0s, next at 1s
+		token, ok := strategy.TryAcquire(keyCtx("a"))
+		asrt.True(ok)
+		token.Release()
+		now += (200 * time.Millisecond).Nanoseconds()
+		token, ok = strategy.TryAcquire(keyCtx("a"))
+		asrt.True(ok)
+		token.Release()
+
+		// swept at 1s, the key has only been idle for 800ms
+		now = time.Second.Nanoseconds()
+		_, ok = strategy.TryAcquire(keyCtx(colliding))
+		asrt.False(ok)
+		// the key is idle by 1.5s, the full shard evicts it before the next periodic sweep
+		now += (500 * time.Millisecond).Nanoseconds()
+		token, ok = strategy.TryAcquire(keyCtx(colliding))
+		asrt.True(ok)
+		asrt.Equal(1, strategy.KeyCount())
+		// a key in flight is never evicted
+		now += (5 * time.Second).Nanoseconds()
+		_, ok = strategy.TryAcquire(keyCtx("a"))
+		asrt.False(ok)
+		token.Release()
+	})
+}
+
+// collidingKey returns another key stored in the same shard as key.
+func collidingKey(strategy *PerKeyStrategy, key string) string {
+	for i := 0; ; i++ {
+		other := fmt.Sprintf("%s-%d", key, i)
+		if strategy.shard(other) == strategy.shard(key) {
+			return other
+		}
+	}
+}
@@ -70,20 +70,35 @@ func (p *PredicatePartition) IsLimitExceeded() bool {
 	return p.busy >= p.limit
 }
 
+// isLimitExceededBy will return true if acquiring weight more units would exceed the limit.
+func (p *PredicatePartition) isLimitExceededBy(weight int32) bool {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.busy+weight > p.limit
+}
+
 // Acquire from the worker pool
 // note: not to be used directly, not thread safe.
 func (p *PredicatePartition) Acquire() {
+	p.acquire(1)
+}
+
+func (p *PredicatePartition) acquire(weight int32) {
 	p.mu.Lock()
 	defer p.mu.Unlock()
-	p.busy++
+	p.busy += weight
 }
 
 // Release from the worker pool
 // note: not to be used directly, not thread safe.
 func (p *PredicatePartition) Release() {
+	p.release(1)
+}
+
+func (p *PredicatePartition) release(weight int32) {
 	p.mu.Lock()
 	defer p.mu.Unlock()
-	p.busy--
+	p.busy -= weight
 }
 
 // Name will return the partition name, these are immutable.
@@ -185,28 +200,38 @@ func (s *PredicatePartitionStrategy) RemovePartitionsMatching(matcher context.Co
 
 // TryAcquire a token from a partition
 func (s *PredicatePartitionStrategy) TryAcquire(ctx context.Context) (core.StrategyToken, bool) {
+	return s.TryAcquireWeighted(ctx, 1)
+}
+
+// TryAcquireWeighted will try to acquire weight units from a partition with a single token.  A request heavier than
+// the limit is only admitted while nothing else is in flight.
+func (s *PredicatePartitionStrategy) TryAcquireWeighted(ctx context.Context, weight int) (core.StrategyToken, bool) {
+	if weight < 1 {
+		weight = 1
+	}
+	w := int32(weight)
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	for _, p := range s.partitions {
 		if p.predicate(ctx) {
-			if s.busy >= s.limit && p.IsLimitExceeded() {
+			if s.busy > 0 && s.busy+w > s.limit && p.isLimitExceededBy(w) {
 				// limit exceeded on this partition
 				return core.NewNotAcquiredStrategyToken(int(s.busy)), false
 			}
-			s.busy++
-			p.Acquire()
-			return core.NewAcquiredStrategyToken(int(s.busy), s.releasePartition(p)), true
+			s.busy += w
+			p.acquire(w)
+			return core.NewAcquiredStrategyToken(int(s.busy), s.releasePartition(p, w)), true
 		}
 	}
 	return core.NewNotAcquiredStrategyToken(int(s.busy)), false
 }
 
-func (s *PredicatePartitionStrategy) releasePartition(partition *PredicatePartition) func() {
+func (s *PredicatePartitionStrategy) releasePartition(partition *PredicatePartition, weight int32) func() {
 	return func() {
 		s.mu.Lock()
 		defer s.mu.Unlock()
-		s.busy--
-		partition.Release()
+		s.busy -= weight
+		partition.release(weight)
 	}
 }
 
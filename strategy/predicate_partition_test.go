@@ -340,4 +340,39 @@ func TestPredicatePartition(t *testing.T) {
 		asrt.False(ok)
 		asrt.False(token.IsAcquired())
 	})
+
+	t.Run("AcquireWeighted", func(t2 *testing.T) {
+		t2.Parallel()
+		asrt := assert.New(t2)
+		strategy, err := NewPredicatePartitionStrategyWithMetricRegistry(
+			makeTestPartitions(),
+			10,
+		)
+		asrt.NoError(err, "failed to create strategy")
+
+		batchCtx := context.WithValue(context.Background(), matchers.StringPredicateContextKey, "batch")
+		liveCtx := context.WithValue(context.Background(), matchers.StringPredicateContextKey, "live")
+
+		// batch may borrow up to the total limit
+		batchToken, ok := strategy.TryAcquireWeighted(batchCtx, 8)
+		asrt.True(ok)
+		asrt.Equal(8, strategy.BusyCount())
+		busyCount, err := strategy.BinBusyCount(0)
+		asrt.NoError(err)
+		asrt.Equal(8, busyCount)
+		_, ok = strategy.TryAcquireWeighted(batchCtx, 3)
+		asrt.False(ok, "expected batch to be limited")
+
+		// live is within its guaranteed share
+		liveToken, ok := strategy.TryAcquireWeighted(liveCtx, 7)
+		asrt.True(ok)
+		asrt.Equal(15, strategy.BusyCount())
+
+		batchToken.Release()
+		liveToken.Release()
+		asrt.Equal(0, strategy.BusyCount())
+		busyCount, err = strategy.BinBusyCount(1)
+		asrt.NoError(err)
+		asrt.Equal(0, busyCount)
+	})
 }
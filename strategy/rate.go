@@ -0,0 +1,238 @@
+package strategy
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/platinummonkey/go-concurrency-limits/core"
+	"github.com/platinummonkey/go-concurrency-limits/limit"
+	"github.com/platinummonkey/go-concurrency-limits/measurements"
+)
+
+// RateLimitedStrategy wraps a delegate Strategy and additionally requires a token from a token bucket, so a single
+// Acquire enforces both a concurrency limit and a rate, i.e. for downstreams with RPS quotas.
+//
+// The rate is either static, or derived from the current limit and the observed RTT with Little's law
+//
+//	rate = limit / rtt
+//
+// where rtt is the exponential average of the time tokens are held.  The bucket holds up to burst tokens, a request
+// weighing more than the burst is only admitted once the bucket is full and empties it, the bucket never goes into
+// debt.
+type RateLimitedStrategy struct {
+	delegate   core.Strategy
+	staticRate float64
+	burst      int
+	clock      limit.Clock
+	rtt        *measurements.ExponentialAverageMeasurement
+
+	limit      int
+	tokens     float64
+	lastRefill int64
+	mu         sync.Mutex
+}
+
+// NewStaticRateLimitedStrategy will create a new RateLimitedStrategy enforcing a static rate.
+func NewStaticRateLimitedStrategy(
+	delegate core.Strategy,
+	rate float64,
+	burst int,
+	initialLimit int,
+) (*RateLimitedStrategy, error) {
+	if rate <= 0 {
+		return nil, fmt.Errorf("rate must be > 0")
+	}
+	return NewRateLimitedStrategy(delegate, rate, burst, 0, nil, initialLimit)
+}
+
+// NewLittlesLawRateLimitedStrategy will create a new RateLimitedStrategy deriving the rate from the limit and the
+// observed RTT.
+func NewLittlesLawRateLimitedStrategy(
+	delegate core.Strategy,
+	burst int,
+	initialRTT time.Duration,
+	initialLimit int,
+) (*RateLimitedStrategy, error) {
+	return NewRateLimitedStrategy(delegate, 0, burst, initialRTT, nil, initialLimit)
+}
+
+// NewRateLimitedStrategy will create a new RateLimitedStrategy.
+// @param delegate: The strategy enforcing the concurrency limit.
+// @param rate: The static rate in requests per second, 0 to derive the rate from the limit and observed RTT.
+// @param burst: The bucket size, defaults to the rate or the limit when derived.
+// @param initialRTT: The RTT assumed until enough samples are observed, required when the rate is derived.
+// @param clock: Source of the current time, defaults to limit.SystemClock.
+// @param initialLimit: The initial limit.
+func NewRateLimitedStrategy(
+	delegate core.Strategy,
+	rate float64,
+	burst int,
+	initialRTT time.Duration,
+	clock limit.Clock,
+	initialLimit int,
+) (*RateLimitedStrategy, error) {
+	if delegate == nil {
+		return nil, fmt.Errorf("delegate must be specified")
+	}
+	if rate < 0 {
+		return nil, fmt.Errorf("rate must be >= 0")
+	}
+	if rate == 0 && initialRTT <= 0 {
+		return nil, fmt.Errorf("initialRTT must be > 0 when the rate is derived")
+	}
+	if burst < 0 {
+		burst = 0
+	}
+	if clock == nil {
+		clock = limit.SystemClock
+	}
+	if initialLimit < 1 {
+		initialLimit = 1
+	}
+
+	rtt := measurements.NewExponentialAverageMeasurement(100, 10)
+	if initialRTT > 0 {
+		// seed the average as a sample so it's weighed against the first samples rather than replaced by them
+		rtt.Add(float64(initialRTT.Nanoseconds()))
+	}
+	s := &RateLimitedStrategy{
+		delegate:   delegate,
+		staticRate: rate,
+		burst:      burst,
+		clock:      clock,
+		rtt:        rtt,
+		limit:      initialLimit,
+		lastRefill: clock(),
+	}
+	s.tokens = s.capacity()
+	delegate.SetLimit(initialLimit)
+	return s, nil
+}
+
+// TryAcquire will try to acquire a token from the bucket and from the delegate strategy.
+func (s *RateLimitedStrategy) TryAcquire(ctx context.Context) (token core.StrategyToken, ok bool) {
+	return s.TryAcquireWeighted(ctx, 1)
+}
+
+// TryAcquireWeighted will try to acquire weight tokens from the bucket and weight units from the delegate strategy, a
+// delegate that doesn't implement core.WeightedStrategy acquires a single unit.
+func (s *RateLimitedStrategy) TryAcquireWeighted(ctx context.Context, weight int) (core.StrategyToken, bool) {
+	if weight < 1 {
+		weight = 1
+	}
+	w := float64(weight)
+
+	s.mu.Lock()
+	s.refill()
+	if s.tokens < w && s.tokens < s.capacity() {
+		s.mu.Unlock()
+		return core.NewNotAcquiredStrategyToken(0), false
+	}
+	taken := math.Min(w, s.tokens)
+	s.tokens -= taken
+	s.mu.Unlock()
+
+	var delegateToken core.StrategyToken
+	var ok bool
+	if weighted, isWeighted := s.delegate.(core.WeightedStrategy); isWeighted {
+		delegateToken, ok = weighted.TryAcquireWeighted(ctx, weight)
+	} else {
+		delegateToken, ok = s.delegate.TryAcquire(ctx)
+	}
+	if !ok || delegateToken == nil {
+		// refund the unused tokens
+		s.mu.Lock()
+		s.tokens = math.Min(s.capacity(), s.tokens+taken)
+		s.mu.Unlock()
+		if delegateToken == nil {
+			delegateToken = core.NewNotAcquiredStrategyToken(0)
+		}
+		return delegateToken, false
+	}
+
+	startTime := s.clock()
+	return core.NewAcquiredStrategyToken(delegateToken.InFlightCount(), func() {
+		delegateToken.Release()
+		s.rtt.Add(float64(s.clock() - startTime))
+	}), true
+}
+
+// refill adds the tokens accrued since the last refill.
+// note: not thread safe.
+func (s *RateLimitedStrategy) refill() {
+	now := s.clock()
+	elapsed := float64(now-s.lastRefill) / float64(time.Second)
+	s.lastRefill = now
+	if elapsed <= 0 {
+		return
+	}
+	s.tokens = math.Min(s.capacity(), s.tokens+elapsed*s.rate())
+}
+
+// rate returns the current rate in tokens per second.
+// note: not thread safe.
+func (s *RateLimitedStrategy) rate() float64 {
+	if s.staticRate > 0 {
+		return s.staticRate
+	}
+	rtt := s.rtt.Get()
+	if rtt <= 0 {
+		return float64(s.limit)
+	}
+	return float64(s.limit) / (rtt / float64(time.Second))
+}
+
+// capacity returns the bucket size.
+// note: not thread safe.
+func (s *RateLimitedStrategy) capacity() float64 {
+	if s.burst > 0 {
+		return float64(s.burst)
+	}
+	if s.staticRate > 0 {
+		return math.Max(1, math.Ceil(s.staticRate))
+	}
+	return float64(s.limit)
+}
+
+// SetLimit will update the limit of the delegate strategy, and the derived rate.
+func (s *RateLimitedStrategy) SetLimit(limit int) {
+	if limit < 1 {
+		limit = 1
+	}
+	s.mu.Lock()
+	s.refill()
+	s.limit = limit
+	s.tokens = math.Min(s.capacity(), s.tokens)
+	s.mu.Unlock()
+	s.delegate.SetLimit(limit)
+}
+
+// Rate returns the current rate in requests per second.
+func (s *RateLimitedStrategy) Rate() float64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.rate()
+}
+
+// Tokens returns the number of tokens currently available in the bucket.
+func (s *RateLimitedStrategy) Tokens() float64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.refill()
+	return s.tokens
+}
+
+// AverageRTT returns the observed average time tokens are held.
+func (s *RateLimitedStrategy) AverageRTT() time.Duration {
+	return time.Duration(s.rtt.Get())
+}
+
+func (s *RateLimitedStrategy) String() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return fmt.Sprintf("RateLimitedStrategy{rate=%0.2f, tokens=%0.2f, limit=%d, delegate=%v}",
+		s.rate(), s.tokens, s.limit, s.delegate)
+}
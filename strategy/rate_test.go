@@ -0,0 +1,174 @@
+package strategy
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRateLimitedStrategy(t *testing.T) {
+	t.Parallel()
+
+	t.Run("NewRateLimitedStrategy", func(t2 *testing.T) {
+		t2.Parallel()
+		asrt := assert.New(t2)
+		_, err := NewRateLimitedStrategy(nil, 10, 0, 0, nil, 10)
+		asrt.Error(err)
+		_, err = NewRateLimitedStrategy(NewSimpleStrategy(10), -1, 0, 0, nil, 10)
+		asrt.Error(err)
+		_, err = NewStaticRateLimitedStrategy(NewSimpleStrategy(10), 0, 0, 10)
+		asrt.Error(err)
+		_, err = NewLittlesLawRateLimitedStrategy(NewSimpleStrategy(10), 0, 0, 10)
+		asrt.Error(err)
+
+		delegate := NewSimpleStrategy(1)
+		strategy, err := NewStaticRateLimitedStrategy(delegate, 2.5, 0, 10)
+		asrt.NoError(err)
+		asrt.Equal(10, delegate.GetLimit())
+		asrt.Equal(2.5, strategy.Rate())
+		asrt.Equal(3.0, strategy.Tokens())
+		asrt.Contains(strategy.String(), "RateLimitedStrategy{rate=2.50, tokens=3.00, limit=10, ")
+	})
+
+	t.Run("StaticRate", func(t2 *testing.T) {
+		t2.Parallel()
+		asrt := assert.New(t2)
+		now := int64(0)
+		delegate := NewSimpleStrategy(100)
+		strategy, err := NewRateLimitedStrategy(delegate, 10, 2, 0, func() int64 { return now }, 100)
+		asrt.NoError(err)
+
+		for i := 0; i < 2; i++ {
+			token, ok := strategy.TryAcquire(context.Background())
+			asrt.True(ok && token.IsAcquired())
+			token.Release()
+		}
+		_, ok := strategy.TryAcquire(context.Background())
+		asrt.False(ok, "expected the bucket to be empty")
+
+		// 10 per second refills a token every 100ms
+		now += (100 * time.Millisecond).Nanoseconds()
+		token, ok := strategy.TryAcquire(context.Background())
+		asrt.True(ok)
+		token.Release()
+		_, ok = strategy.TryAcquire(context.Background())
+		asrt.False(ok)
+
+		// the bucket is capped at the burst
+		now += (10 * time.Second).Nanoseconds()
+		asrt.Equal(2.0, strategy.Tokens())
+		asrt.Equal(0, delegate.GetBusyCount())
+	})
+
+	t.Run("ConcurrencyLimitStillEnforced", func(t2 *testing.T) {
+		t2.Parallel()
+		asrt := assert.New(t2)
+		now := int64(0)
+		strategy, err := NewRateLimitedStrategy(NewSimpleStrategy(1), 10, 5, 0, func() int64 { return now }, 1)
+		asrt.NoError(err)
+
+		token, ok := strategy.TryAcquire(context.Background())
+		asrt.True(ok)
+		_, ok = strategy.TryAcquire(context.Background())
+		asrt.False(ok)
+		// the token of a request rejected by the delegate is refunded
+		asrt.Equal(4.0, strategy.Tokens())
+		token.Release()
+	})
+
+	t.Run("LittlesLawRate", func(t2 *testing.T) {
+		t2.Parallel()
+		asrt := assert.New(t2)
+		now := int64(0)
+		strategy, err := NewRateLimitedStrategy(
+			NewSimpleStrategy(10),
+			0,
+			0,
+			100*time.Millisecond,
+			func() int64 { return now },
+			10,
+		)
+		asrt.NoError(err)
+		// 10 concurrent requests of 100ms each is 100 per second
+		asrt.InDelta(100.0, strategy.Rate(), 0.001)
+		asrt.Equal(10.0, strategy.Tokens())
+
+		strategy.SetLimit(20)
+		asrt.InDelta(200.0, strategy.Rate(), 0.001)
+
+		// the observed rtt replaces the initial rtt
+		for i := 0; i < 10; i++ {
+			token, ok := strategy.TryAcquire(context.Background())
+			asrt.True(ok)
+			now += (50 * time.Millisecond).Nanoseconds()
+			token.Release()
+		}
+		// averaged with the initial rtt during the warm up of the average
+		asrt.InDelta(54.901, strategy.AverageRTT().Seconds()*1000, 0.001)
+		asrt.InDelta(364.292, strategy.Rate(), 0.001)
+	})
+
+	t.Run("InitialRTTSeedsTheAverage", func(t2 *testing.T) {
+		t2.Parallel()
+		asrt := assert.New(t2)
+		now := int64(0)
+		strategy, err := NewRateLimitedStrategy(
+			NewSimpleStrategy(10),
+			0,
+			0,
+			100*time.Millisecond,
+			func() int64 { return now },
+			10,
+		)
+		asrt.NoError(err)
+
+		// a single short sample doesn't replace the initial rtt
+		token, ok := strategy.TryAcquire(context.Background())
+		asrt.True(ok)
+		now += time.Millisecond.Nanoseconds()
+		token.Release()
+		asrt.Equal(50500*time.Microsecond, strategy.AverageRTT())
+		asrt.InDelta(198.02, strategy.Rate(), 0.01)
+	})
+
+	t.Run("AcquireWeighted", func(t2 *testing.T) {
+		t2.Parallel()
+		asrt := assert.New(t2)
+		now := int64(0)
+		delegate := NewSimpleStrategy(100)
+		strategy, err := NewRateLimitedStrategy(delegate, 10, 4, 0, func() int64 { return now }, 100)
+		asrt.NoError(err)
+
+		token, ok := strategy.TryAcquireWeighted(context.Background(), 3)
+		asrt.True(ok)
+		asrt.Equal(3, delegate.GetBusyCount())
+		_, ok = strategy.TryAcquireWeighted(context.Background(), 2)
+		asrt.False(ok)
+		token.Release()
+
+		// a request heavier than the burst is admitted once the bucket is full, emptying it without going into debt
+		_, ok = strategy.TryAcquireWeighted(context.Background(), 8)
+		asrt.False(ok)
+		now += time.Second.Nanoseconds()
+		token, ok = strategy.TryAcquireWeighted(context.Background(), 1000)
+		asrt.True(ok)
+		asrt.Equal(0.0, strategy.Tokens())
+		token.Release()
+		now += (100 * time.Millisecond).Nanoseconds()
+		token, ok = strategy.TryAcquireWeighted(context.Background(), 1)
+		asrt.True(ok)
+		token.Release()
+
+		// a rejected heavy request only refunds what it took
+		now += time.Second.Nanoseconds()
+		delegate.SetLimit(1)
+		held, ok := delegate.TryAcquire(context.Background())
+		asrt.True(ok)
+		_, ok = strategy.TryAcquireWeighted(context.Background(), 8)
+		asrt.False(ok)
+		asrt.Equal(4.0, strategy.Tokens())
+		held.Release()
+	})
+}
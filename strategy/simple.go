@@ -39,17 +39,34 @@ func NewSimpleStrategyWithMetricRegistry(limit int) *SimpleStrategy {
 // context Context of the request for partitioned limits.
 // returns not ok if limit is exceeded, or a StrategyToken that must be released when the operation completes.
 func (s *SimpleStrategy) TryAcquire(ctx context.Context) (token core.StrategyToken, ok bool) {
-	inFlight := atomic.LoadInt32(s.inFlight)
-	if inFlight >= atomic.LoadInt32(s.limit) {
-		return core.NewNotAcquiredStrategyToken(int(inFlight)), false
+	return s.TryAcquireWeighted(ctx, 1)
+}
+
+// TryAcquireWeighted will try to acquire weight units of the limit with a single token.  A request heavier than the
+// limit is only admitted while nothing else is in flight so it can't be starved forever.
+// context Context of the request for partitioned limits.
+// weight The number of units to acquire, values less than 1 are treated as 1.
+// returns not ok if limit is exceeded, or a StrategyToken that must be released when the operation completes.
+func (s *SimpleStrategy) TryAcquireWeighted(ctx context.Context, weight int) (token core.StrategyToken, ok bool) {
+	if weight < 1 {
+		weight = 1
 	}
-	inFlight = atomic.AddInt32(s.inFlight, 1)
-	f := func(ref *int32) func() {
-		return func() {
-			atomic.AddInt32(ref, -1)
+	w := int32(weight)
+	for {
+		inFlight := atomic.LoadInt32(s.inFlight)
+		if inFlight > 0 && inFlight+w > atomic.LoadInt32(s.limit) {
+			return core.NewNotAcquiredStrategyToken(int(inFlight)), false
 		}
+		if atomic.CompareAndSwapInt32(s.inFlight, inFlight, inFlight+w) {
+			return core.NewAcquiredStrategyToken(int(inFlight+w), s.release(w)), true
+		}
+	}
+}
+
+func (s *SimpleStrategy) release(weight int32) func() {
+	return func() {
+		atomic.AddInt32(s.inFlight, -weight)
 	}
-	return core.NewAcquiredStrategyToken(int(inFlight), f(s.inFlight)), true
 }
 
 // SetLimit will update the strategy with a new limit.
@@ -71,5 +88,5 @@ func (s *SimpleStrategy) GetBusyCount() int {
 }
 
 func (s *SimpleStrategy) String() string {
-	return fmt.Sprintf("SimpleStrategy{inFlight=%d, limit=%d}", atomic.LoadInt32(s.inFlight), s.limit)
+	return fmt.Sprintf("SimpleStrategy{inFlight=%d, limit=%d}", atomic.LoadInt32(s.inFlight), atomic.LoadInt32(s.limit))
 }
@@ -23,7 +23,7 @@ func TestSimpleStrategy(t *testing.T) {
 		strategy := NewSimpleStrategy(1)
 		asrt.Equal(1, strategy.GetLimit(), "expected a default limit of 1")
 		asrt.Equal(0, strategy.GetBusyCount(), "expected all resources free")
-		asrt.Contains(strategy.String(), "SimpleStrategy{inFlight=0, ")
+		asrt.Equal("SimpleStrategy{inFlight=0, limit=1}", strategy.String())
 	})
 
 	t.Run("SetLimit", func(t2 *testing.T) {
@@ -88,4 +88,34 @@ func TestSimpleStrategy(t *testing.T) {
 		asrt.True(token.IsAcquired(), "expected acquired token")
 		asrt.Equal(1, strategy.GetBusyCount(), "expected 1 resource taken")
 	})
+
+	t.Run("AcquireWeighted", func(t2 *testing.T) {
+		t2.Parallel()
+		asrt := assert.New(t2)
+		strategy := NewSimpleStrategy(5)
+		token, ok := strategy.TryAcquireWeighted(context.Background(), 3)
+		asrt.True(ok && token.IsAcquired())
+		asrt.Equal(3, token.InFlightCount())
+		asrt.Equal(3, strategy.GetBusyCount())
+
+		// 3 + 3 exceeds the limit
+		_, ok = strategy.TryAcquireWeighted(context.Background(), 3)
+		asrt.False(ok)
+		// weights less than 1 are treated as 1
+		token2, ok := strategy.TryAcquireWeighted(context.Background(), 0)
+		asrt.True(ok)
+		asrt.Equal(4, strategy.GetBusyCount())
+
+		token.Release()
+		token2.Release()
+		asrt.Equal(0, strategy.GetBusyCount())
+
+		// a request heavier than the limit is admitted only while idle
+		token, ok = strategy.TryAcquireWeighted(context.Background(), 10)
+		asrt.True(ok)
+		_, ok = strategy.TryAcquire(context.Background())
+		asrt.False(ok)
+		token.Release()
+		asrt.Equal(0, strategy.GetBusyCount())
+	})
 }